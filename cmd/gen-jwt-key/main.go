@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Generates a PEM key pair for the JWT keyring.
+// Usage: go run ./cmd/gen-jwt-key -alg EdDSA -kid 2026-10 -out ./keys
+func main() {
+	alg := flag.String("alg", "EdDSA", "RS256 or EdDSA")
+	kid := flag.String("kid", "", "key id (used as file name)")
+	out := flag.String("out", "keys", "output directory")
+	flag.Parse()
+
+	if *kid == "" {
+		log.Fatal("-kid is required")
+	}
+
+	var (
+		privDER []byte
+		pubDER  []byte
+		err     error
+	)
+
+	switch *alg {
+	case "RS256":
+		key, genErr := rsa.GenerateKey(rand.Reader, 2048)
+		if genErr != nil {
+			log.Fatal(genErr)
+		}
+		privDER, err = x509.MarshalPKCS8PrivateKey(key)
+		if err == nil {
+			pubDER, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+		}
+	case "EdDSA":
+		pub, priv, genErr := ed25519.GenerateKey(rand.Reader)
+		if genErr != nil {
+			log.Fatal(genErr)
+		}
+		privDER, err = x509.MarshalPKCS8PrivateKey(priv)
+		if err == nil {
+			pubDER, err = x509.MarshalPKIXPublicKey(pub)
+		}
+	default:
+		log.Fatalf("unsupported alg %q", *alg)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(*out, 0o700); err != nil {
+		log.Fatal(err)
+	}
+
+	privPath := filepath.Join(*out, *kid+".pem")
+	pubPath := filepath.Join(*out, *kid+".pub.pem")
+
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✅ Private key:", privPath)
+	fmt.Println("✅ Public key: ", pubPath)
+	fmt.Printf("Keyring entry: {\"kid\": %q, \"alg\": %q, \"private_key_file\": %q}\n", *kid, *alg, privPath)
+}
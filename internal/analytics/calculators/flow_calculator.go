@@ -24,32 +24,56 @@ func (c *flowCalculator) Calculate(ctx context.Context, formID string) ([]FlowTr
 		return []FlowTransition{}, nil
 	}
 
+	graph, err := c.repo.GetFlowGraph(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Map to track transitions: "sourceID->targetID" -> count
 	transitionCounts := make(map[string]*struct {
-		sourceID  string
-		targetID  string
-		count     int
-		isDropOff bool
+		sourceID    string
+		targetID    string
+		count       int
+		isDropOff   bool
+		isCompleted bool
 	})
 
 	// Process each response
 	for _, response := range responses {
+		// Merge points appear once per path, which keeps the diagram acyclic
+		path := dedupePath(response.FlowPath)
+
 		// Process transitions in flow path
-		for i := 0; i < len(response.FlowPath); i++ {
-			currentNodeID := response.FlowPath[i]
+		for i := 0; i < len(path); i++ {
+			currentNodeID := path[i]
 
-			if i < len(response.FlowPath)-1 {
-				// Transition to next node
-				nextNodeID := response.FlowPath[i+1]
+			if i < len(path)-1 {
+				// Transition to next node (a child or a jump target)
+				nextNodeID := path[i+1]
 				key := currentNodeID + "->" + nextNodeID
 
 				if _, exists := transitionCounts[key]; !exists {
 					transitionCounts[key] = &struct {
-						sourceID  string
-						targetID  string
-						count     int
-						isDropOff bool
-					}{sourceID: currentNodeID, targetID: nextNodeID, count: 0, isDropOff: false}
+						sourceID    string
+						targetID    string
+						count       int
+						isDropOff   bool
+						isCompleted bool
+					}{sourceID: currentNodeID, targetID: nextNodeID}
+				}
+				transitionCounts[key].count++
+			} else if graph.IsTerminal(currentNodeID) {
+				// Last node ends the flow - transition to completion
+				key := currentNodeID + "->COMPLETED"
+
+				if _, exists := transitionCounts[key]; !exists {
+					transitionCounts[key] = &struct {
+						sourceID    string
+						targetID    string
+						count       int
+						isDropOff   bool
+						isCompleted bool
+					}{sourceID: currentNodeID, targetID: "COMPLETED", isCompleted: true}
 				}
 				transitionCounts[key].count++
 			} else {
@@ -58,11 +82,12 @@ func (c *flowCalculator) Calculate(ctx context.Context, formID string) ([]FlowTr
 
 				if _, exists := transitionCounts[key]; !exists {
 					transitionCounts[key] = &struct {
-						sourceID  string
-						targetID  string
-						count     int
-						isDropOff bool
-					}{sourceID: currentNodeID, targetID: "DROP_OFF", count: 0, isDropOff: true}
+						sourceID    string
+						targetID    string
+						count       int
+						isDropOff   bool
+						isCompleted bool
+					}{sourceID: currentNodeID, targetID: "DROP_OFF", isDropOff: true}
 				}
 				transitionCounts[key].count++
 			}
@@ -73,10 +98,11 @@ func (c *flowCalculator) Calculate(ctx context.Context, formID string) ([]FlowTr
 	flows := make([]FlowTransition, 0, len(transitionCounts))
 	for _, transition := range transitionCounts {
 		flows = append(flows, FlowTransition{
-			SourceID:  transition.sourceID,
-			TargetID:  transition.targetID,
-			Value:     transition.count,
-			IsDropOff: transition.isDropOff,
+			SourceID:    transition.sourceID,
+			TargetID:    transition.targetID,
+			Value:       transition.count,
+			IsDropOff:   transition.isDropOff,
+			IsCompleted: transition.isCompleted,
 			// Source and Target text will be enriched by service/repository layer
 		})
 	}
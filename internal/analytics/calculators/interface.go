@@ -20,6 +20,28 @@ type FlowCalculator interface {
 	Calculate(ctx context.Context, formID string) ([]FlowTransition, error)
 }
 
+// QuizCalculator calculates quiz scores and item statistics
+type QuizCalculator interface {
+	Calculate(ctx context.Context, formID string) (*QuizStats, error)
+}
+
+// OrderCalculator calculates how the order of randomized blocks affected
+// what respondents chose
+type OrderCalculator interface {
+	Calculate(ctx context.Context, formID string) (*OrderStats, error)
+}
+
+// VariantCalculator compares the variants of a form's A/B test
+type VariantCalculator interface {
+	Calculate(ctx context.Context, formID string) (*VariantStats, error)
+}
+
+// KindCalculator summarizes the answers to questions with a kind (rating,
+// NPS, matrix...)
+type KindCalculator interface {
+	Calculate(ctx context.Context, formID string) (*KindStats, error)
+}
+
 // NodeMetrics represents calculated metrics for a node (matches analytics.NodeMetrics)
 type NodeMetrics struct {
 	FormID           string
@@ -51,4 +73,124 @@ type FlowTransition struct {
 	SourceID    string
 	TargetID    string
 	IsDropOff   bool
+	IsCompleted bool
+}
+
+// QuizStats summarizes the scored responses of a form
+type QuizStats struct {
+	Responses      int
+	AveragePercent float64
+	PassRate       *float64 // nil when no response was graded pass/fail
+	Outcomes       map[string]int
+	Items          []QuizItemStats
+}
+
+// QuizItemStats is the classical item analysis of one scored question
+type QuizItemStats struct {
+	FlowConnectionID string
+	Attempts         int     // responses that reached the question
+	CorrectCount     int
+	AvgPoints        float64
+	Difficulty       float64 // share of attempts answered correctly
+	// Discrimination is the difficulty among the top 27% of respondents
+	// by score minus among the bottom 27%; nil without enough responses
+	Discrimination *float64
+	// PointBiserial correlates answering correctly with the total score;
+	// nil when everyone (or no one) got it right
+	PointBiserial *float64
+}
+
+// OrderStats summarizes the responses that recorded a display order
+type OrderStats struct {
+	Responses int
+	Blocks    []OrderBlockStats
+}
+
+// OrderBlockStats is what one randomized block showed and what was chosen
+type OrderBlockStats struct {
+	FlowConnectionID string
+	Responses        int             // responses the block was arranged for
+	Positions        []PositionStats // all children, by position shown
+	Children         []OrderChildStats
+}
+
+// OrderChildStats is how often one child was shown, where, and chosen
+type OrderChildStats struct {
+	FlowConnectionID string
+	Shown            int
+	Chosen           int // responses whose flow_path went through it
+	Positions        []PositionStats
+}
+
+// PositionStats counts showings and choices at one position (1 = first)
+type PositionStats struct {
+	Position int
+	Shown    int
+	Chosen   int
+}
+
+// VariantStats compares the responses of each A/B test variant with the
+// control (the test's first variant)
+type VariantStats struct {
+	Control  string
+	Variants []VariantArmStats
+}
+
+// VariantArmStats is one variant's completion and time, and how likely the
+// differences from the control are chance
+type VariantArmStats struct {
+	Variant        string
+	Responses      int
+	Completed      int // responses whose path ends where the flow does
+	CompletionRate float64
+	AvgTime        float64 // seconds, over completed responses
+	StdDevTime     float64
+	// p-values against the control: a two-proportion z-test for completion
+	// and Welch's t-test for time; nil for the control and without enough
+	// data
+	CompletionPValue *float64
+	TimePValue       *float64
+}
+
+// KindStats summarizes the answers of a form's questions with a kind
+type KindStats struct {
+	Questions []KindQuestionStats
+}
+
+// KindQuestionStats is the summary of one question's answers, of the
+// kind's own type (kinds.NPSSummary, kinds.RatingSummary...)
+type KindQuestionStats struct {
+	FlowConnectionID string
+	Kind             string
+	Answers          int
+	Valid            int // answers whose value fits the kind
+	Summary          interface{}
+}
+
+// FlowGraph is the structure of a form's flows across all its versions
+type FlowGraph struct {
+	// Successors maps every node to the nodes that can follow it: its
+	// children and its jump targets
+	Successors map[string][]string
+}
+
+// IsTerminal reports whether the flow ends at a node. Unknown nodes are not
+// terminal.
+func (g *FlowGraph) IsTerminal(nodeID string) bool {
+	successors, ok := g.Successors[nodeID]
+	return ok && len(successors) == 0
+}
+
+// dedupePath keeps the first visit of each node. Flows are acyclic, so a
+// repeated node only comes from a client resending a step.
+func dedupePath(path []string) []string {
+	seen := make(map[string]bool, len(path))
+	result := make([]string, 0, len(path))
+	for _, id := range path {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
 }
@@ -0,0 +1,65 @@
+package calculators
+
+import (
+	"context"
+	"sort"
+
+	"smart-forms/internal/kinds"
+)
+
+type kindCalculator struct {
+	repo KindRepository
+}
+
+// KindRepository interface for the answers to questions with a kind
+type KindRepository interface {
+	GetKindAnswers(ctx context.Context, formID string) ([]KindAnswer, error)
+}
+
+// KindAnswer is the value of one answer to a question with a kind
+type KindAnswer struct {
+	FlowConnectionID string
+	Kind             *kinds.Spec
+	Value            map[string]interface{}
+}
+
+func NewKindCalculator(repo KindRepository) KindCalculator {
+	return &kindCalculator{repo: repo}
+}
+
+// Calculate summarizes the answers of every question with a kind the way
+// its kind does: an NPS score, a rating's average and distribution, a
+// ranking's average ranks...
+func (c *kindCalculator) Calculate(ctx context.Context, formID string) (*KindStats, error) {
+	answers, err := c.repo.GetKindAnswers(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	questions := make(map[string]*KindQuestionStats)
+	values := make(map[string][]map[string]interface{})
+	specs := make(map[string]*kinds.Spec)
+	for _, a := range answers {
+		q, ok := questions[a.FlowConnectionID]
+		if !ok {
+			q = &KindQuestionStats{FlowConnectionID: a.FlowConnectionID, Kind: a.Kind.Type}
+			questions[a.FlowConnectionID] = q
+			specs[a.FlowConnectionID] = a.Kind
+		}
+		q.Answers++
+		if a.Kind.Check(a.Value) == nil {
+			q.Valid++
+			values[a.FlowConnectionID] = append(values[a.FlowConnectionID], a.Value)
+		}
+	}
+
+	stats := &KindStats{Questions: []KindQuestionStats{}}
+	for id, q := range questions {
+		q.Summary = specs[id].Aggregate(values[id])
+		stats.Questions = append(stats.Questions, *q)
+	}
+	sort.Slice(stats.Questions, func(i, j int) bool {
+		return stats.Questions[i].FlowConnectionID < stats.Questions[j].FlowConnectionID
+	})
+	return stats, nil
+}
@@ -11,6 +11,7 @@ type nodeCalculator struct {
 // Repository interface for data access
 type Repository interface {
 	GetResponseData(ctx context.Context, formID string) ([]ResponseData, error)
+	GetFlowGraph(ctx context.Context, formID string) (*FlowGraph, error)
 }
 
 // ResponseData represents raw response data for calculations
@@ -44,6 +45,11 @@ func (c *nodeCalculator) Calculate(ctx context.Context, formID string) ([]NodeMe
 		return []NodeMetrics{}, nil
 	}
 
+	graph, err := c.repo.GetFlowGraph(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Map to store metrics per node
 	nodeMetrics := make(map[string]*NodeMetrics)
 
@@ -60,8 +66,9 @@ func (c *nodeCalculator) Calculate(ctx context.Context, formID string) ([]NodeMe
 			}
 		}
 
-		// Process flow path
-		for i, nodeID := range response.FlowPath {
+		// Process flow path; a merge point is one node however it was reached
+		path := dedupePath(response.FlowPath)
+		for i, nodeID := range path {
 			// Initialize node if not exists
 			if _, exists := nodeMetrics[nodeID]; !exists {
 				nodeMetrics[nodeID] = &NodeMetrics{
@@ -88,8 +95,8 @@ func (c *nodeCalculator) Calculate(ctx context.Context, formID string) ([]NodeMe
 				node.SkipCount++
 			}
 
-			// Check if this is the last node in path (drop-off)
-			if i == len(response.FlowPath)-1 {
+			// Last node in path is a drop-off unless the flow ends there
+			if i == len(path)-1 && !graph.IsTerminal(nodeID) {
 				node.DropOffCount++
 			}
 		}
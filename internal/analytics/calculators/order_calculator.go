@@ -0,0 +1,97 @@
+package calculators
+
+import (
+	"context"
+	"sort"
+)
+
+type orderCalculator struct {
+	repo OrderRepository
+}
+
+// OrderRepository interface for responses with a recorded display order
+type OrderRepository interface {
+	GetDisplayOrders(ctx context.Context, formID string) ([]OrderedResponse, error)
+}
+
+// OrderedResponse is a response's display order (randomized block -> the
+// children it showed, in order) and the path it took
+type OrderedResponse struct {
+	Order    map[string][]string
+	FlowPath []string
+}
+
+func NewOrderCalculator(repo OrderRepository) OrderCalculator {
+	return &orderCalculator{repo: repo}
+}
+
+// Calculate counts, for every randomized block, how often each child was
+// shown at each position and chosen there
+func (c *orderCalculator) Calculate(ctx context.Context, formID string) (*OrderStats, error) {
+	responses, err := c.repo.GetDisplayOrders(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &OrderStats{Responses: len(responses), Blocks: []OrderBlockStats{}}
+	blocks := make(map[string]*OrderBlockStats)
+	children := make(map[string]map[string]*OrderChildStats)
+
+	for _, r := range responses {
+		visited := make(map[string]bool, len(r.FlowPath))
+		for _, id := range r.FlowPath {
+			visited[id] = true
+		}
+
+		for blockID, shown := range r.Order {
+			b, ok := blocks[blockID]
+			if !ok {
+				b = &OrderBlockStats{FlowConnectionID: blockID}
+				blocks[blockID] = b
+				children[blockID] = make(map[string]*OrderChildStats)
+			}
+			b.Responses++
+
+			for i, childID := range shown {
+				child, ok := children[blockID][childID]
+				if !ok {
+					child = &OrderChildStats{FlowConnectionID: childID}
+					children[blockID][childID] = child
+				}
+				chosen := visited[childID]
+				child.Shown++
+				b.Positions = count(b.Positions, i+1, chosen)
+				child.Positions = count(child.Positions, i+1, chosen)
+				if chosen {
+					child.Chosen++
+				}
+			}
+		}
+	}
+
+	for blockID, b := range blocks {
+		for _, child := range children[blockID] {
+			b.Children = append(b.Children, *child)
+		}
+		sort.Slice(b.Children, func(i, j int) bool {
+			return b.Children[i].FlowConnectionID < b.Children[j].FlowConnectionID
+		})
+		stats.Blocks = append(stats.Blocks, *b)
+	}
+	sort.Slice(stats.Blocks, func(i, j int) bool {
+		return stats.Blocks[i].FlowConnectionID < stats.Blocks[j].FlowConnectionID
+	})
+	return stats, nil
+}
+
+// count adds a showing at a position, growing the list to reach it
+func count(positions []PositionStats, position int, chosen bool) []PositionStats {
+	for len(positions) < position {
+		positions = append(positions, PositionStats{Position: len(positions) + 1})
+	}
+	positions[position-1].Shown++
+	if chosen {
+		positions[position-1].Chosen++
+	}
+	return positions
+}
@@ -0,0 +1,171 @@
+package calculators
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"smart-forms/internal/quiz"
+)
+
+// groupShare is the share of respondents in each of the upper and lower
+// groups of the discrimination index
+const groupShare = 0.27
+
+type quizCalculator struct {
+	repo QuizRepository
+}
+
+// QuizRepository interface for scored responses
+type QuizRepository interface {
+	GetQuizResults(ctx context.Context, formID string) ([]quiz.Result, error)
+}
+
+func NewQuizCalculator(repo QuizRepository) QuizCalculator {
+	return &quizCalculator{repo: repo}
+}
+
+// Calculate computes score statistics and, for every scored question, its
+// difficulty and discrimination
+func (c *quizCalculator) Calculate(ctx context.Context, formID string) (*QuizStats, error) {
+	results, err := c.repo.GetQuizResults(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &QuizStats{
+		Responses: len(results),
+		Outcomes:  make(map[string]int),
+		Items:     []QuizItemStats{},
+	}
+	if len(results) == 0 {
+		return stats, nil
+	}
+
+	// Lowest scores first, for the lower and upper groups
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Percent < results[j].Percent })
+
+	group := int(math.Round(float64(len(results)) * groupShare))
+	if group < 1 {
+		group = 1
+	}
+
+	var total float64
+	graded, passed := 0, 0
+	for _, r := range results {
+		total += r.Percent
+		if r.Passed != nil {
+			graded++
+			if *r.Passed {
+				passed++
+			}
+		}
+		if r.Outcome != "" {
+			stats.Outcomes[r.Outcome]++
+		}
+	}
+	stats.AveragePercent = total / float64(len(results))
+	if graded > 0 {
+		rate := float64(passed) / float64(graded)
+		stats.PassRate = &rate
+	}
+
+	// Per question: every attempt with its correctness and the total score
+	type attempt struct {
+		rank    int
+		correct bool
+		points  float64
+		percent float64
+	}
+	attempts := make(map[string][]attempt)
+	var order []string
+	for rank, r := range results {
+		for _, q := range r.Questions {
+			if _, ok := attempts[q.NodeID]; !ok {
+				order = append(order, q.NodeID)
+			}
+			attempts[q.NodeID] = append(attempts[q.NodeID], attempt{rank, q.Correct, q.Points, r.Percent})
+		}
+	}
+
+	for _, id := range order {
+		item := QuizItemStats{FlowConnectionID: id, Attempts: len(attempts[id])}
+		var points float64
+		scores := make([]float64, 0, item.Attempts)
+		correct := make([]bool, 0, item.Attempts)
+
+		var lower, lowerCorrect, upper, upperCorrect int
+		for _, a := range attempts[id] {
+			points += a.points
+			if a.correct {
+				item.CorrectCount++
+			}
+			scores = append(scores, a.percent)
+			correct = append(correct, a.correct)
+
+			switch {
+			case a.rank < group:
+				lower++
+				if a.correct {
+					lowerCorrect++
+				}
+			case a.rank >= len(results)-group:
+				upper++
+				if a.correct {
+					upperCorrect++
+				}
+			}
+		}
+
+		item.AvgPoints = points / float64(item.Attempts)
+		item.Difficulty = float64(item.CorrectCount) / float64(item.Attempts)
+
+		// The groups only make sense when they don't overlap
+		if 2*group <= len(results) && lower > 0 && upper > 0 {
+			d := float64(upperCorrect)/float64(upper) - float64(lowerCorrect)/float64(lower)
+			item.Discrimination = &d
+		}
+		item.PointBiserial = pointBiserial(scores, correct)
+
+		stats.Items = append(stats.Items, item)
+	}
+
+	return stats, nil
+}
+
+// pointBiserial is the correlation between a 0/1 item and the scores
+func pointBiserial(scores []float64, correct []bool) *float64 {
+	n := float64(len(scores))
+	if n < 2 {
+		return nil
+	}
+
+	var sum, sumRight float64
+	right := 0
+	for i, s := range scores {
+		sum += s
+		if correct[i] {
+			sumRight += s
+			right++
+		}
+	}
+	if right == 0 || right == len(scores) {
+		return nil
+	}
+
+	mean := sum / n
+	var variance float64
+	for _, s := range scores {
+		variance += (s - mean) * (s - mean)
+	}
+	sd := math.Sqrt(variance / n)
+	if sd == 0 {
+		return nil
+	}
+
+	p := float64(right) / n
+	meanRight := sumRight / float64(right)
+	meanWrong := (sum - sumRight) / (n - float64(right))
+	r := (meanRight - meanWrong) / sd * math.Sqrt(p*(1-p))
+	return &r
+}
@@ -0,0 +1,209 @@
+package calculators
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+type variantCalculator struct {
+	repo VariantRepository
+}
+
+// VariantRepository interface for responses served by A/B test variants
+type VariantRepository interface {
+	GetVariantResponses(ctx context.Context, formID string) ([]VariantResponse, error)
+	GetVariantKeys(ctx context.Context, formID string) ([]string, error)
+	GetFlowGraph(ctx context.Context, formID string) (*FlowGraph, error)
+}
+
+// VariantResponse is a response with the variant it was served
+type VariantResponse struct {
+	Variant        string
+	FlowPath       []string
+	TotalTimeSpent int
+}
+
+func NewVariantCalculator(repo VariantRepository) VariantCalculator {
+	return &variantCalculator{repo: repo}
+}
+
+// Calculate computes every variant's completion rate and time and tests
+// them against the control's. Variants of the running test come first, in
+// order; variants of ended tests follow by key.
+func (c *variantCalculator) Calculate(ctx context.Context, formID string) (*VariantStats, error) {
+	responses, err := c.repo.GetVariantResponses(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := c.repo.GetVariantKeys(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := c.repo.GetFlowGraph(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	type arm struct {
+		stats VariantArmStats
+		times []float64
+	}
+	arms := make(map[string]*arm)
+	order := append([]string(nil), keys...)
+	for _, key := range keys {
+		arms[key] = &arm{stats: VariantArmStats{Variant: key}}
+	}
+	var ended []string
+	for _, r := range responses {
+		a, ok := arms[r.Variant]
+		if !ok {
+			a = &arm{stats: VariantArmStats{Variant: r.Variant}}
+			arms[r.Variant] = a
+			ended = append(ended, r.Variant)
+		}
+		a.stats.Responses++
+		if len(r.FlowPath) > 0 && graph.IsTerminal(r.FlowPath[len(r.FlowPath)-1]) {
+			a.stats.Completed++
+			a.times = append(a.times, float64(r.TotalTimeSpent))
+		}
+	}
+	sort.Strings(ended)
+	order = append(order, ended...)
+
+	stats := &VariantStats{Variants: []VariantArmStats{}}
+	if len(order) == 0 {
+		return stats, nil
+	}
+	stats.Control = order[0]
+	control := arms[stats.Control]
+
+	for _, key := range order {
+		a := arms[key]
+		a.stats.CompletionRate = ratio(a.stats.Completed, a.stats.Responses)
+		a.stats.AvgTime, a.stats.StdDevTime = meanStdDev(a.times)
+		if key != stats.Control {
+			a.stats.CompletionPValue = proportionTest(
+				control.stats.Completed, control.stats.Responses, a.stats.Completed, a.stats.Responses)
+			a.stats.TimePValue = welchTest(control.times, a.times)
+		}
+		stats.Variants = append(stats.Variants, a.stats)
+	}
+	return stats, nil
+}
+
+func ratio(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// meanStdDev returns the mean and sample standard deviation (0 below 2
+// values)
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(values)-1))
+}
+
+// proportionTest is the two-sided p-value of a pooled two-proportion z-test;
+// nil when either group is empty or every (or no) response succeeded
+func proportionTest(x1, n1, x2, n2 int) *float64 {
+	if n1 == 0 || n2 == 0 {
+		return nil
+	}
+	pooled := float64(x1+x2) / float64(n1+n2)
+	if pooled == 0 || pooled == 1 {
+		return nil
+	}
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	z := (float64(x2)/float64(n2) - float64(x1)/float64(n1)) / se
+	p := math.Erfc(math.Abs(z) / math.Sqrt2)
+	return &p
+}
+
+// welchTest is the two-sided p-value of Welch's t-test for different means;
+// nil with fewer than 2 values in a group or no variance at all
+func welchTest(a, b []float64) *float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return nil
+	}
+	meanA, sdA := meanStdDev(a)
+	meanB, sdB := meanStdDev(b)
+	va, vb := sdA*sdA/float64(len(a)), sdB*sdB/float64(len(b))
+	if va+vb == 0 {
+		return nil
+	}
+	t := (meanB - meanA) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	p := incompleteBeta(df/(df+t*t), df/2, 0.5)
+	return &p
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction
+func incompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+func betaFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c := 1.0
+	d := 1 / clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		even := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 / clamp(1+even*d)
+		c = clamp(1 + even/c)
+		h *= d * c
+
+		odd := -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 / clamp(1+odd*d)
+		c = clamp(1 + odd/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -1,15 +1,21 @@
 package analytics
 
 import (
+	"smart-forms/internal/collaborators"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type AnalyticsHandler struct {
 	service *AnalyticsService
+	access  *collaborators.AccessChecker
 }
 
-func NewAnalyticsHandler(service *AnalyticsService) *AnalyticsHandler {
-	return &AnalyticsHandler{service: service}
+func NewAnalyticsHandler(service *AnalyticsService, access *collaborators.AccessChecker) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		service: service,
+		access:  access,
+	}
 }
 
 // GetAnalytics retrieves analytics for a form
@@ -18,6 +24,10 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	// TODO: Implement analytics retrieval
 	// Check if analytics exist and are fresh
 	// If not, trigger calculation
@@ -33,8 +43,13 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 // GetAnalyticsStatus checks the status of analytics calculation
 // GET /forms/:form_id/analytics/status
 func (h *AnalyticsHandler) GetAnalyticsStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	status, err := h.service.GetStatus(c.Context(), formID)
 	if err != nil {
 		return mapServiceError(err)
@@ -49,6 +64,10 @@ func (h *AnalyticsHandler) RefreshAnalytics(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	// TODO: Implement refresh logic
 	// Mark analytics as stale
 	// Trigger calculation
@@ -65,8 +84,13 @@ func (h *AnalyticsHandler) RefreshAnalytics(c *fiber.Ctx) error {
 // GetNodeAnalytics retrieves node-specific analytics
 // GET /forms/:form_id/analytics/nodes
 func (h *AnalyticsHandler) GetNodeAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	metrics, err := h.service.GetNodeMetrics(c.Context(), formID)
 	if err != nil {
 		return mapServiceError(err)
@@ -108,8 +132,13 @@ func (h *AnalyticsHandler) GetNodeAnalytics(c *fiber.Ctx) error {
 // GetPathAnalytics retrieves path-specific analytics
 // GET /forms/:form_id/analytics/paths
 func (h *AnalyticsHandler) GetPathAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	// TODO: Implement path analytics retrieval
 
 	return c.JSON(fiber.Map{
@@ -121,8 +150,13 @@ func (h *AnalyticsHandler) GetPathAnalytics(c *fiber.Ctx) error {
 // GetFlowAnalytics retrieves flow transitions for Sankey diagram
 // GET /forms/:form_id/analytics/flow
 func (h *AnalyticsHandler) GetFlowAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	flowAnalytics, err := h.service.GetFlowAnalytics(c.Context(), formID)
 	if err != nil {
 		return mapServiceError(err)
@@ -131,6 +165,100 @@ func (h *AnalyticsHandler) GetFlowAnalytics(c *fiber.Ctx) error {
 	return c.JSON(flowAnalytics)
 }
 
+// GetQuizAnalytics retrieves score statistics and question difficulty and
+// discrimination for a quiz form
+// GET /forms/:form_id/analytics/quiz
+func (h *AnalyticsHandler) GetQuizAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	quizAnalytics, err := h.service.GetQuizAnalytics(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(quizAnalytics)
+}
+
+// GetEndingAnalytics retrieves how many responses were shown each ending
+// GET /forms/:form_id/analytics/endings
+func (h *AnalyticsHandler) GetEndingAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	endingAnalytics, err := h.service.GetEndingAnalytics(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(endingAnalytics)
+}
+
+// GetOrderAnalytics retrieves how the order of randomized blocks affected
+// what respondents chose
+// GET /forms/:form_id/analytics/order
+func (h *AnalyticsHandler) GetOrderAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	orderAnalytics, err := h.service.GetOrderAnalytics(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(orderAnalytics)
+}
+
+// GetKindAnalytics summarizes the answers to questions with a kind: NPS
+// score, rating averages, matrix and ranking distributions...
+// GET /forms/:form_id/analytics/kinds
+func (h *AnalyticsHandler) GetKindAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	kindAnalytics, err := h.service.GetKindAnalytics(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(kindAnalytics)
+}
+
+// GetVariantAnalytics compares completion rate and time between the
+// variants of the form's A/B test
+// GET /forms/:form_id/analytics/variants
+func (h *AnalyticsHandler) GetVariantAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewAnalytics); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	variantAnalytics, err := h.service.GetVariantAnalytics(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(variantAnalytics)
+}
+
 func mapServiceError(err error) error {
 	switch err {
 	case ErrFormNotFound:
@@ -69,3 +69,124 @@ type FlowAnalytics struct {
 	Flows   []FlowTransition `json:"flows"`
 	Mermaid string           `json:"mermaid"`
 }
+
+// QuizAnalytics is the score summary and item analysis of a quiz form
+type QuizAnalytics struct {
+	FormID         string                `json:"form_id"`
+	Responses      int                   `json:"responses"`
+	AveragePercent float64               `json:"average_percent"`
+	PassRate       *float64              `json:"pass_rate,omitempty"`
+	Outcomes       map[string]int        `json:"outcomes"`
+	Questions      []QuizQuestionMetrics `json:"questions"`
+}
+
+// EndingAnalytics is how the form's responses are spread over its endings
+type EndingAnalytics struct {
+	FormID    string          `json:"form_id"`
+	Responses int             `json:"responses"`
+	Endings   []EndingMetrics `json:"endings"`
+	NoEnding  int             `json:"no_ending"` // responses that got no ending screen
+}
+
+// EndingMetrics counts the responses that were shown one ending
+type EndingMetrics struct {
+	Ending string  `json:"ending"`
+	Title  string  `json:"title"`
+	Count  int     `json:"count"`
+	Share  float64 `json:"share"` // of all responses
+}
+
+// OrderAnalytics is how the order randomized blocks showed their children in
+// affected what respondents chose
+type OrderAnalytics struct {
+	FormID    string                   `json:"form_id"`
+	Responses int                      `json:"responses"` // responses with a recorded order
+	Blocks    []RandomizedBlockMetrics `json:"blocks"`
+}
+
+// RandomizedBlockMetrics is the order effect of one randomized block
+type RandomizedBlockMetrics struct {
+	FlowConnectionID string                   `json:"flow_connection_id"`
+	QuestionText     string                   `json:"question_text"`
+	Version          *int                     `json:"version,omitempty"`
+	Responses        int                      `json:"responses"`
+	Positions        []PositionMetrics        `json:"positions"` // all children, by position
+	Children         []RandomizedChildMetrics `json:"children"`
+}
+
+// RandomizedChildMetrics is how often one child was shown, where, and chosen
+type RandomizedChildMetrics struct {
+	FlowConnectionID string            `json:"flow_connection_id"`
+	QuestionText     string            `json:"question_text"`
+	Shown            int               `json:"shown"`
+	Chosen           int               `json:"chosen"`
+	ChosenRate       float64           `json:"chosen_rate"`
+	AvgPosition      float64           `json:"avg_position"`
+	Positions        []PositionMetrics `json:"positions"`
+}
+
+// PositionMetrics counts showings and choices at one position (1 = first)
+type PositionMetrics struct {
+	Position   int     `json:"position"`
+	Shown      int     `json:"shown"`
+	Chosen     int     `json:"chosen"`
+	ChosenRate float64 `json:"chosen_rate"`
+}
+
+// VariantAnalytics compares the variants of a form's A/B test with the
+// control (the test's first variant)
+type VariantAnalytics struct {
+	FormID   string           `json:"form_id"`
+	Control  string           `json:"control,omitempty"`
+	Alpha    float64          `json:"alpha"` // significance level
+	Variants []VariantMetrics `json:"variants"`
+}
+
+// VariantMetrics is one variant's completion and time against the control
+type VariantMetrics struct {
+	Variant        string  `json:"variant"`
+	Responses      int     `json:"responses"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+	AvgTime        float64 `json:"avg_time"` // seconds, completed responses
+	StdDevTime     float64 `json:"stddev_time"`
+	// Differences from the control and their p-values (omitted for the
+	// control); significant when p < alpha
+	CompletionLift        *float64 `json:"completion_lift,omitempty"`
+	CompletionPValue      *float64 `json:"completion_p_value,omitempty"`
+	CompletionSignificant bool     `json:"completion_significant"`
+	TimeDiff              *float64 `json:"time_diff,omitempty"`
+	TimePValue            *float64 `json:"time_p_value,omitempty"`
+	TimeSignificant       bool     `json:"time_significant"`
+}
+
+// KindAnalytics summarizes the answers of a form's questions with a kind
+type KindAnalytics struct {
+	FormID    string                `json:"form_id"`
+	Questions []KindQuestionMetrics `json:"questions"`
+}
+
+// KindQuestionMetrics is one question's answers as its kind aggregates them:
+// the NPS score, a rating's average, a ranking's average ranks...
+type KindQuestionMetrics struct {
+	FlowConnectionID string      `json:"flow_connection_id"`
+	QuestionText     string      `json:"question_text"`
+	Version          *int        `json:"version,omitempty"`
+	Kind             string      `json:"kind"`
+	Answers          int         `json:"answers"`
+	Valid            int         `json:"valid"` // answers whose value fits the kind
+	Summary          interface{} `json:"summary"`
+}
+
+// QuizQuestionMetrics is the item analysis of one scored question
+type QuizQuestionMetrics struct {
+	FlowConnectionID string   `json:"flow_connection_id"`
+	QuestionText     string   `json:"question_text"`
+	Version          *int     `json:"version,omitempty"`
+	Attempts         int      `json:"attempts"`
+	CorrectCount     int      `json:"correct_count"`
+	AvgPoints        float64  `json:"avg_points"`
+	Difficulty       float64  `json:"difficulty"`
+	Discrimination   *float64 `json:"discrimination"`
+	PointBiserial    *float64 `json:"point_biserial"`
+}
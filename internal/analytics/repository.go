@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"smart-forms/internal/analytics/calculators"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -231,6 +233,61 @@ func (r *AnalyticsRepository) GetResponseData(ctx context.Context, formID string
 	return responses, nil
 }
 
+// GetFlowGraph returns the successors of every node the form ever had
+// (all versions, deleted drafts included), so any recorded path can be read
+func (r *AnalyticsRepository) GetFlowGraph(ctx context.Context, formID string) (*calculators.FlowGraph, error) {
+	graph := &calculators.FlowGraph{Successors: make(map[string][]string)}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, parent_id
+		FROM flow_connections
+		WHERE form_id = $1
+		ORDER BY depth_level, order_index
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		var parentID *string
+		if err := rows.Scan(&id, &parentID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := graph.Successors[id]; !ok {
+			graph.Successors[id] = []string{}
+		}
+		if parentID != nil {
+			graph.Successors[*parentID] = append(graph.Successors[*parentID], id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT source_id, target_id
+		FROM flow_edges
+		WHERE form_id = $1
+		ORDER BY source_id, order_index
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, err
+		}
+		graph.Successors[source] = append(graph.Successors[source], target)
+	}
+
+	return graph, rows.Err()
+}
+
 // EnrichFlowTransitions adds question text to flow transitions
 // TODO: OPTIMIZE - N+1 Query Problem!
 // Currently: Executes ~100+ individual queries for 50 transitions
@@ -269,6 +326,8 @@ func (r *AnalyticsRepository) EnrichFlowTransitions(ctx context.Context, transit
 		targetText := t.TargetID
 		if t.IsDropOff {
 			targetText = "Drop-off"
+		} else if t.IsCompleted {
+			targetText = "Completed"
 		} else {
 			if _, exists := nodeTexts[t.TargetID]; !exists {
 				var questionText string
@@ -296,3 +355,205 @@ func (r *AnalyticsRepository) EnrichFlowTransitions(ctx context.Context, transit
 
 	return enriched, nil
 }
+
+// GetQuizResults returns the scores of the form's scored responses
+func (r *AnalyticsRepository) GetQuizResults(ctx context.Context, formID string) ([]quiz.Result, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT quiz
+		FROM form_responses
+		WHERE form_id = $1 AND quiz IS NOT NULL
+		ORDER BY submitted_at
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []quiz.Result
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var result quiz.Result
+		if err := json.Unmarshal(data, &result); err != nil {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// GetNodeLabels returns the question text and version number of flow
+// connections, in one query
+func (r *AnalyticsRepository) GetNodeLabels(ctx context.Context, ids []string) (map[string]string, map[string]*int, error) {
+	texts := make(map[string]string, len(ids))
+	versions := make(map[string]*int, len(ids))
+	if len(ids) == 0 {
+		return texts, versions, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT fc.id, q.question_text, v.version_number
+		FROM flow_connections fc
+		JOIN questions q ON q.id = fc.question_id
+		LEFT JOIN form_versions v ON v.id = fc.version_id
+		WHERE fc.id = ANY($1::uuid[])
+	`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, text string
+		var version *int
+		if err := rows.Scan(&id, &text, &version); err != nil {
+			return nil, nil, err
+		}
+		texts[id] = text
+		versions[id] = version
+	}
+	return texts, versions, rows.Err()
+}
+
+// GetEndingCounts counts the form's responses by the ending they were shown
+// ("" = none), with the ending's title in the version they answered
+func (r *AnalyticsRepository) GetEndingCounts(ctx context.Context, formID string) ([]EndingMetrics, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT COALESCE(r.ending, ''),
+		       COALESCE((SELECT e->>'title' FROM jsonb_array_elements(v.endings) e
+		                 WHERE e->>'id' = r.ending LIMIT 1), '') AS ending_title,
+		       COUNT(*)
+		FROM form_responses r
+		LEFT JOIN form_versions v ON v.id = r.version_id
+		WHERE r.form_id = $1
+		GROUP BY 1, 2
+		ORDER BY 3 DESC
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []EndingMetrics
+	for rows.Next() {
+		var m EndingMetrics
+		if err := rows.Scan(&m.Ending, &m.Title, &m.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, m)
+	}
+	return counts, rows.Err()
+}
+
+// GetDisplayOrders returns the display order and path of the form's
+// responses that recorded one
+func (r *AnalyticsRepository) GetDisplayOrders(ctx context.Context, formID string) ([]calculators.OrderedResponse, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT display_order, flow_path
+		FROM form_responses
+		WHERE form_id = $1 AND display_order IS NOT NULL
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var responses []calculators.OrderedResponse
+	for rows.Next() {
+		var orderJSON, pathJSON []byte
+		if err := rows.Scan(&orderJSON, &pathJSON); err != nil {
+			return nil, err
+		}
+		var resp calculators.OrderedResponse
+		if err := json.Unmarshal(orderJSON, &resp.Order); err != nil {
+			continue
+		}
+		json.Unmarshal(pathJSON, &resp.FlowPath)
+		responses = append(responses, resp)
+	}
+	return responses, rows.Err()
+}
+
+// GetKindAnswers returns the values of the answers to the form's questions
+// with a kind, with the kind
+func (r *AnalyticsRepository) GetKindAnswers(ctx context.Context, formID string) ([]calculators.KindAnswer, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT ra.flow_connection_id, q.metadata->'kind', ra.answer_value
+		FROM response_answers ra
+		JOIN form_responses r ON r.id = ra.response_id
+		JOIN flow_connections fc ON fc.id = ra.flow_connection_id
+		JOIN questions q ON q.id = fc.question_id
+		WHERE r.form_id = $1 AND q.metadata ? 'kind'
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []calculators.KindAnswer
+	for rows.Next() {
+		var a calculators.KindAnswer
+		var kindJSON, valueJSON []byte
+		if err := rows.Scan(&a.FlowConnectionID, &kindJSON, &valueJSON); err != nil {
+			return nil, err
+		}
+		if a.Kind = kinds.Decode(kindJSON); a.Kind == nil {
+			continue
+		}
+		if len(valueJSON) > 0 {
+			json.Unmarshal(valueJSON, &a.Value)
+		}
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+// GetVariantResponses returns the form's responses served by an A/B test
+// variant
+func (r *AnalyticsRepository) GetVariantResponses(ctx context.Context, formID string) ([]calculators.VariantResponse, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT variant, flow_path, total_time_spent
+		FROM form_responses
+		WHERE form_id = $1 AND variant IS NOT NULL
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var responses []calculators.VariantResponse
+	for rows.Next() {
+		var resp calculators.VariantResponse
+		var pathJSON []byte
+		if err := rows.Scan(&resp.Variant, &pathJSON, &resp.TotalTimeSpent); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(pathJSON, &resp.FlowPath)
+		responses = append(responses, resp)
+	}
+	return responses, rows.Err()
+}
+
+// GetVariantKeys returns the variants of the form's running A/B test in
+// order
+func (r *AnalyticsRepository) GetVariantKeys(ctx context.Context, formID string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT key FROM form_variants WHERE form_id = $1 ORDER BY position
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
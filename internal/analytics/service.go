@@ -3,21 +3,30 @@ package analytics
 import (
 	"context"
 	"smart-forms/internal/analytics/calculators"
+	"sort"
 	"strconv"
 )
 
 type AnalyticsService struct {
-	repo            *AnalyticsRepository
-	nodeCalculator  calculators.NodeCalculator
-	flowCalculator  calculators.FlowCalculator
-	pathCalculator  calculators.PathCalculator
+	repo              *AnalyticsRepository
+	nodeCalculator    calculators.NodeCalculator
+	flowCalculator    calculators.FlowCalculator
+	pathCalculator    calculators.PathCalculator
+	quizCalculator    calculators.QuizCalculator
+	orderCalculator   calculators.OrderCalculator
+	variantCalculator calculators.VariantCalculator
+	kindCalculator    calculators.KindCalculator
 }
 
 func NewAnalyticsService(repo *AnalyticsRepository) *AnalyticsService {
 	return &AnalyticsService{
-		repo:           repo,
-		nodeCalculator: calculators.NewNodeCalculator(repo),
-		flowCalculator: calculators.NewFlowCalculator(repo),
+		repo:              repo,
+		nodeCalculator:    calculators.NewNodeCalculator(repo),
+		flowCalculator:    calculators.NewFlowCalculator(repo),
+		quizCalculator:    calculators.NewQuizCalculator(repo),
+		orderCalculator:   calculators.NewOrderCalculator(repo),
+		variantCalculator: calculators.NewVariantCalculator(repo),
+		kindCalculator:    calculators.NewKindCalculator(repo),
 		// TODO: Initialize path calculator when implementing path analytics
 		// pathCalculator:  calculators.NewPathCalculator(repo),
 	}
@@ -187,3 +196,240 @@ func (s *AnalyticsService) GetFlowAnalytics(ctx context.Context, formID string)
 		Mermaid: mermaid,
 	}, nil
 }
+
+// GetQuizAnalytics computes score statistics and question difficulty and
+// discrimination from the form's scored responses
+func (s *AnalyticsService) GetQuizAnalytics(ctx context.Context, formID string) (*QuizAnalytics, error) {
+	stats, err := s.quizCalculator.Calculate(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(stats.Items))
+	for i, item := range stats.Items {
+		ids[i] = item.FlowConnectionID
+	}
+	texts, versions, err := s.repo.GetNodeLabels(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	questions := make([]QuizQuestionMetrics, len(stats.Items))
+	for i, item := range stats.Items {
+		questions[i] = QuizQuestionMetrics{
+			FlowConnectionID: item.FlowConnectionID,
+			QuestionText:     texts[item.FlowConnectionID],
+			Version:          versions[item.FlowConnectionID],
+			Attempts:         item.Attempts,
+			CorrectCount:     item.CorrectCount,
+			AvgPoints:        item.AvgPoints,
+			Difficulty:       item.Difficulty,
+			Discrimination:   item.Discrimination,
+			PointBiserial:    item.PointBiserial,
+		}
+	}
+
+	return &QuizAnalytics{
+		FormID:         formID,
+		Responses:      stats.Responses,
+		AveragePercent: stats.AveragePercent,
+		PassRate:       stats.PassRate,
+		Outcomes:       stats.Outcomes,
+		Questions:      questions,
+	}, nil
+}
+
+// GetEndingAnalytics reports how many responses were shown each ending. An
+// ending retitled across versions is counted once, under its most common
+// title.
+func (s *AnalyticsService) GetEndingAnalytics(ctx context.Context, formID string) (*EndingAnalytics, error) {
+	counts, err := s.repo.GetEndingCounts(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EndingAnalytics{FormID: formID, Endings: []EndingMetrics{}}
+	index := make(map[string]int)
+	for _, c := range counts {
+		result.Responses += c.Count
+		if c.Ending == "" {
+			result.NoEnding += c.Count
+			continue
+		}
+		// Counts come largest first, so the first title seen is the most common
+		if i, ok := index[c.Ending]; ok {
+			result.Endings[i].Count += c.Count
+			continue
+		}
+		index[c.Ending] = len(result.Endings)
+		result.Endings = append(result.Endings, c)
+	}
+
+	sort.SliceStable(result.Endings, func(i, j int) bool {
+		return result.Endings[i].Count > result.Endings[j].Count
+	})
+	for i := range result.Endings {
+		result.Endings[i].Share = float64(result.Endings[i].Count) / float64(result.Responses)
+	}
+	return result, nil
+}
+
+// GetOrderAnalytics reports, for every randomized block, how often each
+// child was shown at each position and chosen there, so order bias shows up
+// as choice rates falling with position
+func (s *AnalyticsService) GetOrderAnalytics(ctx context.Context, formID string) (*OrderAnalytics, error) {
+	stats, err := s.orderCalculator.Calculate(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, b := range stats.Blocks {
+		ids = append(ids, b.FlowConnectionID)
+		for _, c := range b.Children {
+			ids = append(ids, c.FlowConnectionID)
+		}
+	}
+	texts, versions, err := s.repo.GetNodeLabels(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]RandomizedBlockMetrics, len(stats.Blocks))
+	for i, b := range stats.Blocks {
+		children := make([]RandomizedChildMetrics, len(b.Children))
+		for j, c := range b.Children {
+			sum := 0
+			for _, p := range c.Positions {
+				sum += p.Position * p.Shown
+			}
+			children[j] = RandomizedChildMetrics{
+				FlowConnectionID: c.FlowConnectionID,
+				QuestionText:     texts[c.FlowConnectionID],
+				Shown:            c.Shown,
+				Chosen:           c.Chosen,
+				ChosenRate:       rate(c.Chosen, c.Shown),
+				AvgPosition:      float64(sum) / float64(c.Shown),
+				Positions:        positionMetrics(c.Positions),
+			}
+		}
+		blocks[i] = RandomizedBlockMetrics{
+			FlowConnectionID: b.FlowConnectionID,
+			QuestionText:     texts[b.FlowConnectionID],
+			Version:          versions[b.FlowConnectionID],
+			Responses:        b.Responses,
+			Positions:        positionMetrics(b.Positions),
+			Children:         children,
+		}
+	}
+
+	return &OrderAnalytics{
+		FormID:    formID,
+		Responses: stats.Responses,
+		Blocks:    blocks,
+	}, nil
+}
+
+// significanceLevel is the p-value below which variant differences count
+// as significant
+const significanceLevel = 0.05
+
+// GetVariantAnalytics compares the completion rate and time of the form's
+// A/B test variants with the control's, with significance tests
+func (s *AnalyticsService) GetVariantAnalytics(ctx context.Context, formID string) (*VariantAnalytics, error) {
+	stats, err := s.variantCalculator.Calculate(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VariantAnalytics{
+		FormID:   formID,
+		Control:  stats.Control,
+		Alpha:    significanceLevel,
+		Variants: make([]VariantMetrics, len(stats.Variants)),
+	}
+	var control *calculators.VariantArmStats
+	for i := range stats.Variants {
+		if stats.Variants[i].Variant == stats.Control {
+			control = &stats.Variants[i]
+		}
+	}
+
+	for i, v := range stats.Variants {
+		m := VariantMetrics{
+			Variant:          v.Variant,
+			Responses:        v.Responses,
+			Completed:        v.Completed,
+			CompletionRate:   v.CompletionRate,
+			AvgTime:          v.AvgTime,
+			StdDevTime:       v.StdDevTime,
+			CompletionPValue: v.CompletionPValue,
+			TimePValue:       v.TimePValue,
+		}
+		if v.Variant != stats.Control && control != nil {
+			lift := v.CompletionRate - control.CompletionRate
+			m.CompletionLift = &lift
+			if v.Completed > 0 && control.Completed > 0 {
+				diff := v.AvgTime - control.AvgTime
+				m.TimeDiff = &diff
+			}
+		}
+		m.CompletionSignificant = v.CompletionPValue != nil && *v.CompletionPValue < significanceLevel
+		m.TimeSignificant = v.TimePValue != nil && *v.TimePValue < significanceLevel
+		result.Variants[i] = m
+	}
+	return result, nil
+}
+
+// GetKindAnalytics summarizes the answers of every question with a kind
+// (rating, NPS, matrix...) the way its kind does
+func (s *AnalyticsService) GetKindAnalytics(ctx context.Context, formID string) (*KindAnalytics, error) {
+	stats, err := s.kindCalculator.Calculate(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(stats.Questions))
+	for i, q := range stats.Questions {
+		ids[i] = q.FlowConnectionID
+	}
+	texts, versions, err := s.repo.GetNodeLabels(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	questions := make([]KindQuestionMetrics, len(stats.Questions))
+	for i, q := range stats.Questions {
+		questions[i] = KindQuestionMetrics{
+			FlowConnectionID: q.FlowConnectionID,
+			QuestionText:     texts[q.FlowConnectionID],
+			Version:          versions[q.FlowConnectionID],
+			Kind:             q.Kind,
+			Answers:          q.Answers,
+			Valid:            q.Valid,
+			Summary:          q.Summary,
+		}
+	}
+	return &KindAnalytics{FormID: formID, Questions: questions}, nil
+}
+
+func positionMetrics(stats []calculators.PositionStats) []PositionMetrics {
+	result := make([]PositionMetrics, len(stats))
+	for i, p := range stats {
+		result[i] = PositionMetrics{
+			Position:   p.Position,
+			Shown:      p.Shown,
+			Chosen:     p.Chosen,
+			ChosenRate: rate(p.Chosen, p.Shown),
+		}
+	}
+	return result
+}
+
+// rate is part / whole, 0 when whole is
+func rate(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	service *AuditService
+}
+
+func NewAuditHandler(service *AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListEntries lists the audit log (audit:read)
+// GET /admin/audit-log?actor_id=&subject_user_id=&action=&limit=&offset=
+func (h *AuditHandler) ListEntries(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	items, total, err := h.service.List(c.Context(), ListFilter{
+		ActorID:       c.Query("actor_id", ""),
+		SubjectUserID: c.Query("subject_user_id", ""),
+		Action:        c.Query("action", ""),
+		Limit:         limit,
+		Offset:        offset,
+	})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImpersonationLogger records every request made with an impersonation token.
+// Register after auth.JWTAuthMiddleware (it reads user_id, actor_id, impersonating).
+// No audit entry, no response: when the entry can't be written the handler's
+// result is dropped and the request fails. Impersonated requests only read
+// or dry-run (auth.BlockImpersonatedWrites), so nothing is left unaudited.
+func ImpersonationLogger(service *AuditService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		impersonating, _ := c.Locals("impersonating").(bool)
+		if !impersonating {
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fe, ok := err.(*fiber.Error); ok {
+				status = fe.Code
+			}
+		}
+
+		// Copy request values: fasthttp reuses buffers once the handler returns
+		entry := Entry{
+			ActorID:       localString(c, "actor_id"),
+			SubjectUserID: localString(c, "user_id"),
+			Action:        ActionImpersonatedRequest,
+			Method:        strPtr(c.Method()),
+			Path:          strPtr(c.OriginalURL()),
+			StatusCode:    &status,
+			IPAddress:     strPtr(c.IP()),
+			UserAgent:     strPtr(c.Get(fiber.HeaderUserAgent)),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if recordErr := service.Record(ctx, entry); recordErr != nil {
+			c.Response().ResetBody()
+			c.Response().Header.Del(fiber.HeaderContentDisposition)
+			return fiber.NewError(fiber.StatusServiceUnavailable, "Audit log unavailable, request not served")
+		}
+
+		return err
+	}
+}
+
+func localString(c *fiber.Ctx, key string) *string {
+	v, ok := c.Locals(key).(string)
+	if !ok || v == "" {
+		return nil
+	}
+	return &v
+}
+
+func strPtr(s string) *string {
+	v := string([]byte(s))
+	return &v
+}
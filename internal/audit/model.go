@@ -0,0 +1,45 @@
+package audit
+
+import "time"
+
+// Audit actions
+const (
+	ActionImpersonationStart  = "impersonation.start"
+	ActionImpersonatedRequest = "impersonation.request"
+	ActionRoleCreate          = "role.create"
+	ActionRoleUpdate          = "role.update"
+	ActionRoleDelete          = "role.delete"
+	ActionUserRoleChange      = "user.role_change"
+	ActionUserDeactivate      = "user.deactivate"
+	ActionUserReactivate      = "user.reactivate"
+	ActionUserPasswordReset   = "user.password_reset"
+	ActionUserDelete          = "user.delete"
+	ActionFormTransferStart   = "form.transfer_start"
+	ActionFormTransferAccept  = "form.transfer_accept"
+	ActionFormTransferDecline = "form.transfer_decline"
+	ActionFormTransferCancel  = "form.transfer_cancel"
+)
+
+// Entry is one audit log record
+type Entry struct {
+	ID            string                 `json:"id"`
+	ActorID       *string                `json:"actor_id,omitempty"`
+	SubjectUserID *string                `json:"subject_user_id,omitempty"`
+	Action        string                 `json:"action"`
+	Method        *string                `json:"method,omitempty"`
+	Path          *string                `json:"path,omitempty"`
+	StatusCode    *int                   `json:"status_code,omitempty"`
+	IPAddress     *string                `json:"ip_address,omitempty"`
+	UserAgent     *string                `json:"user_agent,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ListFilter narrows the audit log listing
+type ListFilter struct {
+	ActorID       string
+	SubjectUserID string
+	Action        string
+	Limit         int
+	Offset        int
+}
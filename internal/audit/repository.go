@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Insert appends an entry
+func (r *AuditRepository) Insert(ctx context.Context, e Entry) error {
+	metadata := e.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO audit_log (actor_id, subject_user_id, action, method, path,
+		                       status_code, ip_address, user_agent, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		e.ActorID, e.SubjectUserID, e.Action, e.Method, e.Path,
+		e.StatusCode, e.IPAddress, e.UserAgent, metadataJSON)
+	return err
+}
+
+// List retrieves entries matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter ListFilter) ([]Entry, int, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if filter.ActorID != "" {
+		args = append(args, filter.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	if filter.SubjectUserID != "" {
+		args = append(args, filter.SubjectUserID)
+		conditions = append(conditions, fmt.Sprintf("subject_user_id = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, actor_id, subject_user_id, action, method, path,
+		       status_code, ip_address, user_agent, metadata, created_at
+		FROM audit_log` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var metadataJSON []byte
+
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.SubjectUserID, &e.Action, &e.Method, &e.Path,
+			&e.StatusCode, &e.IPAddress, &e.UserAgent, &metadataJSON, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+
+		if err := json.Unmarshal(metadataJSON, &e.Metadata); err != nil {
+			e.Metadata = make(map[string]interface{})
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
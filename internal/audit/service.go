@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"log"
+)
+
+type AuditService struct {
+	repo *AuditRepository
+}
+
+func NewAuditService(repo *AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record appends an entry. Failures are logged and returned; callers decide
+// whether a missing audit entry should fail the operation.
+func (s *AuditService) Record(ctx context.Context, e Entry) error {
+	if err := s.repo.Insert(ctx, e); err != nil {
+		log.Printf("audit: failed to record %s: %v", e.Action, err)
+		return err
+	}
+	return nil
+}
+
+// List lists audit entries
+func (s *AuditService) List(ctx context.Context, filter ListFilter) ([]Entry, int, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.repo.List(ctx, filter)
+}
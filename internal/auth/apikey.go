@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeFormsRead       = "forms:read"
+	ScopeFormsWrite      = "forms:write"
+	ScopeResponsesRead   = "responses:read"
+	ScopeResponsesExport = "responses:export"
+	ScopeAnalyticsRead   = "analytics:read"
+)
+
+// AllScopes lists every scope an API key may carry
+var AllScopes = []string{
+	ScopeFormsRead,
+	ScopeFormsWrite,
+	ScopeResponsesRead,
+	ScopeResponsesExport,
+	ScopeAnalyticsRead,
+}
+
+// API key format: sfk_<8 char prefix>_<40 char secret>
+const (
+	apiKeyPrefix      = "sfk_"
+	apiKeyPrefixChars = 8
+
+	// Avoid a DB write on every request; last_used_at is accurate to this window
+	apiKeyTouchInterval = time.Minute
+)
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// APIKey represents a stored API key (the secret is never returned after creation)
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// APIKeyPrincipal is the identity resolved from a valid API key
+type APIKeyPrincipal struct {
+	KeyID  string
+	UserID string
+	Role   string
+	Scopes []string
+}
+
+// GenerateAPIKey returns the full key (shown once), its lookup prefix and its hash
+func GenerateAPIKey() (string, string, string, error) {
+	b := make([]byte, 30)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	encoded := strings.ToLower(apiKeyEncoding.EncodeToString(b))
+	prefix := apiKeyPrefix + encoded[:apiKeyPrefixChars]
+	key := prefix + "_" + encoded[apiKeyPrefixChars:]
+
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes a full API key. Keys are high entropy, so SHA-256 is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a bearer credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// parseAPIKeyPrefix extracts the lookup prefix from a full key
+func parseAPIKeyPrefix(key string) (string, bool) {
+	if !IsAPIKey(key) {
+		return "", false
+	}
+
+	prefixLen := len(apiKeyPrefix) + apiKeyPrefixChars
+	if len(key) <= prefixLen+1 || key[prefixLen] != '_' {
+		return "", false
+	}
+
+	return key[:prefixLen], true
+}
+
+// apiKeyHashMatches compares hashes in constant time
+func apiKeyHashMatches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(hash)) == 1
+}
+
+// isValidScope checks a scope against AllScopes
+func isValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyScope reports whether granted contains at least one of required
+func hasAnyScope(granted []string, required []string) bool {
+	for _, r := range required {
+		for _, g := range granted {
+			if g == r {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,12 @@
 package auth
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"smart-forms/internal/auth/oidc"
+)
 
 // AuthHandler holds dependencies for HTTP layer
 type AuthHandler struct {
@@ -35,6 +41,9 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		req.Password,
 	)
 	if err != nil {
+		if err == ErrPasswordResetRequired {
+			return fiber.NewError(fiber.StatusForbidden, "Password reset required")
+		}
 		return fiber.ErrUnauthorized
 	}
 
@@ -123,3 +132,425 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	})
 }
 
+
+/*
+========================
+ PASSWORD RESET
+========================
+*/
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ResetPassword sets a new password with a reset token
+// POST /auth/password/reset
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req resetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	err := h.service.ResetPassword(c.Context(), req.Token, req.NewPassword)
+	switch err {
+	case nil:
+		return c.JSON(fiber.Map{
+			"message": "password updated successfully",
+		})
+	case ErrInvalidInput:
+		return fiber.NewError(fiber.StatusBadRequest, "token and new_password are required")
+	case ErrInvalidResetToken:
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired reset token")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+/*
+========================
+ LOGIN (MFA STEP)
+========================
+*/
+
+type mfaLoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (h *AuthHandler) LoginMFA(c *fiber.Ctx) error {
+	var req mfaLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if req.MFAToken == "" {
+		return fiber.ErrBadRequest
+	}
+
+	response, err := h.service.CompleteMFALogin(
+		c.Context(),
+		req.MFAToken,
+		req.Code,
+		req.RecoveryCode,
+	)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(response)
+}
+
+/*
+========================
+ MFA MANAGEMENT
+========================
+*/
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAStatus returns MFA state for the current user
+// GET /auth/mfa
+func (h *AuthHandler) MFAStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	status, err := h.service.GetMFAStatus(c.Context(), userID)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(status)
+}
+
+// EnrollMFA starts TOTP enrollment
+// POST /auth/mfa/enroll
+func (h *AuthHandler) EnrollMFA(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	enrollment, err := h.service.EnrollMFA(c.Context(), userID)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(enrollment)
+}
+
+// VerifyMFA confirms enrollment and returns recovery codes
+// POST /auth/mfa/verify
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req mfaCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	codes, err := h.service.VerifyMFA(c.Context(), userID, req.Code)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "MFA enabled successfully",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA turns MFA off
+// POST /auth/mfa/disable
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req mfaDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	err := h.service.DisableMFA(c.Context(), userID, req.Password, req.Code, req.RecoveryCode)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "MFA disabled successfully",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes
+// POST /auth/mfa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req mfaCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.Context(), userID, req.Code)
+	if err != nil {
+		return mapMFAError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func mapMFAError(err error) error {
+	switch err {
+	case ErrInvalidCredentials, ErrInvalidMFACode, ErrUserInactive:
+		return fiber.ErrUnauthorized
+	case ErrPasswordResetRequired:
+		return fiber.NewError(fiber.StatusForbidden, "Password reset required")
+	case ErrMFALocked:
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed MFA attempts, try again later")
+	case ErrMFARequired:
+		return fiber.NewError(fiber.StatusBadRequest, "code or recovery_code is required")
+	case ErrMFANotEnrolled:
+		return fiber.NewError(fiber.StatusBadRequest, "MFA enrollment not started")
+	case ErrMFAAlreadyEnabled:
+		return fiber.NewError(fiber.StatusConflict, "MFA is already enabled")
+	case ErrMFANotEnabled:
+		return fiber.NewError(fiber.StatusConflict, "MFA is not enabled")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+/*
+========================
+ API KEYS
+========================
+*/
+
+// ListAPIKeys lists the current user's API keys
+// GET /auth/api-keys
+func (h *AuthHandler) ListAPIKeys(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	keys, err := h.service.ListAPIKeys(c.Context(), userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items":            keys,
+		"available_scopes": AllScopes,
+	})
+}
+
+// CreateAPIKey creates a new API key (the key is only returned once)
+// POST /auth/api-keys
+func (h *AuthHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	created, err := h.service.CreateAPIKey(c.Context(), userID, req)
+	if err != nil {
+		return mapAPIKeyError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// UpdateAPIKey renames a key or changes its scopes
+// PATCH /auth/api-keys/:id
+func (h *AuthHandler) UpdateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	keyID := c.Params("id")
+
+	var req UpdateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	key, err := h.service.UpdateAPIKey(c.Context(), userID, keyID, req)
+	if err != nil {
+		return mapAPIKeyError(err)
+	}
+
+	return c.JSON(key)
+}
+
+// RevokeAPIKey revokes a key
+// DELETE /auth/api-keys/:id
+func (h *AuthHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	keyID := c.Params("id")
+
+	if err := h.service.RevokeAPIKey(c.Context(), userID, keyID); err != nil {
+		return mapAPIKeyError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mapAPIKeyError(err error) error {
+	switch err {
+	case ErrInvalidInput:
+		return fiber.NewError(fiber.StatusBadRequest, "name and at least one valid scope are required; expiry must be in the future")
+	case ErrAPIKeyNotFound:
+		return fiber.ErrNotFound
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+/*
+========================
+ SOCIAL LOGIN (OIDC)
+========================
+*/
+
+type oidcCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// OIDCProviders lists configured identity providers
+// GET /auth/oidc/providers
+func (h *AuthHandler) OIDCProviders(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"items": h.service.ListOIDCProviders(),
+	})
+}
+
+// OIDCAuthorize starts a provider login. Returns the authorization URL and state,
+// or redirects straight to the provider with ?redirect=true.
+// GET /auth/oidc/:provider/authorize
+func (h *AuthHandler) OIDCAuthorize(c *fiber.Ctx) error {
+	auth, err := h.service.StartOIDCLogin(c.Context(), c.Params("provider"), nil)
+	if err != nil {
+		return mapOIDCError(err)
+	}
+
+	if c.QueryBool("redirect") {
+		return c.Redirect(auth.AuthorizationURL, fiber.StatusFound)
+	}
+
+	return c.JSON(auth)
+}
+
+// OIDCCallback exchanges the authorization code returned by the provider.
+// Responds like /auth/login (tokens or an MFA challenge), or with the linked
+// identity when the flow was started via /auth/identities/:provider/link.
+// POST /auth/oidc/:provider/callback
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	var req oidcCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	response, linked, err := h.service.CompleteOIDCLogin(c.Context(), c.Params("provider"), req.Code, req.State)
+	if err != nil {
+		return mapOIDCError(err)
+	}
+
+	if linked != nil {
+		return c.JSON(fiber.Map{
+			"linked_identity": linked,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// ListIdentities lists the current user's linked identities
+// GET /auth/identities
+func (h *AuthHandler) ListIdentities(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	identities, err := h.service.ListIdentities(c.Context(), userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items": identities,
+	})
+}
+
+// LinkIdentity starts a provider flow that links the identity to the current user
+// POST /auth/identities/:provider/link
+func (h *AuthHandler) LinkIdentity(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	auth, err := h.service.StartOIDCLogin(c.Context(), c.Params("provider"), &userID)
+	if err != nil {
+		return mapOIDCError(err)
+	}
+
+	return c.JSON(auth)
+}
+
+// UnlinkIdentity removes a linked identity
+// DELETE /auth/identities/:id
+func (h *AuthHandler) UnlinkIdentity(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.UnlinkIdentity(c.Context(), userID, c.Params("id")); err != nil {
+		return mapOIDCError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mapOIDCError(err error) error {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider), errors.Is(err, ErrIdentityNotFound):
+		return fiber.ErrNotFound
+	case errors.Is(err, ErrOIDCStateInvalid):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired login state")
+	case errors.Is(err, ErrOIDCEmailNotVerified):
+		return fiber.NewError(fiber.StatusForbidden, "A verified email address is required")
+	case errors.Is(err, ErrIdentityAlreadyLinked):
+		return fiber.NewError(fiber.StatusConflict, "This account is already linked to another user")
+	case errors.Is(err, ErrUserAlreadyExists):
+		return fiber.NewError(fiber.StatusConflict, "User already exists")
+	case errors.Is(err, ErrLastLoginMethod):
+		return fiber.NewError(fiber.StatusConflict, "Set a password or link another provider first")
+	case errors.Is(err, ErrUserInactive),
+		errors.Is(err, ErrInvalidCredentials),
+		errors.Is(err, oidc.ErrTokenExchange),
+		errors.Is(err, oidc.ErrInvalidIDToken),
+		errors.Is(err, oidc.ErrMissingIdentity):
+		return fiber.ErrUnauthorized
+	case errors.Is(err, oidc.ErrDiscovery):
+		return fiber.ErrBadGateway
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+/*
+========================
+ JWKS
+========================
+*/
+
+// JWKS publishes public keys for verifying access tokens
+// GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(c *fiber.Ctx) error {
+	ring, err := AccessKeyring()
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	// Short cache so newly added keys propagate before they become active
+	c.Set("Cache-Control", "public, max-age=300")
+
+	return c.JSON(ring.JWKS())
+}
@@ -0,0 +1,27 @@
+package auth
+
+import "time"
+
+// Lifetime of an in-flight authorization request (user must finish login within it)
+const oidcStateTTL = 10 * time.Minute
+
+// UserIdentity is an external (OIDC / OAuth2) account linked to a user
+type UserIdentity struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"-"`
+	Provider      string     `json:"provider"`
+	Subject       string     `json:"subject"`
+	Email         *string    `json:"email,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// oidcLoginState is a pending authorization request
+type oidcLoginState struct {
+	State        string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	LinkUserID   *string
+}
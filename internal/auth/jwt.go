@@ -11,58 +11,99 @@ import (
 var (
 	accessTokenTTL  = 15 * time.Minute
 	refreshTokenTTL = 7 * 24 * time.Hour
-)
+	mfaTokenTTL     = 5 * time.Minute
 
-func getAccessSecret() []byte {
-	secret := os.Getenv("ACCESS_TOKEN_SECRET")
-	if secret == "" {
-		panic("ACCESS_TOKEN_SECRET not set")
-	}
-	return []byte(secret)
-}
+	// Impersonation tokens are access tokens that cannot be refreshed
+	impersonationTokenTTL = 10 * time.Minute
+)
 
-func getRefreshSecret() []byte {
-	secret := os.Getenv("REFRESH_TOKEN_SECRET")
-	if secret == "" {
-		panic("REFRESH_TOKEN_SECRET not set")
-	}
-	return []byte(secret)
-}
+// Token purposes (empty purpose = regular access/refresh token)
+const (
+	PurposeMFA     = "mfa"
+	PurposeRefresh = "refresh"
+)
 
 // Claims defines JWT payload
 type Claims struct {
-	UserID string `json:"sub"`
-	Role   string `json:"role"`
+	UserID      string       `json:"sub"`
+	Role        string       `json:"role"`
+	Permissions []string     `json:"perms,omitempty"` // access tokens only; reloaded on refresh
+	Purpose     string       `json:"purpose,omitempty"`
+	Actor       *ActorClaims `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaims identifies who is acting on behalf of sub (RFC 8693 "act" claim)
+type ActorClaims struct {
+	UserID string `json:"sub"`
+}
+
 /*
 ========================
  TOKEN GENERATION
 ========================
 */
 
-func GenerateAccessToken(userID, role string) (string, error) {
-	return generateToken(userID, role, getAccessSecret(), accessTokenTTL)
+func GenerateAccessToken(userID, role string, permissions []string) (string, error) {
+	claims := newClaims(userID, role, accessTokenTTL)
+	claims.Permissions = permissions
+
+	return signWith(AccessKeyring, claims)
 }
 
 func GenerateRefreshToken(userID, role string) (string, error) {
-	return generateToken(userID, role, getRefreshSecret(), refreshTokenTTL)
+	// Purpose keeps refresh tokens out of access checks even if both keyrings share a key
+	claims := newClaims(userID, role, refreshTokenTTL)
+	claims.Purpose = PurposeRefresh
+
+	return signWith(RefreshKeyring, claims)
+}
+
+// GenerateMFAToken issues a short-lived challenge token after password check.
+// It cannot be used as an access token (see ValidateAccessToken). jti lets
+// the caller make it single use.
+func GenerateMFAToken(userID, role, jti string) (string, error) {
+	claims := newClaims(userID, role, mfaTokenTTL)
+	claims.Purpose = PurposeMFA
+	claims.ID = jti
+
+	return signWith(AccessKeyring, claims)
+}
+
+// GenerateImpersonationToken issues a short-lived access token for targetUserID
+// that records actorID as the acting admin. No refresh token is issued and no
+// permissions are carried: admin powers never flow through impersonation.
+func GenerateImpersonationToken(targetUserID, targetRole, actorID string) (string, time.Time, error) {
+	claims := newClaims(targetUserID, targetRole, impersonationTokenTTL)
+	claims.Actor = &ActorClaims{UserID: actorID}
+
+	token, err := signWith(AccessKeyring, claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, claims.ExpiresAt.Time, nil
 }
 
+func signWith(keyring func() (*Keyring, error), claims Claims) (string, error) {
+	ring, err := keyring()
+	if err != nil {
+		return "", err
+	}
+	return ring.Sign(claims)
+}
 
-func generateToken(userID, role string, secret []byte, ttl time.Duration) (string, error) {
-	claims := Claims{
+func newClaims(userID, role string, ttl time.Duration) Claims {
+	return Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// Lets downstream services verifying via JWKS check the issuer
+			Issuer:    os.Getenv("JWT_ISSUER"),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
 }
 
 /*
@@ -73,7 +114,31 @@ func generateToken(userID, role string, secret []byte, ttl time.Duration) (strin
 
 // ValidateAccessToken validates access token
 func ValidateAccessToken(tokenStr string) (*Claims, error) {
-	return validateToken(tokenStr, getAccessSecret())
+	claims, err := validateToken(tokenStr, AccessKeyring)
+	if err != nil {
+		return nil, err
+	}
+
+	// Challenge tokens share the signing key but must never grant access
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid token purpose")
+	}
+
+	return claims, nil
+}
+
+// ValidateMFAToken validates an MFA challenge token
+func ValidateMFAToken(tokenStr string) (*Claims, error) {
+	claims, err := validateToken(tokenStr, AccessKeyring)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != PurposeMFA {
+		return nil, errors.New("invalid token purpose")
+	}
+
+	return claims, nil
 }
 
 // ValidateRefreshToken validates refresh token
@@ -82,21 +147,32 @@ func ValidateRefreshToken(tokenStr string) (*Claims, error) {
 	// 1. Check Redis blacklist
 	// 2. Reject if token revoked
 
-	return validateToken(tokenStr, getRefreshSecret())
+	claims, err := validateToken(tokenStr, RefreshKeyring)
+	if err != nil {
+		return nil, err
+	}
+
+	// Access tokens have no purpose: accepting one here would let it be
+	// traded for new tokens when both keyrings share a key
+	if claims.Purpose != PurposeRefresh {
+		return nil, errors.New("invalid token purpose")
+	}
+
+	// Impersonation tokens must never be extended via refresh
+	if claims.Actor != nil {
+		return nil, errors.New("impersonation tokens cannot be refreshed")
+	}
+
+	return claims, nil
 }
 
-func validateToken(tokenStr string, secret []byte) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		&Claims{},
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return secret, nil
-		},
-	)
+func validateToken(tokenStr string, keyring func() (*Keyring, error)) (*Claims, error) {
+	ring, err := keyring()
+	if err != nil {
+		return nil, err
+	}
 
+	token, err := ring.Parse(tokenStr, &Claims{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,395 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// Tokens issued before key rotation carry no kid header; they are
+	// verified against the key with this id (if configured).
+	defaultKID = "default"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrKeyRetired     = errors.New("signing key retired")
+	ErrKeyringInvalid = errors.New("invalid keyring configuration")
+)
+
+/*
+========================
+ CONFIGURATION
+========================
+*/
+
+// keyringConfig is the JSON file format referenced by ACCESS_TOKEN_KEYS_FILE /
+// REFRESH_TOKEN_KEYS_FILE
+type keyringConfig struct {
+	ActiveKID string      `json:"active_kid"`
+	Keys      []keyConfig `json:"keys"`
+}
+
+type keyConfig struct {
+	KID            string     `json:"kid"`
+	Alg            string     `json:"alg"`
+	SecretEnv      string     `json:"secret_env,omitempty"`       // HS256: env var holding the secret
+	PrivateKeyFile string     `json:"private_key_file,omitempty"` // RS256/EdDSA: PEM (PKCS#1/PKCS#8)
+	PublicKeyFile  string     `json:"public_key_file,omitempty"`  // RS256/EdDSA: PEM, for verify-only keys
+	RetireAt       *time.Time `json:"retire_at,omitempty"`        // stop accepting tokens after this time
+}
+
+/*
+========================
+ KEYRING
+========================
+*/
+
+// SigningKey is one entry of a keyring
+type SigningKey struct {
+	KID       string
+	Algorithm string
+	RetireAt  *time.Time
+
+	method    jwt.SigningMethod
+	signKey   interface{} // nil for verify-only keys
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds private material
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// IsRetired reports whether the key no longer verifies tokens
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return k.RetireAt != nil && !now.Before(*k.RetireAt)
+}
+
+// Keyring holds the active signing key and all keys still valid for verification
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+// Sign signs claims with the active key and sets the kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.KID
+	return token.SignedString(k.active.signKey)
+}
+
+// Parse verifies a token against the key named by its kid header
+func (k *Keyring) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(
+		tokenStr,
+		claims,
+		k.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
+	)
+}
+
+func (k *Keyring) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = defaultKID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	// The token's alg must match the key's configured alg (prevents alg confusion)
+	if t.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	if key.IsRetired(time.Now()) {
+		return nil, ErrKeyRetired
+	}
+
+	return key.verifyKey, nil
+}
+
+// ActiveKID returns the id of the signing key
+func (k *Keyring) ActiveKID() string {
+	return k.active.KID
+}
+
+// Keys returns all keys in configuration order
+func (k *Keyring) Keys() []*SigningKey {
+	result := make([]*SigningKey, 0, len(k.order))
+	for _, kid := range k.order {
+		result = append(result, k.keys[kid])
+	}
+	return result
+}
+
+/*
+========================
+ JWKS
+========================
+*/
+
+// JWK is a JSON Web Key (RFC 7517) for a public verification key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the /.well-known/jwks.json document
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes every non-retired asymmetric key. HS256 secrets are never published.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+
+	for _, key := range k.Keys() {
+		if key.IsRetired(now) {
+			continue
+		}
+
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.KID,
+				Alg: key.Algorithm,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.KID,
+				Alg: key.Algorithm,
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return set
+}
+
+/*
+========================
+ LOADING
+========================
+*/
+
+var (
+	keyringsOnce   sync.Once
+	keyringsErr    error
+	accessKeyring  *Keyring
+	refreshKeyring *Keyring
+)
+
+// LoadKeyrings loads access and refresh keyrings from the environment.
+// Call once at startup so misconfiguration fails fast instead of on first login.
+func LoadKeyrings() error {
+	keyringsOnce.Do(func() {
+		accessKeyring, keyringsErr = loadKeyring("ACCESS_TOKEN_KEYS_FILE", "ACCESS_TOKEN_ACTIVE_KID", "ACCESS_TOKEN_SECRET")
+		if keyringsErr != nil {
+			keyringsErr = fmt.Errorf("access keyring: %w", keyringsErr)
+			return
+		}
+
+		refreshKeyring, keyringsErr = loadKeyring("REFRESH_TOKEN_KEYS_FILE", "REFRESH_TOKEN_ACTIVE_KID", "REFRESH_TOKEN_SECRET")
+		if keyringsErr != nil {
+			keyringsErr = fmt.Errorf("refresh keyring: %w", keyringsErr)
+		}
+	})
+	return keyringsErr
+}
+
+// AccessKeyring returns the keyring used for access (and MFA challenge) tokens
+func AccessKeyring() (*Keyring, error) {
+	if err := LoadKeyrings(); err != nil {
+		return nil, err
+	}
+	return accessKeyring, nil
+}
+
+// RefreshKeyring returns the keyring used for refresh tokens
+func RefreshKeyring() (*Keyring, error) {
+	if err := LoadKeyrings(); err != nil {
+		return nil, err
+	}
+	return refreshKeyring, nil
+}
+
+// loadKeyring reads a keyring file, or falls back to a single HS256 secret
+// (kid "default") so existing deployments keep working unchanged.
+func loadKeyring(fileEnv, activeEnv, legacySecretEnv string) (*Keyring, error) {
+	var cfg keyringConfig
+
+	if path := os.Getenv(fileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyringInvalid, err)
+		}
+	} else {
+		cfg = keyringConfig{
+			ActiveKID: defaultKID,
+			Keys: []keyConfig{
+				{KID: defaultKID, Alg: AlgHS256, SecretEnv: legacySecretEnv},
+			},
+		}
+	}
+
+	if active := os.Getenv(activeEnv); active != "" {
+		cfg.ActiveKID = active
+	}
+
+	return buildKeyring(cfg)
+}
+
+func buildKeyring(cfg keyringConfig) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string]*SigningKey)}
+
+	for _, kc := range cfg.Keys {
+		if kc.KID == "" {
+			return nil, fmt.Errorf("%w: key without kid", ErrKeyringInvalid)
+		}
+		if _, dup := ring.keys[kc.KID]; dup {
+			return nil, fmt.Errorf("%w: duplicate kid %q", ErrKeyringInvalid, kc.KID)
+		}
+
+		key, err := buildSigningKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kc.KID, err)
+		}
+
+		ring.keys[kc.KID] = key
+		ring.order = append(ring.order, kc.KID)
+	}
+
+	active, ok := ring.keys[cfg.ActiveKID]
+	if !ok {
+		return nil, fmt.Errorf("%w: active kid %q not found", ErrKeyringInvalid, cfg.ActiveKID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("%w: active kid %q has no private key", ErrKeyringInvalid, cfg.ActiveKID)
+	}
+	if active.IsRetired(time.Now()) {
+		return nil, fmt.Errorf("%w: active kid %q is retired", ErrKeyringInvalid, cfg.ActiveKID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+func buildSigningKey(kc keyConfig) (*SigningKey, error) {
+	key := &SigningKey{
+		KID:       kc.KID,
+		Algorithm: kc.Alg,
+		RetireAt:  kc.RetireAt,
+	}
+
+	switch kc.Alg {
+	case AlgHS256:
+		secret := os.Getenv(kc.SecretEnv)
+		if kc.SecretEnv == "" || secret == "" {
+			return nil, fmt.Errorf("%w: secret_env %q not set", ErrKeyringInvalid, kc.SecretEnv)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(secret)
+		key.verifyKey = []byte(secret)
+
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		} else if kc.PublicKeyFile != "" {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		} else {
+			return nil, fmt.Errorf("%w: private_key_file or public_key_file required", ErrKeyringInvalid)
+		}
+
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = priv
+			key.verifyKey = priv.(crypto.Signer).Public()
+		} else if kc.PublicKeyFile != "" {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		} else {
+			return nil, fmt.Errorf("%w: private_key_file or public_key_file required", ErrKeyringInvalid)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrKeyringInvalid, kc.Alg)
+	}
+
+	return key, nil
+}
@@ -1,27 +1,52 @@
 package auth
 
 import (
+	"context"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// JWTAuthMiddleware validates access token and injects user_id
-func JWTAuthMiddleware() fiber.Handler {
+// Authentication methods stored in c.Locals("auth_method")
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// APIKeyAuthenticator resolves API keys (implemented by AuthService)
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error)
+}
+
+// JWTAuthMiddleware validates access token (or API key) and injects user_id.
+// Pass nil to accept JWTs only.
+func JWTAuthMiddleware(apiKeys APIKeyAuthenticator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
+		credential := extractCredential(c)
+		if credential == "" {
 			return fiber.ErrUnauthorized
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			return fiber.ErrUnauthorized
-		}
+		if IsAPIKey(credential) {
+			if apiKeys == nil {
+				return fiber.ErrUnauthorized
+			}
+
+			principal, err := apiKeys.AuthenticateAPIKey(c.Context(), credential)
+			if err != nil {
+				return fiber.ErrUnauthorized
+			}
 
-		tokenString := parts[1]
+			c.Locals("user_id", principal.UserID)
+			c.Locals("user_role", principal.Role)
+			c.Locals("auth_method", AuthMethodAPIKey)
+			c.Locals("api_key_id", principal.KeyID)
+			c.Locals("api_key_scopes", principal.Scopes)
 
-		claims, err := ValidateAccessToken(tokenString)
+			return c.Next()
+		}
+
+		claims, err := ValidateAccessToken(credential)
 		if err != nil {
 			return fiber.ErrUnauthorized
 		}
@@ -35,12 +60,137 @@ func JWTAuthMiddleware() fiber.Handler {
 			role = "user"
 		}
 		c.Locals("user_role", role)
+		c.Locals("user_permissions", claims.Permissions)
+		c.Locals("auth_method", AuthMethodJWT)
+
+		// Impersonation: user_id is the customer, actor_id the admin behind the request
+		if claims.Actor != nil && claims.Actor.UserID != "" {
+			c.Locals("actor_id", claims.Actor.UserID)
+			c.Locals("impersonating", true)
+		}
+
+		return c.Next()
+	}
+}
+
+// IsImpersonating reports whether the request uses an impersonation token
+func IsImpersonating(c *fiber.Ctx) bool {
+	impersonating, _ := c.Locals("impersonating").(bool)
+	return impersonating
+}
+
+// extractCredential reads "Authorization: Bearer <token>" or "X-API-Key: <key>"
+func extractCredential(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Get("X-API-Key")); key != "" {
+		return key
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return ""
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// RequireScope ensures API key requests carry at least one of the given scopes.
+// JWT (interactive) sessions are not scope-limited.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("auth_method") != AuthMethodAPIKey {
+			return c.Next()
+		}
 
+		granted, _ := c.Locals("api_key_scopes").([]string)
+		if !hasAnyScope(granted, scopes) {
+			return fiber.NewError(fiber.StatusForbidden, "API key is missing required scope: "+strings.Join(scopes, " or "))
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireSession rejects API key authentication (account and admin routes).
+// Impersonation tokens are rejected too: admins must not manage a customer's
+// MFA, API keys or linked identities.
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("auth_method") == AuthMethodAPIKey {
+			return fiber.NewError(fiber.StatusForbidden, "API keys cannot access this route")
+		}
+		if IsImpersonating(c) {
+			return fiber.NewError(fiber.StatusForbidden, "Not allowed while impersonating")
+		}
+		return c.Next()
+	}
+}
+
+// BlockImpersonatedWrites rejects requests made with an impersonation token
+// that may change data: every method but GET, HEAD and OPTIONS, unless the
+// route is allowed ("POST /forms/:form_id/flow/validate"; ":name" matches
+// one path segment). Apply to the protected group so new mutating routes
+// are blocked by default.
+func BlockImpersonatedWrites(allowed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsImpersonating(c) {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		for _, route := range allowed {
+			if matchRoute(route, c.Method(), c.Path()) {
+				return c.Next()
+			}
+		}
+		return fiber.NewError(fiber.StatusForbidden, "Destructive operations are blocked while impersonating")
+	}
+}
+
+// matchRoute reports whether a request matches a "METHOD /path/:param" route
+func matchRoute(route, method, path string) bool {
+	routeMethod, routePath, ok := strings.Cut(route, " ")
+	if !ok || routeMethod != method {
+		return false
+	}
+	want := strings.Split(strings.Trim(routePath, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if strings.HasPrefix(want[i], ":") {
+			if got[i] == "" {
+				return false
+			}
+		} else if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RequirePermission ensures the access token grants perm.
+// API keys never carry permissions (they cannot reach admin routes).
+func RequirePermission(perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted, _ := c.Locals("user_permissions").([]string)
+		if !hasPermission(granted, perm) {
+			return fiber.NewError(fiber.StatusForbidden, "Missing permission: "+perm)
+		}
 		return c.Next()
 	}
 }
 
-// RequireSuperAdmin middleware ensures user has super_admin role
+// RequireSuperAdmin middleware ensures user has super_admin role.
+//
+// Deprecated: use RequirePermission with a specific permission.
 func RequireSuperAdmin() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		role, ok := c.Locals("user_role").(string)
@@ -0,0 +1,146 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Provider types
+const (
+	TypeOIDC   = "oidc"   // any OpenID Connect provider with discovery (Google, Okta, Keycloak, ...)
+	TypeGitHub = "github" // GitHub OAuth2 (no discovery, no ID token)
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidConfig   = errors.New("invalid identity provider configuration")
+)
+
+// ProviderConfig configures one identity provider
+type ProviderConfig struct {
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name"`
+	Type            string   `json:"type"`
+	Issuer          string   `json:"issuer,omitempty"`
+	ClientID        string   `json:"client_id"`
+	ClientSecretEnv string   `json:"client_secret_env"`
+	RedirectURL     string   `json:"redirect_url"`
+	Scopes          []string `json:"scopes,omitempty"`
+
+	clientSecret string
+}
+
+type providersFile struct {
+	Providers []ProviderConfig `json:"providers"`
+}
+
+// LoadProviders reads provider configuration from OIDC_PROVIDERS_FILE.
+// Returns an empty registry when the variable is not set (social login disabled).
+func LoadProviders() (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider)}
+
+	path := os.Getenv("OIDC_PROVIDERS_FILE")
+	if path == "" {
+		return registry, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file providersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	for _, cfg := range file.Providers {
+		if err := cfg.normalize(); err != nil {
+			return nil, fmt.Errorf("provider %q: %w", cfg.Name, err)
+		}
+		if _, dup := registry.providers[cfg.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate provider %q", ErrInvalidConfig, cfg.Name)
+		}
+
+		registry.providers[cfg.Name] = newProvider(cfg)
+		registry.order = append(registry.order, cfg.Name)
+	}
+
+	return registry, nil
+}
+
+func (c *ProviderConfig) normalize() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || c.ClientID == "" || c.RedirectURL == "" {
+		return fmt.Errorf("%w: name, client_id and redirect_url are required", ErrInvalidConfig)
+	}
+
+	if c.DisplayName == "" {
+		c.DisplayName = c.Name
+	}
+
+	if c.ClientSecretEnv != "" {
+		c.clientSecret = os.Getenv(c.ClientSecretEnv)
+		if c.clientSecret == "" {
+			return fmt.Errorf("%w: %s not set", ErrInvalidConfig, c.ClientSecretEnv)
+		}
+	}
+
+	switch c.Type {
+	case TypeOIDC:
+		if c.Issuer == "" {
+			return fmt.Errorf("%w: issuer is required", ErrInvalidConfig)
+		}
+		c.Issuer = strings.TrimRight(c.Issuer, "/")
+		if len(c.Scopes) == 0 {
+			c.Scopes = []string{"openid", "email", "profile"}
+		}
+	case TypeGitHub:
+		if len(c.Scopes) == 0 {
+			c.Scopes = []string{"read:user", "user:email"}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported type %q", ErrInvalidConfig, c.Type)
+	}
+
+	return nil
+}
+
+// Registry holds all configured providers
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+// Get returns a provider by name
+func (r *Registry) Get(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// ProviderInfo is the public description of a provider (for login buttons)
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// List returns public provider info in configuration order
+func (r *Registry) List() []ProviderInfo {
+	result := make([]ProviderInfo, 0, len(r.order))
+	for _, name := range r.order {
+		cfg := r.providers[name].config
+		result = append(result, ProviderInfo{
+			Name:        cfg.Name,
+			DisplayName: cfg.DisplayName,
+			Type:        cfg.Type,
+		})
+	}
+	return result
+}
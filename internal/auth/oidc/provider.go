@@ -0,0 +1,327 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHub endpoints (GitHub does not publish OIDC discovery for user login)
+const (
+	githubAuthURL   = "https://github.com/login/oauth/authorize"
+	githubTokenURL  = "https://github.com/login/oauth/access_token"
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+
+	discoveryTTL = time.Hour
+)
+
+var (
+	ErrDiscovery       = errors.New("identity provider discovery failed")
+	ErrTokenExchange   = errors.New("authorization code exchange failed")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+	ErrMissingIdentity = errors.New("identity provider returned no subject")
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Identity is the normalized user identity returned by any provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// discoveryDocument is the subset of /.well-known/openid-configuration we use
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider performs the authorization code flow against one identity provider
+type Provider struct {
+	config ProviderConfig
+
+	mu          sync.Mutex
+	discovery   *discoveryDocument
+	discoveryAt time.Time
+	keys        *keySet
+}
+
+func newProvider(cfg ProviderConfig) *Provider {
+	return &Provider{config: cfg}
+}
+
+// Name returns the configured provider name
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+/*
+========================
+ DISCOVERY
+========================
+*/
+
+// discover fetches (and caches) the provider's OpenID configuration
+func (p *Provider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveryAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, p.config.Issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+
+	// The issuer in the document must match the configured issuer exactly
+	if strings.TrimRight(doc.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch (%s)", ErrDiscovery, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrDiscovery)
+	}
+
+	if p.keys == nil || p.keys.uri != doc.JWKSURI {
+		p.keys = newKeySet(doc.JWKSURI)
+	}
+	p.discovery = &doc
+	p.discoveryAt = time.Now()
+
+	return p.discovery, nil
+}
+
+/*
+========================
+ AUTHORIZATION CODE FLOW
+========================
+*/
+
+// AuthCodeURL builds the authorization URL for a login attempt
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	authURL := githubAuthURL
+	if p.config.Type == TypeOIDC {
+		doc, err := p.discover(ctx)
+		if err != nil {
+			return "", err
+		}
+		authURL = doc.AuthorizationEndpoint
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+	if p.config.Type == TypeOIDC {
+		params.Set("nonce", nonce)
+	}
+
+	sep := "?"
+	if strings.Contains(authURL, "?") {
+		sep = "&"
+	}
+	return authURL + sep + params.Encode(), nil
+}
+
+// tokenResponse is the token endpoint response
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems an authorization code and returns the verified identity
+func (p *Provider) Exchange(ctx context.Context, code, nonce, codeVerifier string) (*Identity, error) {
+	switch p.config.Type {
+	case TypeOIDC:
+		return p.exchangeOIDC(ctx, code, nonce, codeVerifier)
+	case TypeGitHub:
+		return p.exchangeGitHub(ctx, code, codeVerifier)
+	default:
+		return nil, ErrInvalidConfig
+	}
+}
+
+func (p *Provider) exchangeOIDC(ctx context.Context, code, nonce, codeVerifier string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := p.redeemCode(ctx, doc.TokenEndpoint, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if tok.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrTokenExchange)
+	}
+
+	p.mu.Lock()
+	keys := p.keys
+	p.mu.Unlock()
+
+	claims, err := verifyIDToken(ctx, keys, tok.IDToken, p.config.Issuer, p.config.ClientID, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+
+	// Some providers only return email via userinfo
+	if identity.Email == "" && doc.UserinfoEndpoint != "" && tok.AccessToken != "" {
+		var info idTokenClaims
+		if err := getJSON(ctx, doc.UserinfoEndpoint, tok.AccessToken, &info); err == nil && info.Subject == claims.Subject {
+			identity.Email = strings.ToLower(strings.TrimSpace(info.Email))
+			identity.EmailVerified = bool(info.EmailVerified)
+			if identity.Name == "" {
+				identity.Name = info.Name
+			}
+		}
+	}
+
+	if identity.Subject == "" {
+		return nil, ErrMissingIdentity
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) exchangeGitHub(ctx context.Context, code, codeVerifier string) (*Identity, error) {
+	tok, err := p.redeemCode(ctx, githubTokenURL, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(ctx, githubUserURL, tok.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	if user.ID == 0 {
+		return nil, ErrMissingIdentity
+	}
+
+	// The public profile email is unverified; use the primary verified address instead
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, githubEmailsURL, tok.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+
+	identity := &Identity{
+		Provider: p.config.Name,
+		Subject:  fmt.Sprintf("%d", user.ID),
+		Name:     user.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = strings.ToLower(e.Email)
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
+
+// redeemCode posts the authorization code and PKCE verifier to the token endpoint
+func (p *Provider) redeemCode(ctx context.Context, endpoint, code, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.clientSecret != "" {
+		form.Set("client_secret", p.config.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var tok tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+
+	// GitHub reports errors with HTTP 200 and an error field
+	if resp.StatusCode != http.StatusOK || tok.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrTokenExchange, tok.Error, tok.ErrorDescription)
+	}
+	if tok.AccessToken == "" && tok.IDToken == "" {
+		return nil, fmt.Errorf("%w: empty token response", ErrTokenExchange)
+	}
+
+	return &tok, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response
+func getJSON(ctx context.Context, endpoint, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
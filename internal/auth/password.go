@@ -1,6 +1,14 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // HashPassword hashes a plain password using bcrypt
 func HashPassword(password string) (string, error) {
@@ -22,3 +30,21 @@ func VerifyPassword(password string, hash string) bool {
 	)
 	return err == nil
 }
+
+// GeneratePasswordResetToken returns a one-time reset token (handed to the user)
+// and its hash (stored)
+func GeneratePasswordResetToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken hashes a reset token. Tokens are high entropy, so SHA-256 is sufficient.
+func HashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
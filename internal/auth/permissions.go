@@ -0,0 +1,22 @@
+package auth
+
+// Permissions granted through roles (catalog seeded by migrations)
+const (
+	PermPlansWrite        = "plans:write"
+	PermTemplatesModerate = "templates:moderate"
+	PermUsersManage       = "users:manage"
+	PermUsersImpersonate  = "users:impersonate"
+	PermAuditRead         = "audit:read"
+	PermRolesManage       = "roles:manage"
+	PermFormsTransfer     = "forms:transfer"
+)
+
+// hasPermission reports whether granted contains perm
+func hasPermission(granted []string, perm string) bool {
+	for _, g := range granted {
+		if g == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,10 @@ package auth
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,7 +16,15 @@ type User struct {
 	Email        string
 	PasswordHash string
 	IsActive     bool
-	Role         string // 'user' or 'super_admin'
+	Role         string // roles.name, e.g. 'user' or 'super_admin'
+
+	MFAEnabled      bool
+	MFASecret       *string
+	MFALastUsedStep int64
+	MFALockedUntil  *time.Time // too many failed second factor attempts
+
+	PasswordResetRequired bool
+	TokensValidAfter      *time.Time // refresh tokens issued earlier are revoked
 }
 
 // AuthRepository handles raw SQL for auth
@@ -57,14 +67,39 @@ func (r *AuthRepository) GetUserByEmail(
 	email string,
 ) (*User, error) {
 
-	const query = `
-		SELECT id, email, password_hash, is_active, role
+	query := `
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE email = $1
 	`
 
-	row := r.db.QueryRow(ctx, query, email)
+	return scanUser(r.db.QueryRow(ctx, query, email))
+}
+
+/*
+========================
+ GET USER BY ID
+========================
+*/
+func (r *AuthRepository) GetUserByID(
+	ctx context.Context,
+	userID string,
+) (*User, error) {
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1
+	`
+
+	return scanUser(r.db.QueryRow(ctx, query, userID))
+}
 
+const userColumns = `id, email, password_hash, is_active, role,
+		mfa_enabled, mfa_secret, mfa_last_used_step, mfa_locked_until,
+		password_reset_required, tokens_valid_after`
+
+func scanUser(row pgx.Row) (*User, error) {
 	var user User
 	err := row.Scan(
 		&user.ID,
@@ -72,6 +107,12 @@ func (r *AuthRepository) GetUserByEmail(
 		&user.PasswordHash,
 		&user.IsActive,
 		&user.Role,
+		&user.MFAEnabled,
+		&user.MFASecret,
+		&user.MFALastUsedStep,
+		&user.MFALockedUntil,
+		&user.PasswordResetRequired,
+		&user.TokensValidAfter,
 	)
 
 	if err != nil {
@@ -104,3 +145,819 @@ func (r *AuthRepository) UpdateUserRole(
 	_, err := r.db.Exec(ctx, query, role, userID)
 	return err
 }
+
+/*
+========================
+ PERMISSIONS
+========================
+*/
+
+// GetRolePermissions lists the permissions granted by a role (sorted)
+func (r *AuthRepository) GetRolePermissions(
+	ctx context.Context,
+	role string,
+) ([]string, error) {
+
+	const query = `
+		SELECT permission
+		FROM role_permissions
+		WHERE role_name = $1
+		ORDER BY permission
+	`
+
+	rows, err := r.db.Query(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+/*
+========================
+ PASSWORD RESET
+========================
+*/
+
+// ResetPasswordByToken sets a new password for the holder of a valid reset token.
+// Clears the reset flag and revokes existing refresh tokens. Returns false if the
+// token is unknown or expired.
+func (r *AuthRepository) ResetPasswordByToken(
+	ctx context.Context,
+	tokenHash string,
+	passwordHash string,
+) (bool, error) {
+
+	const query = `
+		UPDATE users
+		SET password_hash = $2,
+		    password_reset_required = false,
+		    password_reset_token_hash = NULL,
+		    password_reset_expires_at = NULL,
+		    tokens_valid_after = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE password_reset_token_hash = $1
+		  AND password_reset_expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+	`
+
+	cmd, err := r.db.Exec(ctx, query, tokenHash, passwordHash)
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.RowsAffected() == 1, nil
+}
+
+/*
+========================
+ MFA
+========================
+*/
+
+// SetPendingMFASecret stores a secret for an enrollment that is not yet verified
+func (r *AuthRepository) SetPendingMFASecret(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+
+	const query = `
+		UPDATE users
+		SET mfa_secret = $1, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $2 AND mfa_enabled = false
+	`
+
+	cmd, err := r.db.Exec(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableMFA turns on MFA and replaces recovery codes in one transaction
+func (r *AuthRepository) EnableMFA(
+	ctx context.Context,
+	userID string,
+	usedStep int64,
+	codeHashes []string,
+) error {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET mfa_enabled = true,
+		    mfa_last_used_step = $1,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $2
+	`, usedStep, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DisableMFA clears the secret and all recovery codes
+func (r *AuthRepository) DisableMFA(
+	ctx context.Context,
+	userID string,
+) error {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET mfa_enabled = false,
+		    mfa_secret = NULL,
+		    mfa_last_used_step = 0,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReplaceRecoveryCodes invalidates old recovery codes and stores new ones
+func (r *AuthRepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID string,
+	codeHashes []string,
+) error {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AdvanceMFAStep records the last accepted TOTP step.
+// Returns false if the step was already used (replayed code).
+func (r *AuthRepository) AdvanceMFAStep(
+	ctx context.Context,
+	userID string,
+	step int64,
+) (bool, error) {
+
+	const query = `
+		UPDATE users
+		SET mfa_last_used_step = $1
+		WHERE id = $2 AND mfa_last_used_step < $1
+	`
+
+	cmd, err := r.db.Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.RowsAffected() > 0, nil
+}
+
+// RecordMFAFailure counts a failed second factor attempt. Reaching
+// maxAttempts locks MFA until lockedUntil and starts the count again.
+func (r *AuthRepository) RecordMFAFailure(
+	ctx context.Context,
+	userID string,
+	maxAttempts int,
+	lockedUntil time.Time,
+) error {
+
+	const query = `
+		UPDATE users
+		SET mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN 0
+		                               ELSE mfa_failed_attempts + 1 END,
+		    mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN $3
+		                            ELSE mfa_locked_until END
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, userID, maxAttempts, lockedUntil)
+	return err
+}
+
+// ResetMFAFailures clears the failed attempts after a valid second factor
+func (r *AuthRepository) ResetMFAFailures(ctx context.Context, userID string) error {
+	const query = `
+		UPDATE users
+		SET mfa_failed_attempts = 0, mfa_locked_until = NULL
+		WHERE id = $1 AND (mfa_failed_attempts > 0 OR mfa_locked_until IS NOT NULL)
+	`
+
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// CreateMFAChallenge records an issued MFA challenge token (expired ones
+// are purged on the way)
+func (r *AuthRepository) CreateMFAChallenge(
+	ctx context.Context,
+	jti string,
+	userID string,
+	expiresAt time.Time,
+) error {
+
+	if _, err := r.db.Exec(ctx, `
+		DELETE FROM mfa_challenges
+		WHERE expires_at < (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+	`); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO mfa_challenges (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := r.db.Exec(ctx, query, jti, userID, expiresAt)
+	return err
+}
+
+// ConsumeMFAChallenge deletes an MFA challenge (single use).
+// Returns false if it is unknown, expired, already used or another user's.
+func (r *AuthRepository) ConsumeMFAChallenge(
+	ctx context.Context,
+	jti string,
+	userID string,
+) (bool, error) {
+
+	const query = `
+		DELETE FROM mfa_challenges
+		WHERE jti = $1
+		  AND user_id = $2
+		  AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+	`
+
+	cmd, err := r.db.Exec(ctx, query, jti, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.RowsAffected() > 0, nil
+}
+
+// ConsumeRecoveryCode marks a recovery code as used.
+// Returns false if the code does not exist or was already used.
+func (r *AuthRepository) ConsumeRecoveryCode(
+	ctx context.Context,
+	userID string,
+	codeHash string,
+) (bool, error) {
+
+	const query = `
+		UPDATE user_recovery_codes
+		SET used_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	cmd, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.RowsAffected() > 0, nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes remain
+func (r *AuthRepository) CountUnusedRecoveryCodes(
+	ctx context.Context,
+	userID string,
+) (int, error) {
+
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+
+	return count, err
+}
+
+/*
+========================
+ API KEYS
+========================
+*/
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at, updated_at`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&k.Scopes,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+		&k.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateAPIKey stores a new hashed API key
+func (r *AuthRepository) CreateAPIKey(
+	ctx context.Context,
+	userID string,
+	name string,
+	prefix string,
+	keyHash string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*APIKey, error) {
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(r.db.QueryRow(ctx, query, userID, name, prefix, keyHash, scopes, expiresAt))
+}
+
+// ListAPIKeys lists all keys of a user (including revoked and expired)
+func (r *AuthRepository) ListAPIKeys(
+	ctx context.Context,
+	userID string,
+) ([]APIKey, error) {
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKey retrieves a single key owned by the user
+func (r *AuthRepository) GetAPIKey(
+	ctx context.Context,
+	userID string,
+	keyID string,
+) (*APIKey, error) {
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE id = $1 AND user_id = $2
+	`
+
+	k, err := scanAPIKey(r.db.QueryRow(ctx, query, keyID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+// UpdateAPIKey renames a key and/or changes its scopes
+func (r *AuthRepository) UpdateAPIKey(
+	ctx context.Context,
+	userID string,
+	keyID string,
+	name string,
+	scopes []string,
+) (*APIKey, error) {
+
+	query := `
+		UPDATE api_keys
+		SET name = $1, scopes = $2, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL
+		RETURNING ` + apiKeyColumns
+
+	k, err := scanAPIKey(r.db.QueryRow(ctx, query, name, scopes, keyID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+// RevokeAPIKey revokes a key (kept for audit, never deleted)
+func (r *AuthRepository) RevokeAPIKey(
+	ctx context.Context,
+	userID string,
+	keyID string,
+) error {
+
+	const query = `
+		UPDATE api_keys
+		SET revoked_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	cmd, err := r.db.Exec(ctx, query, keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// GetActiveAPIKeyByPrefix looks up a usable key together with its owner's role.
+// Revoked keys, expired keys and keys of inactive users are excluded.
+func (r *AuthRepository) GetActiveAPIKeyByPrefix(
+	ctx context.Context,
+	prefix string,
+) (*APIKey, string, error) {
+
+	const query = `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at,
+		       k.last_used_at, k.revoked_at, k.created_at, k.updated_at, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1
+		  AND k.revoked_at IS NULL
+		  AND (k.expires_at IS NULL OR k.expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'))
+		  AND u.is_active = true
+	`
+
+	var k APIKey
+	var role string
+	err := r.db.QueryRow(ctx, query, prefix).Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&k.Scopes,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+		&k.UpdatedAt,
+		&role,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	return &k, role, nil
+}
+
+// TouchAPIKey updates last_used_at
+func (r *AuthRepository) TouchAPIKey(
+	ctx context.Context,
+	keyID string,
+) error {
+
+	const query = `
+		UPDATE api_keys
+		SET last_used_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, keyID)
+	return err
+}
+
+/*
+========================
+ EXTERNAL IDENTITIES
+========================
+*/
+
+const identityColumns = `id, user_id, provider, subject, email, email_verified, last_login_at, created_at`
+
+func scanIdentity(row pgx.Row) (*UserIdentity, error) {
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.EmailVerified,
+		&i.LastLoginAt,
+		&i.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// CreateOIDCState stores a pending authorization request and purges expired ones
+func (r *AuthRepository) CreateOIDCState(
+	ctx context.Context,
+	state oidcLoginState,
+	expiresAt time.Time,
+) error {
+
+	if _, err := r.db.Exec(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE expires_at < (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+	`); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO oidc_login_states (state, provider, code_verifier, nonce, link_user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		state.State, state.Provider, state.CodeVerifier, state.Nonce, state.LinkUserID, expiresAt)
+	return err
+}
+
+// ConsumeOIDCState deletes and returns a pending request (single use).
+// Returns nil if the state is unknown, expired or belongs to another provider.
+func (r *AuthRepository) ConsumeOIDCState(
+	ctx context.Context,
+	state string,
+	provider string,
+) (*oidcLoginState, error) {
+
+	const query = `
+		DELETE FROM oidc_login_states
+		WHERE state = $1
+		  AND provider = $2
+		  AND expires_at > (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		RETURNING state, provider, code_verifier, nonce, link_user_id
+	`
+
+	var s oidcLoginState
+	err := r.db.QueryRow(ctx, query, state, provider).Scan(
+		&s.State,
+		&s.Provider,
+		&s.CodeVerifier,
+		&s.Nonce,
+		&s.LinkUserID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GetIdentity looks up a linked identity by provider and subject
+func (r *AuthRepository) GetIdentity(
+	ctx context.Context,
+	provider string,
+	subject string,
+) (*UserIdentity, error) {
+
+	query := `
+		SELECT ` + identityColumns + `
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	i, err := scanIdentity(r.db.QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+// LinkIdentity links an external identity to an existing user
+func (r *AuthRepository) LinkIdentity(
+	ctx context.Context,
+	userID string,
+	provider string,
+	subject string,
+	email string,
+	emailVerified bool,
+) (*UserIdentity, error) {
+
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, email_verified, last_login_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'))
+		RETURNING ` + identityColumns
+
+	i, err := scanIdentity(r.db.QueryRow(ctx, query, userID, provider, subject, email, emailVerified))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+// CreateUserWithIdentity creates a passwordless user and links the identity in one transaction.
+// The empty password hash never matches, so the account can only sign in via the provider
+// until a password is set.
+func (r *AuthRepository) CreateUserWithIdentity(
+	ctx context.Context,
+	email string,
+	provider string,
+	subject string,
+	emailVerified bool,
+) (*User, error) {
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := scanUser(tx.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash)
+		VALUES ($1, '')
+		RETURNING `+userColumns, email))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, email_verified, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'))
+	`, user.ID, provider, subject, email, emailVerified); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// TouchIdentity records a login and refreshes the provider-reported email
+func (r *AuthRepository) TouchIdentity(
+	ctx context.Context,
+	identityID string,
+	email string,
+	emailVerified bool,
+) error {
+
+	const query = `
+		UPDATE user_identities
+		SET email = COALESCE(NULLIF($2, ''), email),
+		    email_verified = $3,
+		    last_login_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, identityID, email, emailVerified)
+	return err
+}
+
+// ListIdentities lists the identities linked to a user
+func (r *AuthRepository) ListIdentities(
+	ctx context.Context,
+	userID string,
+) ([]UserIdentity, error) {
+
+	query := `
+		SELECT ` + identityColumns + `
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []UserIdentity{}
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, *i)
+	}
+
+	return identities, rows.Err()
+}
+
+// DeleteIdentity unlinks an identity owned by the user
+func (r *AuthRepository) DeleteIdentity(
+	ctx context.Context,
+	userID string,
+	identityID string,
+) error {
+
+	const query = `
+		DELETE FROM user_identities
+		WHERE id = $1 AND user_id = $2
+	`
+
+	cmd, err := r.db.Exec(ctx, query, identityID, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports a Postgres unique_violation (23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strings"
+	"time"
+
+	"smart-forms/internal/auth/oidc"
+
+	"github.com/google/uuid"
 )
 
 /*
@@ -16,6 +22,26 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserInactive       = errors.New("user is inactive")
 	ErrUserAlreadyExists  = errors.New("user already exists")
+
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+
+	ErrMFARequired       = errors.New("mfa code required")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrMFANotEnrolled    = errors.New("mfa enrollment not started")
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotEnabled     = errors.New("mfa not enabled")
+	ErrMFALocked         = errors.New("too many failed mfa attempts")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrInvalidInput   = errors.New("invalid input")
+
+	ErrOIDCStateInvalid      = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified  = errors.New("identity provider did not return a verified email")
+	ErrIdentityAlreadyLinked = errors.New("identity already linked to another account")
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrLastLoginMethod       = errors.New("cannot remove the only sign-in method")
 )
 
 /*
@@ -26,12 +52,13 @@ var (
 
 // AuthService coordinates auth logic
 type AuthService struct {
-	repo *AuthRepository
+	repo      *AuthRepository
+	providers *oidc.Registry
 }
 
 // NewAuthService creates auth service
-func NewAuthService(repo *AuthRepository) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo *AuthRepository, providers *oidc.Registry) *AuthService {
+	return &AuthService{repo: repo, providers: providers}
 }
 
 /*
@@ -40,18 +67,23 @@ func NewAuthService(repo *AuthRepository) *AuthService {
 ========================
 */
 
-// LoginResponse contains login result
+// LoginResponse contains login result.
+// When MFA is enabled, only MFARequired and MFAToken are set and the client
+// must exchange the challenge token via POST /auth/login/mfa.
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	User         UserResponse `json:"user"`
+	AccessToken  string        `json:"access_token,omitempty"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	User         *UserResponse `json:"user,omitempty"`
+	MFARequired  bool          `json:"mfa_required,omitempty"`
+	MFAToken     string        `json:"mfa_token,omitempty"`
 }
 
 // UserResponse contains user info for client
 type UserResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID          string   `json:"id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
 }
 
 // Login validates credentials and issues tokens
@@ -79,6 +111,11 @@ func (s *AuthService) Login(
 		return nil, ErrInvalidCredentials
 	}
 
+	// An admin forced a reset: the old password no longer grants access
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
+
 	// Bootstrap super admin from ENV
 	superAdminEmail := os.Getenv("SUPER_ADMIN_EMAIL")
 	if superAdminEmail != "" && email == superAdminEmail && user.Role != "super_admin" {
@@ -89,7 +126,88 @@ func (s *AuthService) Login(
 		}
 	}
 
-	accessToken, err := GenerateAccessToken(user.ID, user.Role)
+	return s.completeLogin(ctx, user)
+}
+
+// CompleteMFALogin exchanges an MFA challenge token plus a TOTP or recovery code for tokens
+func (s *AuthService) CompleteMFALogin(
+	ctx context.Context,
+	mfaToken string,
+	code string,
+	recoveryCode string,
+) (*LoginResponse, error) {
+
+	claims, err := ValidateMFAToken(mfaToken)
+	if err != nil || claims.ID == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Challenge tokens are single use: after a wrong code the user signs in again
+	consumed, err := s.repo.ConsumeMFAChallenge(ctx, claims.ID, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.MFAEnabled {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	// As for password logins and refresh: a reset forced or tokens revoked
+	// since the challenge was issued end it
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
+	if user.TokensValidAfter != nil && claims.IssuedAt != nil &&
+		claims.IssuedAt.Time.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// completeLogin issues tokens, or an MFA challenge when a second factor is required
+func (s *AuthService) completeLogin(ctx context.Context, user *User) (*LoginResponse, error) {
+	// Second factor required: hand out a challenge token instead of real tokens
+	if user.MFAEnabled {
+		jti := uuid.NewString()
+		mfaToken, err := GenerateMFAToken(user.ID, user.Role, jti)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.CreateMFAChallenge(ctx, jti, user.ID, time.Now().Add(mfaTokenTTL)); err != nil {
+			return nil, err
+		}
+
+		return &LoginResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *User) (*LoginResponse, error) {
+	permissions, err := s.repo.GetRolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := GenerateAccessToken(user.ID, user.Role, permissions)
 	if err != nil {
 		return nil, err
 	}
@@ -102,10 +220,11 @@ func (s *AuthService) Login(
 	return &LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		User: UserResponse{
-			ID:    user.ID,
-			Email: user.Email,
-			Role:  user.Role,
+		User: &UserResponse{
+			ID:          user.ID,
+			Email:       user.Email,
+			Role:        user.Role,
+			Permissions: permissions,
 		},
 	}, nil
 }
@@ -116,7 +235,9 @@ func (s *AuthService) Login(
 ========================
 */
 
-// RefreshAccessToken issues a new access token
+// RefreshAccessToken issues a new access token.
+// The user is re-checked so deactivation, role and permission changes and
+// revocations take effect at the next refresh.
 func (s *AuthService) RefreshAccessToken(
 	ctx context.Context,
 	refreshToken string,
@@ -132,14 +253,30 @@ func (s *AuthService) RefreshAccessToken(
 	// - Token rotation
 	// - Device/session validation
 
-	// Use role from existing token (avoids DB query)
-	// If role is empty (old token), default to 'user'
-	role := claims.Role
-	if role == "" {
-		role = "user"
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return "", ErrUserInactive
+	}
+
+	// Tokens issued before a password reset / deactivation are revoked
+	if user.TokensValidAfter != nil && claims.IssuedAt != nil &&
+		claims.IssuedAt.Time.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return "", ErrInvalidCredentials
+	}
+
+	// Permissions are reloaded so role changes take effect on refresh
+	permissions, err := s.repo.GetRolePermissions(ctx, user.Role)
+	if err != nil {
+		return "", err
 	}
 
-	return GenerateAccessToken(claims.UserID, role)
+	return GenerateAccessToken(user.ID, user.Role, permissions)
 }
 
 /*
@@ -173,3 +310,680 @@ func (s *AuthService) Register(
 	// Create user
 	return s.repo.CreateUser(ctx, email, hash)
 }
+
+/*
+========================
+ PASSWORD RESET
+========================
+*/
+
+// ResetPassword sets a new password using a reset token issued by an admin
+func (s *AuthService) ResetPassword(
+	ctx context.Context,
+	token string,
+	newPassword string,
+) error {
+
+	if strings.TrimSpace(token) == "" || newPassword == "" {
+		return ErrInvalidInput
+	}
+
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.repo.ResetPasswordByToken(ctx, HashPasswordResetToken(token), hash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	return nil
+}
+
+/*
+========================
+ MFA
+========================
+*/
+
+// MFAEnrollment contains the data needed to set up an authenticator app
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus describes the MFA state of a user
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// EnrollMFA starts enrollment by generating a new (unverified) secret
+func (s *AuthService) EnrollMFA(
+	ctx context.Context,
+	userID string,
+) (*MFAEnrollment, error) {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPendingMFASecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: TOTPProvisioningURI(secret, user.Email),
+	}, nil
+}
+
+// VerifyMFA confirms enrollment with a first code and returns recovery codes (shown once)
+func (s *AuthService) VerifyMFA(
+	ctx context.Context,
+	userID string,
+	code string,
+) ([]string, error) {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == nil || *user.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := ValidateTOTP(*user.MFASecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.EnableMFA(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after re-checking the password and a second factor
+func (s *AuthService) DisableMFA(
+	ctx context.Context,
+	userID string,
+	password string,
+	code string,
+	recoveryCode string,
+) error {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !VerifyPassword(password, user.PasswordHash) {
+		return ErrInvalidCredentials
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		return err
+	}
+
+	return s.repo.DisableMFA(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after a valid TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(
+	ctx context.Context,
+	userID string,
+	code string,
+) ([]string, error) {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	// Recovery codes cannot be used to mint new recovery codes
+	if err := s.verifySecondFactor(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// GetMFAStatus returns whether MFA is enabled and how many recovery codes remain
+func (s *AuthService) GetMFAStatus(
+	ctx context.Context,
+	userID string,
+) (*MFAStatus, error) {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	status := &MFAStatus{Enabled: user.MFAEnabled}
+	if user.MFAEnabled {
+		remaining, err := s.repo.CountUnusedRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = remaining
+	}
+
+	return status, nil
+}
+
+// Failed second factor attempts allowed before MFA is locked, and for how long
+const (
+	maxMFAFailures = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+// verifySecondFactor accepts either a TOTP code or a one-time recovery code.
+// Failed attempts are counted: maxMFAFailures in a row lock MFA (and so
+// sign-in) for mfaLockout.
+func (s *AuthService) verifySecondFactor(
+	ctx context.Context,
+	user *User,
+	code string,
+	recoveryCode string,
+) error {
+
+	if user.MFALockedUntil != nil && time.Now().Before(*user.MFALockedUntil) {
+		return ErrMFALocked
+	}
+
+	err := s.checkSecondFactor(ctx, user, code, recoveryCode)
+	switch err {
+	case nil:
+		return s.repo.ResetMFAFailures(ctx, user.ID)
+	case ErrInvalidMFACode:
+		if err := s.repo.RecordMFAFailure(ctx, user.ID, maxMFAFailures, time.Now().Add(mfaLockout)); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *AuthService) checkSecondFactor(
+	ctx context.Context,
+	user *User,
+	code string,
+	recoveryCode string,
+) error {
+
+	code = strings.TrimSpace(code)
+	recoveryCode = strings.TrimSpace(recoveryCode)
+
+	switch {
+	case code != "":
+		if user.MFASecret == nil {
+			return ErrInvalidMFACode
+		}
+
+		step, ok := ValidateTOTP(*user.MFASecret, code, time.Now())
+		if !ok || step <= user.MFALastUsedStep {
+			return ErrInvalidMFACode
+		}
+
+		// Atomically reject a code that was accepted concurrently
+		advanced, err := s.repo.AdvanceMFAStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		return nil
+
+	case recoveryCode != "":
+		used, err := s.repo.ConsumeRecoveryCode(ctx, user.ID, HashRecoveryCode(recoveryCode))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+
+	default:
+		return ErrMFARequired
+	}
+}
+
+/*
+========================
+ API KEYS
+========================
+*/
+
+// CreateAPIKeyRequest describes a new API key
+type CreateAPIKeyRequest struct {
+	Name          string     `json:"name"`
+	Scopes        []string   `json:"scopes"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	ExpiresInDays int        `json:"expires_in_days,omitempty"`
+}
+
+// UpdateAPIKeyRequest renames a key or changes its scopes
+type UpdateAPIKeyRequest struct {
+	Name   *string   `json:"name,omitempty"`
+	Scopes *[]string `json:"scopes,omitempty"`
+}
+
+// CreatedAPIKey is returned once on creation and includes the plain key
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey generates and stores a new scoped API key
+func (s *AuthService) CreateAPIKey(
+	ctx context.Context,
+	userID string,
+	req CreateAPIKeyRequest,
+) (*CreatedAPIKey, error) {
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && req.ExpiresInDays > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, ErrInvalidInput
+	}
+
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.CreateAPIKey(ctx, userID, name, prefix, hash, scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{APIKey: *stored, Key: key}, nil
+}
+
+// ListAPIKeys lists the user's API keys
+func (s *AuthService) ListAPIKeys(
+	ctx context.Context,
+	userID string,
+) ([]APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+// UpdateAPIKey renames a key or replaces its scopes
+func (s *AuthService) UpdateAPIKey(
+	ctx context.Context,
+	userID string,
+	keyID string,
+	req UpdateAPIKeyRequest,
+) (*APIKey, error) {
+
+	existing, err := s.repo.GetAPIKey(ctx, userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := existing.Name
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	scopes := existing.Scopes
+	if req.Scopes != nil {
+		scopes, err = normalizeScopes(*req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.UpdateAPIKey(ctx, userID, keyID, name, scopes)
+}
+
+// RevokeAPIKey revokes a key immediately
+func (s *AuthService) RevokeAPIKey(
+	ctx context.Context,
+	userID string,
+	keyID string,
+) error {
+	return s.repo.RevokeAPIKey(ctx, userID, keyID)
+}
+
+// AuthenticateAPIKey resolves a plain API key into a principal
+func (s *AuthService) AuthenticateAPIKey(
+	ctx context.Context,
+	key string,
+) (*APIKeyPrincipal, error) {
+
+	prefix, ok := parseAPIKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	stored, role, err := s.repo.GetActiveAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || !apiKeyHashMatches(key, stored.KeyHash) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Best-effort usage tracking, throttled to limit writes
+	if stored.LastUsedAt == nil || time.Since(*stored.LastUsedAt) > apiKeyTouchInterval {
+		_ = s.repo.TouchAPIKey(ctx, stored.ID)
+	}
+
+	return &APIKeyPrincipal{
+		KeyID:  stored.ID,
+		UserID: stored.UserID,
+		Role:   role,
+		Scopes: stored.Scopes,
+	}, nil
+}
+
+// normalizeScopes validates and de-duplicates requested scopes
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !isValidScope(scope) {
+			return nil, ErrInvalidInput
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	return result, nil
+}
+
+/*
+========================
+ SOCIAL LOGIN (OIDC)
+========================
+*/
+
+// OIDCAuthorization is returned when starting a provider login
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// ListOIDCProviders lists configured identity providers (for login buttons)
+func (s *AuthService) ListOIDCProviders() []oidc.ProviderInfo {
+	return s.providers.List()
+}
+
+// StartOIDCLogin creates a pending authorization request and returns the provider URL.
+// linkUserID is set when an authenticated user links a provider to their account.
+func (s *AuthService) StartOIDCLogin(
+	ctx context.Context,
+	providerName string,
+	linkUserID *string,
+) (*OIDCAuthorization, error) {
+
+	provider, err := s.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := oidcLoginState{
+		State:        state,
+		Provider:     provider.Name(),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+	}
+	if err := s.repo.CreateOIDCState(ctx, pending, time.Now().UTC().Add(oidcStateTTL)); err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthorization{
+		AuthorizationURL: authURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code and signs the user in.
+//
+// Resolution order:
+//  1. identity already linked -> that user
+//  2. verified email matches an existing user -> link and sign in
+//  3. verified email is new -> create a passwordless user
+//
+// For link requests the identity is attached to the initiating user and
+// returned instead of tokens.
+func (s *AuthService) CompleteOIDCLogin(
+	ctx context.Context,
+	providerName string,
+	code string,
+	state string,
+) (*LoginResponse, *UserIdentity, error) {
+
+	provider, err := s.providers.Get(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if code == "" || state == "" {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+
+	pending, err := s.repo.ConsumeOIDCState(ctx, state, provider.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	if pending == nil {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+
+	identity, err := provider.Exchange(ctx, code, pending.Nonce, pending.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := s.repo.GetIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Linking from account settings
+	if pending.LinkUserID != nil {
+		if existing != nil {
+			if existing.UserID != *pending.LinkUserID {
+				return nil, nil, ErrIdentityAlreadyLinked
+			}
+			return nil, existing, nil
+		}
+
+		linked, err := s.repo.LinkIdentity(ctx, *pending.LinkUserID, identity.Provider,
+			identity.Subject, identity.Email, identity.EmailVerified)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, linked, nil
+	}
+
+	user, err := s.resolveOIDCUser(ctx, identity, existing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !user.IsActive {
+		return nil, nil, ErrUserInactive
+	}
+
+	response, err := s.completeLogin(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, nil, nil
+}
+
+// resolveOIDCUser finds, links or creates the user for a provider identity
+func (s *AuthService) resolveOIDCUser(
+	ctx context.Context,
+	identity *oidc.Identity,
+	existing *UserIdentity,
+) (*User, error) {
+
+	if existing != nil {
+		if err := s.repo.TouchIdentity(ctx, existing.ID, identity.Email, identity.EmailVerified); err != nil {
+			return nil, err
+		}
+
+		user, err := s.repo.GetUserByID(ctx, existing.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return user, nil
+	}
+
+	// Unverified emails could be used to take over accounts; never link or create on them
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		if _, err := s.repo.LinkIdentity(ctx, user.ID, identity.Provider,
+			identity.Subject, identity.Email, identity.EmailVerified); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	return s.repo.CreateUserWithIdentity(ctx, identity.Email, identity.Provider,
+		identity.Subject, identity.EmailVerified)
+}
+
+// ListIdentities lists the external identities linked to a user
+func (s *AuthService) ListIdentities(
+	ctx context.Context,
+	userID string,
+) ([]UserIdentity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+// UnlinkIdentity removes a linked identity. Passwordless users must keep at least one.
+func (s *AuthService) UnlinkIdentity(
+	ctx context.Context,
+	userID string,
+	identityID string,
+) error {
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidCredentials
+	}
+
+	if user.PasswordHash == "" {
+		identities, err := s.repo.ListIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return ErrLastLoginMethod
+		}
+	}
+
+	return s.repo.DeleteIdentity(ctx, userID, identityID)
+}
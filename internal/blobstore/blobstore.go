@@ -0,0 +1,68 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps uploaded files. Keys are slash-separated paths of letters,
+// digits, "-", "_" and "." (no "." or ".." segments), e.g.
+// "<form_id>/<upload_id>".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens a blob; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Drivers
+const (
+	DriverLocal = "local"
+)
+
+// DefaultLocalPath is where the local driver keeps files unless
+// BLOBSTORE_PATH says otherwise
+const DefaultLocalPath = "data/uploads"
+
+// FromEnv opens the store BLOBSTORE_DRIVER names (default local, in
+// BLOBSTORE_PATH). S3-compatible storage will be another driver.
+func FromEnv() (Store, error) {
+	driver := os.Getenv("BLOBSTORE_DRIVER")
+	switch driver {
+	case "", DriverLocal:
+		path := os.Getenv("BLOBSTORE_PATH")
+		if path == "" {
+			path = DefaultLocalPath
+		}
+		return NewLocalStore(path)
+	default:
+		return nil, fmt.Errorf("unsupported BLOBSTORE_DRIVER %q", driver)
+	}
+}
+
+// validKey reports whether a key is safe to use as a relative path
+func validKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package collaborators
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Capability is something a handler needs to do with a form
+type Capability int
+
+const (
+	ViewForm      Capability = iota // read form metadata and flow
+	EditForm                        // update metadata and flow, publish, toggle responses
+	ManageForm                      // delete, move, manage collaborators
+	ViewResponses                   // list and read responses
+	ViewAnalytics                   // read analytics
+)
+
+var roleCapabilities = map[string][]Capability{
+	RoleOwner:           {ViewForm, EditForm, ManageForm, ViewResponses, ViewAnalytics},
+	RoleEditor:          {ViewForm, EditForm, ViewResponses, ViewAnalytics},
+	RoleResponderViewer: {ViewForm, ViewResponses},
+	RoleAnalyticsViewer: {ViewForm, ViewResponses, ViewAnalytics},
+}
+
+// AccessChecker resolves a user's role on a form and checks capabilities
+type AccessChecker struct {
+	repo *CollaboratorsRepository
+}
+
+func NewAccessChecker(repo *CollaboratorsRepository) *AccessChecker {
+	return &AccessChecker{repo: repo}
+}
+
+// Role returns the user's effective role on a form (see workspaceFormRole
+// for workspace members).
+// Returns ErrNoAccess if the form doesn't exist or isn't shared with the user.
+func (a *AccessChecker) Role(ctx context.Context, userID, formID string) (string, error) {
+	return a.repo.EffectiveRole(ctx, formID, userID)
+}
+
+// Require checks that the user's role on the form grants capability
+func (a *AccessChecker) Require(ctx context.Context, userID, formID string, capability Capability) error {
+	role, err := a.Role(ctx, userID, formID)
+	if err != nil {
+		return err
+	}
+
+	if !roleAllows(role, capability) {
+		return ErrInsufficientRole
+	}
+
+	return nil
+}
+
+// AccessError maps Require errors to HTTP errors for handlers
+func AccessError(err error) error {
+	switch err {
+	case ErrNoAccess:
+		return fiber.ErrNotFound
+	case ErrInsufficientRole:
+		return fiber.NewError(fiber.StatusForbidden, "Your role on this form does not allow this")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+// workspaceFormRole maps a workspace role to the role its holder has on the
+// workspace's forms: owners and admins own them, members edit them
+func workspaceFormRole(workspaceRole string) string {
+	switch workspaceRole {
+	case "owner", "admin":
+		return RoleOwner
+	default:
+		return RoleEditor
+	}
+}
+
+func roleAllows(role string, capability Capability) bool {
+	for _, c := range roleCapabilities[role] {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidRole(role string) bool {
+	_, ok := roleCapabilities[role]
+	return ok
+}
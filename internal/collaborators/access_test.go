@@ -0,0 +1,32 @@
+package collaborators
+
+import "testing"
+
+func TestWorkspaceFormRole(t *testing.T) {
+	cases := []struct {
+		workspaceRole string
+		want          string
+	}{
+		{"owner", RoleOwner},
+		{"admin", RoleOwner},
+		{"member", RoleEditor},
+	}
+	for _, tc := range cases {
+		if got := workspaceFormRole(tc.workspaceRole); got != tc.want {
+			t.Errorf("workspaceFormRole(%q) = %q, want %q", tc.workspaceRole, got, tc.want)
+		}
+	}
+}
+
+func TestPlainMemberCannotManageForm(t *testing.T) {
+	role := workspaceFormRole("member")
+
+	for _, c := range []Capability{ViewForm, EditForm, ViewResponses, ViewAnalytics} {
+		if !roleAllows(role, c) {
+			t.Errorf("member should be allowed capability %d", c)
+		}
+	}
+	if roleAllows(role, ManageForm) {
+		t.Error("member must not be allowed to manage (delete, move, share) the form")
+	}
+}
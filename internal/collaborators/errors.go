@@ -0,0 +1,13 @@
+package collaborators
+
+import "errors"
+
+var (
+	ErrNoAccess           = errors.New("form not found or no access")
+	ErrInsufficientRole   = errors.New("form role does not allow this")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrInvalidRole        = errors.New("invalid collaborator role")
+	ErrNotFound           = errors.New("collaborator not found")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrAlreadyHasAccess   = errors.New("user already has access to the form")
+)
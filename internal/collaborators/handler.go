@@ -0,0 +1,155 @@
+package collaborators
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type CollaboratorsHandler struct {
+	service *CollaboratorsService
+}
+
+func NewCollaboratorsHandler(service *CollaboratorsService) *CollaboratorsHandler {
+	return &CollaboratorsHandler{service: service}
+}
+
+// ListCollaborators lists a form's collaborators and pending invitations
+// GET /forms/:form_id/collaborators
+func (h *CollaboratorsHandler) ListCollaborators(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	collaborators, invitations, err := h.service.ListCollaborators(c.Context(), userID, c.Params("form_id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"collaborators": collaborators,
+		"invitations":   invitations,
+	})
+}
+
+// Invite invites someone to a form by email
+// POST /forms/:form_id/collaborators/invitations
+func (h *CollaboratorsHandler) Invite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req InviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	invitation, err := h.service.Invite(c.Context(), userID, c.Params("form_id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(invitation)
+}
+
+// RevokeInvitation deletes a pending invitation
+// DELETE /forms/:form_id/collaborators/invitations/:id
+func (h *CollaboratorsHandler) RevokeInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.RevokeInvitation(c.Context(), userID, c.Params("form_id"), c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Invitation revoked",
+	})
+}
+
+// UpdateRole changes a collaborator's role
+// PATCH /forms/:form_id/collaborators/:user_id
+func (h *CollaboratorsHandler) UpdateRole(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	collaborators, err := h.service.UpdateRole(c.Context(), userID, c.Params("form_id"), c.Params("user_id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(collaborators)
+}
+
+// RemoveCollaborator revokes access, or leaves the form when user_id is the caller
+// DELETE /forms/:form_id/collaborators/:user_id
+func (h *CollaboratorsHandler) RemoveCollaborator(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.Remove(c.Context(), userID, c.Params("form_id"), c.Params("user_id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Collaborator removed",
+	})
+}
+
+// ListMyInvitations lists pending invitations for the caller
+// GET /invitations
+func (h *CollaboratorsHandler) ListMyInvitations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	invitations, err := h.service.ListMyInvitations(c.Context(), userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(invitations)
+}
+
+// AcceptInvitation accepts an invitation
+// POST /invitations/:id/accept
+func (h *CollaboratorsHandler) AcceptInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	formID, err := h.service.AcceptInvitation(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Invitation accepted",
+		"form_id": formID,
+	})
+}
+
+// DeclineInvitation declines an invitation
+// POST /invitations/:id/decline
+func (h *CollaboratorsHandler) DeclineInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.DeclineInvitation(c.Context(), userID, c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Invitation declined",
+	})
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrNoAccess, ErrInsufficientRole:
+		return AccessError(err)
+	case ErrNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Collaborator not found")
+	case ErrInvitationNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Invitation not found or expired")
+	case ErrInvalidInput:
+		return fiber.ErrBadRequest
+	case ErrInvalidRole:
+		return fiber.NewError(fiber.StatusBadRequest, "role must be owner, editor, responder-viewer or analytics-viewer")
+	case ErrAlreadyHasAccess:
+		return fiber.NewError(fiber.StatusConflict, "User already has access to this form")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
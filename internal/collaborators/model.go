@@ -0,0 +1,48 @@
+package collaborators
+
+import "time"
+
+// Per-form roles. Workspace owners and admins act as owner on the
+// workspace's forms, members as editor.
+const (
+	RoleOwner           = "owner"            // everything, including sharing and deleting
+	RoleEditor          = "editor"           // edit form and flow, publish, see responses and analytics
+	RoleResponderViewer = "responder-viewer" // read-only form and flow, see responses
+	RoleAnalyticsViewer = "analytics-viewer" // read-only form and flow, see responses and analytics
+)
+
+// How long a pending invitation can be accepted
+const invitationTTL = 14 * 24 * time.Hour
+
+// Collaborator is a user with a per-form role
+type Collaborator struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	AddedBy   *string   `json:"added_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invitation is a pending invite matched to an account by email
+type Invitation struct {
+	ID        string    `json:"id"`
+	FormID    string    `json:"form_id"`
+	FormTitle string    `json:"form_title,omitempty"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy *string   `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InviteRequest invites someone by email
+type InviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// UpdateRoleRequest changes a collaborator's role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
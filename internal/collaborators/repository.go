@@ -0,0 +1,331 @@
+package collaborators
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CollaboratorsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCollaboratorsRepository(db *pgxpool.Pool) *CollaboratorsRepository {
+	return &CollaboratorsRepository{db: db}
+}
+
+/*
+========================
+ ACCESS
+========================
+*/
+
+// EffectiveRole returns the user's role on a form: the one their workspace
+// role maps to for members of the form's workspace, otherwise the
+// collaborator role
+func (r *CollaboratorsRepository) EffectiveRole(ctx context.Context, formID, userID string) (string, error) {
+	const query = `
+		SELECT
+			(SELECT m.role FROM workspace_members m
+			 WHERE m.workspace_id = f.workspace_id AND m.user_id = $2),
+			(SELECT c.role FROM form_collaborators c
+			 WHERE c.form_id = f.id AND c.user_id = $2)
+		FROM forms f
+		WHERE f.id = $1 AND f.deleted_at IS NULL
+	`
+
+	var workspaceRole, role *string
+	err := r.db.QueryRow(ctx, query, formID, userID).Scan(&workspaceRole, &role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNoAccess
+		}
+		return "", err
+	}
+
+	if workspaceRole != nil {
+		return workspaceFormRole(*workspaceRole), nil
+	}
+	if role == nil {
+		return "", ErrNoAccess
+	}
+
+	return *role, nil
+}
+
+// HasAccessByEmail reports whether the account with email already reaches
+// the form (workspace member or collaborator)
+func (r *CollaboratorsRepository) HasAccessByEmail(ctx context.Context, formID, email string) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users u
+			JOIN forms f ON f.id = $1
+			WHERE lower(u.email) = $2
+			  AND (
+				EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = f.workspace_id AND m.user_id = u.id)
+				OR EXISTS (SELECT 1 FROM form_collaborators c WHERE c.form_id = f.id AND c.user_id = u.id)
+			  )
+		)
+	`
+
+	var has bool
+	err := r.db.QueryRow(ctx, query, formID, email).Scan(&has)
+	return has, err
+}
+
+/*
+========================
+ COLLABORATORS
+========================
+*/
+
+// ListCollaborators retrieves a form's collaborators
+func (r *CollaboratorsRepository) ListCollaborators(ctx context.Context, formID string) ([]Collaborator, error) {
+	const query = `
+		SELECT c.user_id, u.email, c.role, c.added_by, c.created_at, c.updated_at
+		FROM form_collaborators c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.form_id = $1
+		ORDER BY c.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := []Collaborator{}
+	for rows.Next() {
+		var c Collaborator
+		if err := rows.Scan(&c.UserID, &c.Email, &c.Role, &c.AddedBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		collaborators = append(collaborators, c)
+	}
+
+	return collaborators, rows.Err()
+}
+
+// UpdateRole changes a collaborator's role
+func (r *CollaboratorsRepository) UpdateRole(ctx context.Context, formID, userID, role string) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE form_collaborators
+		SET role = $3, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE form_id = $1 AND user_id = $2
+	`, formID, userID, role)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Remove revokes a collaborator's access
+func (r *CollaboratorsRepository) Remove(ctx context.Context, formID, userID string) error {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM form_collaborators
+		WHERE form_id = $1 AND user_id = $2
+	`, formID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+/*
+========================
+ INVITATIONS
+========================
+*/
+
+// CreateInvitation stores a pending invitation.
+// Inviting the same email again replaces the role and restarts the expiry.
+func (r *CollaboratorsRepository) CreateInvitation(
+	ctx context.Context,
+	formID string,
+	email string,
+	role string,
+	invitedBy string,
+	expiresAt time.Time,
+) (*Invitation, error) {
+
+	const query = `
+		INSERT INTO form_invitations (form_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (form_id, email) DO UPDATE
+		SET role = EXCLUDED.role,
+		    invited_by = EXCLUDED.invited_by,
+		    created_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    expires_at = EXCLUDED.expires_at
+		RETURNING id, form_id, email, role, invited_by, created_at, expires_at
+	`
+
+	var inv Invitation
+	err := r.db.QueryRow(ctx, query, formID, email, role, invitedBy, expiresAt).Scan(
+		&inv.ID,
+		&inv.FormID,
+		&inv.Email,
+		&inv.Role,
+		&inv.InvitedBy,
+		&inv.CreatedAt,
+		&inv.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ListFormInvitations retrieves a form's pending, unexpired invitations
+func (r *CollaboratorsRepository) ListFormInvitations(ctx context.Context, formID string) ([]Invitation, error) {
+	const query = `
+		SELECT id, form_id, email, role, invited_by, created_at, expires_at
+		FROM form_invitations
+		WHERE form_id = $1 AND expires_at > NOW()
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []Invitation{}
+	for rows.Next() {
+		var inv Invitation
+		if err := rows.Scan(
+			&inv.ID,
+			&inv.FormID,
+			&inv.Email,
+			&inv.Role,
+			&inv.InvitedBy,
+			&inv.CreatedAt,
+			&inv.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// DeleteFormInvitation revokes a pending invitation
+func (r *CollaboratorsRepository) DeleteFormInvitation(ctx context.Context, formID, invitationID string) error {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM form_invitations
+		WHERE id = $1 AND form_id = $2
+	`, invitationID, formID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// ListInvitationsForUser retrieves unexpired invitations addressed to the user's email
+func (r *CollaboratorsRepository) ListInvitationsForUser(ctx context.Context, userID string) ([]Invitation, error) {
+	const query = `
+		SELECT i.id, i.form_id, f.title, i.email, i.role, i.invited_by, i.created_at, i.expires_at
+		FROM form_invitations i
+		JOIN users u ON lower(u.email) = i.email
+		JOIN forms f ON f.id = i.form_id AND f.deleted_at IS NULL
+		WHERE u.id = $1 AND i.expires_at > NOW()
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []Invitation{}
+	for rows.Next() {
+		var inv Invitation
+		if err := rows.Scan(
+			&inv.ID,
+			&inv.FormID,
+			&inv.FormTitle,
+			&inv.Email,
+			&inv.Role,
+			&inv.InvitedBy,
+			&inv.CreatedAt,
+			&inv.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// AcceptInvitation turns an invitation addressed to the user into a collaborator
+// role and deletes it. Returns the form ID.
+func (r *CollaboratorsRepository) AcceptInvitation(ctx context.Context, invitationID, userID string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var formID, role string
+	var invitedBy *string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM form_invitations i
+		USING users u, forms f
+		WHERE i.id = $1
+		  AND u.id = $2 AND lower(u.email) = i.email
+		  AND f.id = i.form_id AND f.deleted_at IS NULL
+		  AND i.expires_at > NOW()
+		RETURNING i.form_id, i.role, i.invited_by
+	`, invitationID, userID).Scan(&formID, &role, &invitedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrInvitationNotFound
+		}
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO form_collaborators (form_id, user_id, role, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (form_id, user_id) DO UPDATE
+		SET role = EXCLUDED.role,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+	`, formID, userID, role, invitedBy); err != nil {
+		return "", err
+	}
+
+	return formID, tx.Commit(ctx)
+}
+
+// DeclineInvitation deletes an invitation addressed to the user
+func (r *CollaboratorsRepository) DeclineInvitation(ctx context.Context, invitationID, userID string) error {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM form_invitations i
+		USING users u
+		WHERE i.id = $1 AND u.id = $2 AND lower(u.email) = i.email
+	`, invitationID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
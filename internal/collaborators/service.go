@@ -0,0 +1,127 @@
+package collaborators
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type CollaboratorsService struct {
+	repo   *CollaboratorsRepository
+	access *AccessChecker
+}
+
+func NewCollaboratorsService(repo *CollaboratorsRepository, access *AccessChecker) *CollaboratorsService {
+	return &CollaboratorsService{
+		repo:   repo,
+		access: access,
+	}
+}
+
+// ListCollaborators lists a form's collaborators and pending invitations
+func (s *CollaboratorsService) ListCollaborators(ctx context.Context, userID, formID string) ([]Collaborator, []Invitation, error) {
+	if err := s.access.Require(ctx, userID, formID, ViewForm); err != nil {
+		return nil, nil, err
+	}
+
+	collaborators, err := s.repo.ListCollaborators(ctx, formID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invitations, err := s.repo.ListFormInvitations(ctx, formID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return collaborators, invitations, nil
+}
+
+// Invite creates a pending invitation for an email address (form owners only)
+func (s *CollaboratorsService) Invite(ctx context.Context, userID, formID string, req InviteRequest) (*Invitation, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, ErrInvalidInput
+	}
+
+	role := strings.TrimSpace(req.Role)
+	if !isValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.access.Require(ctx, userID, formID, ManageForm); err != nil {
+		return nil, err
+	}
+
+	has, err := s.repo.HasAccessByEmail(ctx, formID, email)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		return nil, ErrAlreadyHasAccess
+	}
+
+	return s.repo.CreateInvitation(ctx, formID, email, role, userID, time.Now().UTC().Add(invitationTTL))
+}
+
+// RevokeInvitation deletes a pending invitation (form owners only)
+func (s *CollaboratorsService) RevokeInvitation(ctx context.Context, userID, formID, invitationID string) error {
+	if err := s.access.Require(ctx, userID, formID, ManageForm); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteFormInvitation(ctx, formID, invitationID)
+}
+
+// UpdateRole changes a collaborator's role (form owners only)
+func (s *CollaboratorsService) UpdateRole(ctx context.Context, userID, formID, collaboratorID string, req UpdateRoleRequest) ([]Collaborator, error) {
+	role := strings.TrimSpace(req.Role)
+	if !isValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.access.Require(ctx, userID, formID, ManageForm); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRole(ctx, formID, collaboratorID, role); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListCollaborators(ctx, formID)
+}
+
+// Remove revokes a collaborator (form owners), or lets a collaborator leave
+func (s *CollaboratorsService) Remove(ctx context.Context, userID, formID, collaboratorID string) error {
+	capability := ManageForm
+	if collaboratorID == userID {
+		capability = ViewForm
+	}
+
+	if err := s.access.Require(ctx, userID, formID, capability); err != nil {
+		return err
+	}
+
+	return s.repo.Remove(ctx, formID, collaboratorID)
+}
+
+/*
+========================
+ INVITEE SIDE
+========================
+*/
+
+// ListMyInvitations lists pending invitations addressed to the user's email
+func (s *CollaboratorsService) ListMyInvitations(ctx context.Context, userID string) ([]Invitation, error) {
+	return s.repo.ListInvitationsForUser(ctx, userID)
+}
+
+// AcceptInvitation grants the invited role. Returns the form ID.
+func (s *CollaboratorsService) AcceptInvitation(ctx context.Context, userID, invitationID string) (string, error) {
+	return s.repo.AcceptInvitation(ctx, invitationID, userID)
+}
+
+// DeclineInvitation deletes an invitation addressed to the user
+func (s *CollaboratorsService) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	return s.repo.DeclineInvitation(ctx, invitationID, userID)
+}
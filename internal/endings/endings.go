@@ -0,0 +1,201 @@
+// Package endings implements a flow's ending screens: what a respondent sees
+// after submitting. An ending is chosen by the node the respondent finished
+// at, by their quiz score or as the flow's default, and rendered with their
+// variables. Everything here is pure; versions store the definitions.
+package endings
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"smart-forms/internal/variables"
+)
+
+// Limits of one flow's endings
+const (
+	MaxEndings     = 50
+	MaxButtons     = 5
+	MaxTitleLength = 200
+	MaxBodyLength  = 20000
+	MaxURLLength   = 2000
+	MaxLabelLength = 80
+)
+
+// Ending is an ending screen of a flow. Body is rich text (HTML from the
+// builder's editor); title, body and URLs may use {{variable}} templates.
+type Ending struct {
+	ID          string   `json:"id" yaml:"id"`
+	Title       string   `json:"title" yaml:"title"`
+	Body        string   `json:"body,omitempty" yaml:"body,omitempty"`
+	RedirectURL string   `json:"redirect_url,omitempty" yaml:"redirect_url,omitempty"`
+	Buttons     []Button `json:"buttons,omitempty" yaml:"buttons,omitempty"`
+	// Score range (percent, inclusive) for quiz forms; either bound may be
+	// omitted. Endings without one are only reached through nodes or as
+	// the default.
+	MinPercent *float64 `json:"min_percent,omitempty" yaml:"min_percent,omitempty"`
+	MaxPercent *float64 `json:"max_percent,omitempty" yaml:"max_percent,omitempty"`
+	// Default is shown when nothing else picks an ending
+	Default bool `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Button is a call to action on an ending screen
+type Button struct {
+	Label string `json:"label" yaml:"label"`
+	URL   string `json:"url" yaml:"url"`
+}
+
+// Rendered is an ending resolved for one respondent
+type Rendered struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Body        string   `json:"body,omitempty"`
+	RedirectURL string   `json:"redirect_url,omitempty"`
+	Buttons     []Button `json:"buttons,omitempty"`
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidID reports whether id can name an ending
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Check validates a flow's endings: IDs, texts, URLs, score ranges and at
+// most one default
+func Check(endings []Ending) error {
+	if len(endings) > MaxEndings {
+		return fmt.Errorf("at most %d endings", MaxEndings)
+	}
+	seen := make(map[string]bool, len(endings))
+	defaults := 0
+	for _, e := range endings {
+		if !ValidID(e.ID) {
+			return fmt.Errorf("invalid ending id %q", e.ID)
+		}
+		if seen[e.ID] {
+			return fmt.Errorf("duplicate ending %q", e.ID)
+		}
+		seen[e.ID] = true
+
+		title := strings.TrimSpace(e.Title)
+		if title == "" || len(title) > MaxTitleLength {
+			return fmt.Errorf("ending %q: title is required (at most %d characters)", e.ID, MaxTitleLength)
+		}
+		if len(e.Body) > MaxBodyLength {
+			return fmt.Errorf("ending %q: body is too long", e.ID)
+		}
+		if e.RedirectURL != "" && !validURL(e.RedirectURL) {
+			return fmt.Errorf("ending %q: redirect_url must be an http(s) URL", e.ID)
+		}
+		if len(e.Buttons) > MaxButtons {
+			return fmt.Errorf("ending %q: at most %d buttons", e.ID, MaxButtons)
+		}
+		for _, b := range e.Buttons {
+			label := strings.TrimSpace(b.Label)
+			if label == "" || len(label) > MaxLabelLength {
+				return fmt.Errorf("ending %q: button labels are required (at most %d characters)", e.ID, MaxLabelLength)
+			}
+			if !validURL(b.URL) {
+				return fmt.Errorf("ending %q: button url must be an http(s) URL", e.ID)
+			}
+		}
+
+		if !percent(e.MinPercent) || !percent(e.MaxPercent) {
+			return fmt.Errorf("ending %q: score range must be within 0-100", e.ID)
+		}
+		if e.MinPercent != nil && e.MaxPercent != nil && *e.MinPercent > *e.MaxPercent {
+			return fmt.Errorf("ending %q: min_percent is above max_percent", e.ID)
+		}
+		if e.Default {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		return fmt.Errorf("at most one default ending")
+	}
+	return nil
+}
+
+// TemplateRefs returns the variables an ending's texts refer to
+func (e *Ending) TemplateRefs() []string {
+	var refs []string
+	texts := []string{e.Title, e.Body, e.RedirectURL}
+	for _, b := range e.Buttons {
+		texts = append(texts, b.Label, b.URL)
+	}
+	for _, t := range texts {
+		refs = append(refs, variables.TemplateRefs(t)...)
+	}
+	return refs
+}
+
+// Select picks a respondent's ending: the one their last node names, else
+// the first whose score range holds their percent (quiz forms, nil
+// otherwise), else the default. Returns nil when none applies.
+func Select(endings []Ending, nodeEnding string, percent *float64) *Ending {
+	if nodeEnding != "" {
+		for i := range endings {
+			if endings[i].ID == nodeEnding {
+				return &endings[i]
+			}
+		}
+	}
+	if percent != nil {
+		for i := range endings {
+			e := &endings[i]
+			if e.MinPercent == nil && e.MaxPercent == nil {
+				continue
+			}
+			if (e.MinPercent == nil || *percent >= *e.MinPercent) &&
+				(e.MaxPercent == nil || *percent <= *e.MaxPercent) {
+				return e
+			}
+		}
+	}
+	for i := range endings {
+		if endings[i].Default {
+			return &endings[i]
+		}
+	}
+	return nil
+}
+
+// Render resolves an ending's templates. Values are HTML-escaped in the
+// body and URL-encoded in URLs; a redirect that no longer parses as an
+// http(s) URL is dropped.
+func (e *Ending) Render(env variables.Env) *Rendered {
+	r := &Rendered{
+		ID:    e.ID,
+		Title: variables.Render(e.Title, env),
+		Body:  variables.RenderWith(e.Body, env, html.EscapeString),
+	}
+	if e.RedirectURL != "" {
+		if u := variables.RenderWith(e.RedirectURL, env, url.QueryEscape); validURL(u) {
+			r.RedirectURL = u
+		}
+	}
+	for _, b := range e.Buttons {
+		u := variables.RenderWith(b.URL, env, url.QueryEscape)
+		if !validURL(u) {
+			continue
+		}
+		r.Buttons = append(r.Buttons, Button{Label: variables.Render(b.Label, env), URL: u})
+	}
+	return r
+}
+
+// validURL accepts absolute http(s) URLs; templates count as plain text
+func validURL(raw string) bool {
+	if raw == "" || len(raw) > MaxURLLength {
+		return false
+	}
+	u, err := url.Parse(variables.RenderWith(raw, nil, func(string) string { return "x" }))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func percent(v *float64) bool {
+	return v == nil || (*v >= 0 && *v <= 100)
+}
@@ -6,4 +6,13 @@ var (
 	ErrInvalidInput = errors.New("invalid input")
 	ErrNotFound     = errors.New("flow not found")
 	ErrFormNotFound = errors.New("form not found")
+	ErrDraftChanged = errors.New("flow was changed since it was read")
+	ErrNoIfMatch    = errors.New("flow writes require If-Match")
+	ErrJumpTarget   = errors.New("jump target not found")
+	ErrFlowCycle    = errors.New("flow contains a cycle")
+
+	ErrFragmentNotFound = errors.New("fragment not found")
+
+	ErrInvalidFormat   = errors.New("unsupported format")
+	ErrInvalidDocument = errors.New("invalid flow document")
 )
@@ -0,0 +1,222 @@
+package flows
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Export and import formats
+const (
+	FormatJSON    = "json"
+	FormatYAML    = "yaml"
+	FormatMermaid = "mermaid"
+	FormatDOT     = "dot"
+)
+
+// documentVersion is the FlowDocument format written by exports
+const documentVersion = 1
+
+// ContentType returns the Content-Type of an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatYAML:
+		return "application/yaml; charset=utf-8"
+	case FormatMermaid:
+		return "text/plain; charset=utf-8"
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// buildBlocks nests stored flow items into typed blocks
+func buildBlocks(items []map[string]interface{}, parentID *string) []Block {
+	var result []Block
+
+	for _, item := range items {
+		itemParentID := item["parent_id"].(*string)
+
+		if (parentID == nil && itemParentID == nil) ||
+			(parentID != nil && itemParentID != nil && *parentID == *itemParentID) {
+
+			id := item["id"].(string)
+			next, _ := item["next"].([]string)
+			if len(next) == 0 {
+				next = nil
+			}
+			result = append(result, Block{
+				ID:        id,
+				Type:      item["type"].(string),
+				Question:  item["question"].(string),
+				Children:  buildBlocks(items, &id),
+				Next:      next,
+				Fragment:  item["fragment"].(*FragmentRef),
+				Variable:  item["variable"].(string),
+				Set:       item["set"].([]variables.Assignment),
+				Quiz:      item["quiz"].(*quiz.Scoring),
+				Ending:    item["ending"].(string),
+				Randomize: item["randomize"].(*randomize.Settings),
+				Kind:      item["kind"].(*kinds.Spec),
+			})
+		}
+	}
+
+	return result
+}
+
+// renderDocument writes a flow in one of the export formats
+func renderDocument(doc *FlowDocument, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case FormatMermaid:
+		return renderMermaid(doc.Blocks), nil
+	case FormatDOT:
+		return renderDOT(doc.Blocks), nil
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
+
+// diagramNode is a block with the short name diagrams refer to it by
+type diagramNode struct {
+	name  string
+	block *Block
+}
+
+// diagramNodes names blocks n0, n1, ... in flow order and lists the child
+// and jump edges between them
+func diagramNodes(blocks []Block) (nodes []diagramNode, children, jumps [][2]string) {
+	names := make(map[string]string)
+
+	var walk func(blocks []Block, parent string)
+	walk = func(blocks []Block, parent string) {
+		for i := range blocks {
+			b := &blocks[i]
+			name := fmt.Sprintf("n%d", len(nodes))
+			nodes = append(nodes, diagramNode{name: name, block: b})
+			if b.ID != "" {
+				names[b.ID] = name
+			}
+			if parent != "" {
+				children = append(children, [2]string{parent, name})
+			}
+			walk(b.Children, name)
+		}
+	}
+	walk(blocks, "")
+
+	for _, n := range nodes {
+		for _, target := range n.block.Next {
+			if name, ok := names[target]; ok {
+				jumps = append(jumps, [2]string{n.name, name})
+			}
+		}
+	}
+	return nodes, children, jumps
+}
+
+// renderMermaid draws the flow as a Mermaid flowchart: questions as boxes,
+// options as rounded boxes, fragments as subroutines, jumps as dotted arrows
+func renderMermaid(blocks []Block) []byte {
+	nodes, children, jumps := diagramNodes(blocks)
+
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, n := range nodes {
+		label := strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(n.block.Question)
+		switch n.block.Type {
+		case "option":
+			fmt.Fprintf(&b, "  %s(\"%s\")\n", n.name, label)
+		case "fragment":
+			fmt.Fprintf(&b, "  %s[[\"%s\"]]\n", n.name, label)
+		default:
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", n.name, label)
+		}
+	}
+	for _, e := range children {
+		fmt.Fprintf(&b, "  %s --> %s\n", e[0], e[1])
+	}
+	for _, e := range jumps {
+		fmt.Fprintf(&b, "  %s -.-> %s\n", e[0], e[1])
+	}
+	return []byte(b.String())
+}
+
+// renderDOT draws the flow as a GraphViz digraph: questions as boxes,
+// options as ellipses, fragments as components, jumps as dashed edges
+func renderDOT(blocks []Block) []byte {
+	nodes, children, jumps := diagramNodes(blocks)
+
+	var b strings.Builder
+	b.WriteString("digraph flow {\n  rankdir=TB;\n")
+	for _, n := range nodes {
+		label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(n.block.Question)
+		shape := "box"
+		switch n.block.Type {
+		case "option":
+			shape = "ellipse"
+		case "fragment":
+			shape = "component"
+		}
+		fmt.Fprintf(&b, "  %s [label=\"%s\", shape=%s];\n", n.name, label, shape)
+	}
+	for _, e := range children {
+		fmt.Fprintf(&b, "  %s -> %s;\n", e[0], e[1])
+	}
+	for _, e := range jumps {
+		fmt.Fprintf(&b, "  %s -> %s [style=dashed];\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// parseDocument reads a YAML or JSON flow document. Unknown fields are
+// rejected so typos don't silently drop parts of a flow.
+func parseDocument(data []byte, format string) (*FlowDocument, error) {
+	var doc FlowDocument
+
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+		}
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	if doc.Version != 0 && doc.Version != documentVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidDocument, doc.Version)
+	}
+	if len(doc.Blocks) == 0 {
+		return nil, fmt.Errorf("%w: no blocks", ErrInvalidDocument)
+	}
+
+	return &doc, nil
+}
@@ -0,0 +1,155 @@
+package flows
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"smart-forms/internal/versions"
+)
+
+// FragmentSource looks up the flow fragments a flow embeds (implemented by
+// the fragments package)
+type FragmentSource interface {
+	// CheckFragment returns the name of a fragment the user may embed;
+	// ErrFragmentNotFound if there is none or the version doesn't exist
+	CheckFragment(ctx context.Context, userID, fragmentID string, version *int) (string, error)
+	// FragmentBlocks returns the blocks of a fragment version (nil = latest)
+	FragmentBlocks(ctx context.Context, fragmentID string, version *int) ([]Block, error)
+}
+
+// SetFragments enables fragment blocks; without a source they are rejected
+func (s *FlowService) SetFragments(source FragmentSource) {
+	s.fragments = source
+}
+
+// prepareEmbeds checks the fragment blocks of a flow and names them after
+// their fragment when they have no question text
+func (s *FlowService) prepareEmbeds(ctx context.Context, userID string, blocks []Block) error {
+	for i := range blocks {
+		b := &blocks[i]
+		if b.Fragment == nil && b.Type != versions.TypeFragment {
+			if err := s.prepareEmbeds(ctx, userID, b.Children); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if b.Fragment == nil || len(b.Children) > 0 {
+			return ErrInvalidInput
+		}
+		if s.fragments == nil {
+			return ErrFragmentNotFound
+		}
+		name, err := s.fragments.CheckFragment(ctx, userID, b.Fragment.ID, b.Fragment.Version)
+		if err != nil {
+			return err
+		}
+		b.Type = versions.TypeFragment
+		if strings.TrimSpace(b.Question) == "" {
+			b.Question = name
+		}
+	}
+	return nil
+}
+
+// expandFragments returns a copy of blocks with the content of every fragment
+// they embed, the way publishing expands them. Used to lint and simulate the
+// flow respondents will get.
+func (s *FlowService) expandFragments(ctx context.Context, blocks []Block) ([]Block, error) {
+	if s.fragments == nil {
+		return blocks, nil
+	}
+
+	count := 0
+	var expand func(blocks []Block) ([]Block, error)
+	expand = func(blocks []Block) ([]Block, error) {
+		result := make([]Block, len(blocks))
+		for i, b := range blocks {
+			if b.Fragment == nil {
+				children, err := expand(b.Children)
+				if err != nil {
+					return nil, err
+				}
+				b.Children = children
+				result[i] = b
+				continue
+			}
+
+			content, err := s.fragments.FragmentBlocks(ctx, b.Fragment.ID, b.Fragment.Version)
+			if err != nil {
+				return nil, err
+			}
+			prefix := b.ID
+			if prefix == "" {
+				prefix = "fragment" + strconv.Itoa(count)
+			}
+			count++
+			result[i] = ExpandFragment(b, content, prefix+"/")
+		}
+		return result, nil
+	}
+	return expand(blocks)
+}
+
+// ExpandFragment returns a fragment block with the fragment's content as its
+// children. Content block IDs get prefix so several embeds of one fragment
+// stay distinct. Where the content ends (no children, no jumps) the flow
+// continues at the fragment block's own jump targets.
+func ExpandFragment(embed Block, content []Block, prefix string) Block {
+	var copyBlocks func(blocks []Block) []Block
+	copyBlocks = func(blocks []Block) []Block {
+		if len(blocks) == 0 {
+			return nil
+		}
+		result := make([]Block, len(blocks))
+		for i, b := range blocks {
+			if b.ID != "" {
+				b.ID = prefix + b.ID
+			}
+			var next []string
+			for _, target := range b.Next {
+				next = append(next, prefix+target)
+			}
+			if len(b.Children) == 0 && len(next) == 0 {
+				next = append(next, embed.Next...)
+			}
+			b.Next = next
+			b.Children = copyBlocks(b.Children)
+			result[i] = b
+		}
+		return result
+	}
+
+	embed.Children = copyBlocks(content)
+	return embed
+}
+
+// ValidateFragment checks the blocks of a new fragment version like a flow
+// being imported: save checks, then the linter (a LintError carries the
+// report). Fragments don't embed other fragments. They declare no variables:
+// the variables their blocks use are those of the flows embedding them.
+func ValidateFragment(blocks []Block) error {
+	if len(blocks) == 0 || embedsFragment(blocks) {
+		return ErrInvalidInput
+	}
+	if err := validateBlocks(blocks); err != nil {
+		return err
+	}
+	if err := validateGraph(blocks); err != nil {
+		return err
+	}
+	if report := lintBlocks(blocks, nil, nil); !report.Valid {
+		return &versions.LintError{Report: report}
+	}
+	return nil
+}
+
+func embedsFragment(blocks []Block) bool {
+	for _, b := range blocks {
+		if b.Fragment != nil || b.Type == versions.TypeFragment || embedsFragment(b.Children) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package flows
+
+import (
+	"strconv"
+
+	"smart-forms/internal/versions"
+)
+
+// validateGraph checks the jumps of a full flow: every target must be the ID
+// of exactly one block in the flow, and the flow must stay acyclic. Blocks
+// are identified by their position, since not every block has an ID.
+func validateGraph(blocks []Block) error {
+	ids := make(map[string]int)
+	var all []*Block
+	var collect func(blocks []Block)
+	collect = func(blocks []Block) {
+		for i := range blocks {
+			b := &blocks[i]
+			if b.ID != "" {
+				ids[b.ID]++
+			}
+			all = append(all, b)
+			collect(b.Children)
+		}
+	}
+	collect(blocks)
+
+	index := make(map[*Block]string, len(all))
+	for i, b := range all {
+		index[b] = strconv.Itoa(i)
+	}
+	byID := make(map[string]*Block)
+	for _, b := range all {
+		if b.ID != "" {
+			byID[b.ID] = b
+		}
+	}
+
+	hasJumps := false
+	successors := make(map[string][]string, len(all))
+	for _, b := range all {
+		key := index[b]
+		for i := range b.Children {
+			successors[key] = append(successors[key], index[&b.Children[i]])
+		}
+		for _, target := range b.Next {
+			if ids[target] != 1 {
+				return ErrJumpTarget
+			}
+			successors[key] = append(successors[key], index[byID[target]])
+			hasJumps = true
+		}
+	}
+
+	// Without jumps the flow is a tree
+	if hasJumps && versions.HasCycle(successors) {
+		return ErrFlowCycle
+	}
+	return nil
+}
@@ -1,55 +1,254 @@
 package flows
 
 import (
+	"errors"
+	"strings"
+
+	"smart-forms/internal/collaborators"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/versions"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type FlowHandler struct {
 	service *FlowService
+	access  *collaborators.AccessChecker
 }
 
-func NewFlowHandler(service *FlowService) *FlowHandler {
-	return &FlowHandler{service: service}
+func NewFlowHandler(service *FlowService, access *collaborators.AccessChecker) *FlowHandler {
+	return &FlowHandler{
+		service: service,
+		access:  access,
+	}
 }
 
 func (h *FlowHandler) UpdateFlow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	var req FlowRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
 	}
 
-	mapping, err := h.service.UpdateFlow(c.Context(), userID, formID, req)
+	// Optimistic concurrency: If-Match carries the ETag of GET /flow
+	mapping, etag, err := h.service.UpdateFlow(c.Context(), userID, formID, c.Get(fiber.HeaderIfMatch), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	c.Set(fiber.HeaderETag, etag)
+	return c.JSON(fiber.Map{
+		"message": "Flow updated successfully",
+		"mapping": mapping,
+	})
+}
+
+// ApplyOperations edits the flow incrementally (see docs/flows.txt)
+func (h *FlowHandler) ApplyOperations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	var req OperationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	mapping, etag, err := h.service.ApplyOperations(c.Context(), userID, formID, c.Get(fiber.HeaderIfMatch), req)
 	if err != nil {
 		return mapServiceError(err)
 	}
 
+	c.Set(fiber.HeaderETag, etag)
 	return c.JSON(fiber.Map{
 		"message": "Flow updated successfully",
 		"mapping": mapping,
 	})
 }
 
+// ValidateFlow lints a flow without saving it
+func (h *FlowHandler) ValidateFlow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	// The body is optional: without blocks the current flow is linted
+	var req FlowRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.ErrBadRequest
+		}
+	}
+
+	report, err := h.service.ValidateFlow(c.Context(), userID, formID, req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(report)
+}
+
+// ExportFlow downloads the flow as json, yaml, mermaid or dot
+// GET /forms/:form_id/flow/export?format=
+func (h *FlowHandler) ExportFlow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	format := c.Query("format", FormatJSON)
+	data, etag, err := h.service.ExportFlow(c.Context(), userID, formID, format)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+	c.Set(fiber.HeaderContentType, ContentType(format))
+	return c.Send(data)
+}
+
+// ImportFlow replaces the flow with a YAML or JSON flow document
+// POST /forms/:form_id/flow/import?format=&dry_run=
+func (h *FlowHandler) ImportFlow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	// Format from the query, else from the Content-Type
+	format := c.Query("format")
+	if format == "" {
+		format = FormatJSON
+		if strings.Contains(c.Get(fiber.HeaderContentType), "yaml") {
+			format = FormatYAML
+		}
+	}
+	dryRun := c.QueryBool("dry_run", false)
+
+	mapping, report, etag, err := h.service.ImportFlow(c.Context(), userID, formID, c.Get(fiber.HeaderIfMatch), format, c.Body(), dryRun)
+	if err != nil {
+		var lintErr *versions.LintError
+		if errors.As(err, &lintErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Flow has errors; nothing was imported",
+				"lint":    lintErr.Report,
+			})
+		}
+		return mapServiceError(err)
+	}
+
+	if dryRun {
+		return c.JSON(fiber.Map{
+			"message": "Flow is valid",
+			"lint":    report,
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag)
+	return c.JSON(fiber.Map{
+		"message": "Flow imported successfully",
+		"mapping": mapping,
+		"lint":    report,
+	})
+}
+
+// SimulateFlow dry-runs the flow: follows answers, or enumerates paths
+// POST /forms/:form_id/flow/simulate
+func (h *FlowHandler) SimulateFlow(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	var req SimulateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	switch req.Mode {
+	case "", SimulateAnswers:
+		result, err := h.service.Simulate(c.Context(), userID, formID, req.Answers)
+		if err != nil {
+			return mapServiceError(err)
+		}
+		return c.JSON(result)
+	case SimulatePaths:
+		result, err := h.service.SimulatePaths(c.Context(), userID, formID, req.MaxPaths)
+		if err != nil {
+			return mapServiceError(err)
+		}
+		return c.JSON(result)
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "mode must be answers or paths")
+	}
+}
+
 func (h *FlowHandler) GetFlow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
-	tree, err := h.service.GetFlowTree(c.Context(), userID, formID)
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	tree, etag, err := h.service.GetFlowTree(c.Context(), userID, formID)
 	if err != nil {
 		return mapServiceError(err)
 	}
 
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+
 	return c.JSON(tree)
 }
 
 func mapServiceError(err error) error {
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return fiber.NewError(fiber.StatusBadRequest, opErr.Error())
+	}
+	if errors.Is(err, ErrInvalidDocument) || errors.Is(err, kinds.ErrInvalidSpec) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	switch err {
 	case ErrInvalidInput:
 		return fiber.ErrBadRequest
+	case ErrJumpTarget:
+		return fiber.NewError(fiber.StatusBadRequest, "Jump target not found in flow")
+	case ErrFlowCycle:
+		return fiber.NewError(fiber.StatusBadRequest, "Flow contains a cycle")
+	case ErrFragmentNotFound:
+		return fiber.NewError(fiber.StatusBadRequest, "Fragment or fragment version not found")
+	case ErrInvalidFormat:
+		return fiber.NewError(fiber.StatusBadRequest, "format must be one of json, yaml, mermaid, dot")
 	case ErrNotFound, ErrFormNotFound:
 		return fiber.ErrNotFound
+	case ErrDraftChanged:
+		return fiber.NewError(fiber.StatusPreconditionFailed, "Flow was changed by someone else; reload it and retry")
+	case ErrNoIfMatch:
+		return fiber.NewError(fiber.StatusPreconditionRequired, "If-Match is required: send the ETag of GET /flow (* for a form without one)")
 	default:
 		return fiber.ErrInternalServerError
 	}
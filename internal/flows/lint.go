@@ -0,0 +1,77 @@
+package flows
+
+import (
+	"strconv"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/variables"
+	"smart-forms/internal/versions"
+)
+
+// blocksToNodes flattens request blocks for the linter. Blocks get internal
+// IDs (client IDs are optional and may repeat); the returned map leads back
+// to the client IDs.
+func blocksToNodes(blocks []Block) ([]versions.Node, map[string]string) {
+	var nodes []versions.Node
+	clientIDs := make(map[string]string)
+	byClientID := make(map[string][]string)
+
+	var flatten func(blocks []Block, parentID *string, depth int)
+	flatten = func(blocks []Block, parentID *string, depth int) {
+		for i, b := range blocks {
+			id := "#" + strconv.Itoa(len(nodes))
+			clientIDs[id] = b.ID
+			if b.ID != "" {
+				byClientID[b.ID] = append(byClientID[b.ID], id)
+			}
+			nodes = append(nodes, versions.Node{
+				ID:         id,
+				ParentID:   parentID,
+				OrderIndex: i,
+				DepthLevel: depth,
+				Type:       b.Type,
+				Question:   b.Question,
+				Next:       b.Next,
+				Variable:   b.Variable,
+				Set:        b.Set,
+				Quiz:       b.Quiz,
+				Ending:     b.Ending,
+				Randomize:  b.Randomize,
+				Kind:       b.Kind,
+			})
+			flatten(b.Children, &id, depth+1)
+		}
+	}
+	flatten(blocks, nil, 0)
+
+	// Jumps name client IDs; ambiguous or unknown ones stay as they are and
+	// are reported as unknown targets
+	for i := range nodes {
+		if len(nodes[i].Next) == 0 {
+			continue
+		}
+		next := make([]string, len(nodes[i].Next))
+		for j, target := range nodes[i].Next {
+			next[j] = target
+			if ids := byClientID[target]; len(ids) == 1 {
+				next[j] = ids[0]
+			}
+		}
+		nodes[i].Next = next
+	}
+
+	return nodes, clientIDs
+}
+
+// lintBlocks lints request blocks with the flow's variable declarations and
+// endings, and reports client block IDs
+func lintBlocks(blocks []Block, vars []variables.Variable, ends []endings.Ending) *versions.LintReport {
+	nodes, clientIDs := blocksToNodes(blocks)
+	report := versions.Lint(nodes, vars, ends)
+	for _, issues := range [][]versions.LintIssue{report.Errors, report.Warnings} {
+		for i := range issues {
+			issues[i].BlockID = clientIDs[issues[i].BlockID]
+		}
+	}
+	return report
+}
@@ -1,6 +1,15 @@
 package flows
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+)
 
 type FlowConnection struct {
 	ID         string    `json:"id"`
@@ -17,12 +26,91 @@ type FlowConnection struct {
 
 // Request structures
 type Block struct {
-	ID       string   `json:"id"`
-	Type     string   `json:"type"`
-	Question string   `json:"question"`
-	Children []Block  `json:"children"`
+	ID       string   `json:"id" yaml:"id,omitempty"`
+	Type     string   `json:"type" yaml:"type"`
+	Question string   `json:"question" yaml:"question"`
+	Children []Block  `json:"children" yaml:"children,omitempty"`
+	Next     []string `json:"next,omitempty" yaml:"next,omitempty"` // IDs of blocks to jump to after this one
+	// Fragment embeds a flow fragment (type "fragment", no children); the
+	// question defaults to the fragment's name
+	Fragment *FragmentRef `json:"fragment,omitempty" yaml:"fragment,omitempty"`
+	// Variable stores the block's answer (a question's chosen option text)
+	// under a name; Set runs assignments when a respondent passes the block
+	Variable string                 `json:"variable,omitempty" yaml:"variable,omitempty"`
+	Set      []variables.Assignment `json:"set,omitempty" yaml:"set,omitempty"`
+	// Quiz holds the block's correct flag, points, feedback or score routing,
+	// stored in its question's metadata
+	Quiz *quiz.Scoring `json:"quiz,omitempty" yaml:"quiz,omitempty"`
+	// Ending names the ending screen shown to respondents who finish here
+	Ending string `json:"ending,omitempty" yaml:"ending,omitempty"`
+	// Randomize shuffles or samples the block's children per respondent
+	Randomize *randomize.Settings `json:"randomize,omitempty" yaml:"randomize,omitempty"`
+	// Kind makes a question a rating, NPS, matrix... (see kinds), stored in
+	// its question's metadata
+	Kind *kinds.Spec `json:"kind,omitempty" yaml:"kind,omitempty"`
+}
+
+// FragmentRef points at a fragment version; a nil Version follows the latest
+type FragmentRef struct {
+	ID      string `json:"id" yaml:"id"`
+	Version *int   `json:"version,omitempty" yaml:"version,omitempty"`
 }
 
 type FlowRequest struct {
 	Blocks []Block `json:"blocks"`
+	// Variables replaces the flow's variable declarations; omitted keeps them
+	Variables []variables.Variable `json:"variables,omitempty"`
+	// Endings replaces the flow's ending screens; omitted keeps them
+	Endings []endings.Ending `json:"endings,omitempty"`
+}
+
+// FlowDocument is a flow as exported to and imported from files
+type FlowDocument struct {
+	Version   int                  `json:"version" yaml:"version"` // document format, currently 1
+	Variables []variables.Variable `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Endings   []endings.Ending     `json:"endings,omitempty" yaml:"endings,omitempty"`
+	Blocks    []Block              `json:"blocks" yaml:"blocks"`
+}
+
+// Operation is one incremental edit of the draft flow. Node IDs may be the
+// draft's connection IDs or those of the published version it was copied from.
+type Operation struct {
+	Op       string   `json:"op"`                  // add | move | reorder | rename | delete | link | unlink | logic | variables | quiz | ending | endings | randomize | kind
+	ID       string   `json:"id,omitempty"`        // node to move, rename or delete
+	ParentID *string  `json:"parent_id,omitempty"` // add, move, reorder: target parent (omitted = root)
+	Index    *int     `json:"index,omitempty"`     // add, move: position among siblings (omitted = last)
+	Block    *Block   `json:"block,omitempty"`     // add: subtree to insert
+	Type     string   `json:"type,omitempty"`      // rename: new question type (omitted = keep)
+	Question string   `json:"question,omitempty"`  // rename: new question text
+	Order    []string `json:"order,omitempty"`     // reorder: all children of parent_id, in order
+	Target   string   `json:"target,omitempty"`    // link, unlink: jump target
+	// logic: the node's answer variable and assignments (both replaced);
+	// variables: the flow's variable declarations (replaced)
+	Variable  string                 `json:"variable,omitempty"`
+	Set       []variables.Assignment `json:"set,omitempty"`
+	Variables []variables.Variable   `json:"variables,omitempty"`
+	// quiz: the node's quiz data (replaced; omitted clears it)
+	Quiz *quiz.Scoring `json:"quiz,omitempty"`
+	// ending: the node's ending (omitted clears it); endings: the flow's
+	// ending screens (replaced)
+	Ending  string           `json:"ending,omitempty"`
+	Endings []endings.Ending `json:"endings,omitempty"`
+	// randomize: the node's randomization (replaced; omitted clears it)
+	Randomize *randomize.Settings `json:"randomize,omitempty"`
+	// kind: the node's question kind (replaced; omitted clears it)
+	Kind *kinds.Spec `json:"kind,omitempty"`
+}
+
+type OperationsRequest struct {
+	Operations []Operation `json:"operations"`
+}
+
+// OperationError rejects an operation that does not fit the current tree
+type OperationError struct {
+	Index   int
+	Message string
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
 }
@@ -0,0 +1,591 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+	"smart-forms/internal/versions"
+
+	"github.com/google/uuid"
+)
+
+// maxOperations caps one operations request
+const maxOperations = 200
+
+// nodePlacement is what a save writes for a draft connection
+type nodePlacement struct {
+	parentID   *string
+	questionID string
+	orderIndex int
+	depthLevel int
+	isTerminal bool
+}
+
+func (p nodePlacement) equal(o nodePlacement) bool {
+	sameParent := (p.parentID == nil && o.parentID == nil) ||
+		(p.parentID != nil && o.parentID != nil && *p.parentID == *o.parentID)
+	return sameParent && p.questionID == o.questionID && p.orderIndex == o.orderIndex &&
+		p.depthLevel == o.depthLevel && p.isTerminal == o.isTerminal
+}
+
+// flowNode is a draft connection while operations are applied. An empty
+// questionID means the question changed and is resolved when saving.
+type flowNode struct {
+	nodePlacement
+	id       string
+	originID *string
+	qType    string
+	question string
+
+	// Set on fragment nodes; fixed once the node exists
+	fragmentID      *string
+	fragmentVersion *int
+
+	// Answer variable and assignments; logicChanged marks them for saving
+	variable     string
+	set          []variables.Assignment
+	logicChanged bool
+
+	// Quiz data and the kind live on the question, so changing them
+	// resolves the question again
+	quiz *quiz.Scoring
+	kind *kinds.Spec
+
+	// Ending screen; endingChanged marks it for saving
+	ending        string
+	endingChanged bool
+
+	// Randomization of the children; randomizeChanged marks it for saving
+	randomize        *randomize.Settings
+	randomizeChanged bool
+
+	saved   nodePlacement
+	isNew   bool
+	deleted bool
+}
+
+// flowTree is the draft flow in memory. Operations are validated and applied
+// here first; save writes only the nodes that changed, so untouched nodes
+// keep their IDs.
+type flowTree struct {
+	nodes    map[string]*flowNode
+	origins  map[string]string   // origin connection ID -> draft ID
+	children map[string][]string // parent ID ("" = root) -> ordered child IDs
+	next     map[string][]string // jump source ID -> ordered target IDs
+
+	edgesChanged bool
+
+	// Set by a variables operation: the draft's new declarations
+	variables        []variables.Variable
+	variablesChanged bool
+
+	// Set by an endings operation: the draft's new ending screens
+	endings        []endings.Ending
+	endingsChanged bool
+}
+
+func newFlowTree(nodes []*flowNode, next map[string][]string) *flowTree {
+	t := &flowTree{
+		nodes:    make(map[string]*flowNode, len(nodes)),
+		origins:  make(map[string]string),
+		children: make(map[string][]string),
+		next:     next,
+	}
+	// Nodes come ordered by depth and order_index
+	for _, n := range nodes {
+		t.nodes[n.id] = n
+		if n.originID != nil {
+			t.origins[*n.originID] = n.id
+		}
+		key := parentKey(n.parentID)
+		t.children[key] = append(t.children[key], n.id)
+	}
+	return t
+}
+
+func parentKey(parentID *string) string {
+	if parentID == nil {
+		return ""
+	}
+	return *parentID
+}
+
+// resolve maps a node ID sent by the client to the live draft node. IDs of
+// the version the draft was copied from are translated and recorded in
+// mapping; client IDs of blocks added earlier in the request resolve too.
+func (t *flowTree) resolve(id string, mapping map[string]string) (*flowNode, bool) {
+	if n, ok := t.nodes[id]; ok && !n.deleted {
+		return n, true
+	}
+	if draftID, ok := mapping[id]; ok {
+		if n := t.nodes[draftID]; !n.deleted {
+			return n, true
+		}
+	}
+	if draftID, ok := t.origins[id]; ok {
+		if n := t.nodes[draftID]; !n.deleted {
+			mapping[id] = draftID
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// resolveParent returns the children key of a target parent
+func (t *flowTree) resolveParent(parentID *string, mapping map[string]string) (string, bool) {
+	if parentID == nil || *parentID == "" {
+		return "", true
+	}
+	n, ok := t.resolve(*parentID, mapping)
+	if !ok {
+		return "", false
+	}
+	return n.id, true
+}
+
+func (t *flowTree) detach(n *flowNode) {
+	key := parentKey(n.parentID)
+	siblings := t.children[key]
+	for i, id := range siblings {
+		if id == n.id {
+			t.children[key] = append(siblings[:i:i], siblings[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *flowTree) attach(n *flowNode, key string, index int) {
+	siblings := t.children[key]
+	siblings = append(siblings[:index:index], append([]string{n.id}, siblings[index:]...)...)
+	t.children[key] = siblings
+	if key == "" {
+		n.parentID = nil
+	} else {
+		parent := key
+		n.parentID = &parent
+	}
+}
+
+// position checks an optional sibling index; omitted means last
+func position(index *int, count int) (int, bool) {
+	if index == nil {
+		return count, true
+	}
+	if *index < 0 || *index > count {
+		return 0, false
+	}
+	return *index, true
+}
+
+// apply validates one operation against the current tree and applies it
+func (t *flowTree) apply(i int, op Operation, mapping map[string]string) error {
+	fail := func(msg string) error { return &OperationError{Index: i, Message: msg} }
+
+	switch op.Op {
+	case "add":
+		if op.Block == nil {
+			return fail("block is required")
+		}
+		if err := validateBlocks([]Block{*op.Block}); err != nil {
+			if errors.Is(err, kinds.ErrInvalidSpec) {
+				return fail(err.Error())
+			}
+			return fail("every block needs a type and a question")
+		}
+		key, ok := t.resolveParent(op.ParentID, mapping)
+		if !ok {
+			return fail("parent not found")
+		}
+		if t.isFragment(key) {
+			return fail("fragment blocks can't have children")
+		}
+		index, ok := position(op.Index, len(t.children[key]))
+		if !ok {
+			return fail("index out of range")
+		}
+		jumps := make(map[string][]string)
+		t.addBlock(*op.Block, key, index, mapping, jumps)
+
+		// Jumps may target existing nodes or blocks of the new subtree
+		for sourceID, targets := range jumps {
+			for _, id := range targets {
+				target, ok := t.resolve(id, mapping)
+				if !ok {
+					return fail("jump target not found")
+				}
+				t.next[sourceID] = append(t.next[sourceID], target.id)
+			}
+			t.edgesChanged = true
+		}
+		if len(jumps) > 0 && t.cyclic() {
+			return fail("jumps would create a cycle")
+		}
+
+	case "move":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		key, ok := t.resolveParent(op.ParentID, mapping)
+		if !ok {
+			return fail("parent not found")
+		}
+		if t.isFragment(key) {
+			return fail("fragment blocks can't have children")
+		}
+		// A node can't move into its own subtree
+		for p := key; p != ""; p = parentKey(t.nodes[p].parentID) {
+			if p == n.id {
+				return fail("cannot move a node into its own subtree")
+			}
+		}
+		t.detach(n)
+		index, ok := position(op.Index, len(t.children[key]))
+		if !ok {
+			return fail("index out of range")
+		}
+		t.attach(n, key, index)
+		if t.cyclic() {
+			return fail("move would create a cycle")
+		}
+
+	case "reorder":
+		key, ok := t.resolveParent(op.ParentID, mapping)
+		if !ok {
+			return fail("parent not found")
+		}
+		current := t.children[key]
+		if len(op.Order) != len(current) {
+			return fail("order must list every child of the parent exactly once")
+		}
+		isChild := make(map[string]bool, len(current))
+		for _, id := range current {
+			isChild[id] = true
+		}
+		order := make([]string, 0, len(op.Order))
+		for _, id := range op.Order {
+			n, ok := t.resolve(id, mapping)
+			if !ok || !isChild[n.id] {
+				return fail("order must list every child of the parent exactly once")
+			}
+			delete(isChild, n.id)
+			order = append(order, n.id)
+		}
+		t.children[key] = order
+
+	case "rename":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		question := strings.TrimSpace(op.Question)
+		if question == "" {
+			return fail("question is required")
+		}
+		qType := op.Type
+		if qType == "" {
+			qType = n.qType
+		}
+		if (qType == versions.TypeFragment) != (n.fragmentID != nil) {
+			return fail("a fragment block's type can't change")
+		}
+		if qType != n.qType || question != n.question {
+			n.qType, n.question, n.questionID = qType, question, ""
+		}
+
+	case "delete":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		t.detach(n)
+		t.markDeleted(n.id)
+		t.pruneEdges()
+
+	case "link":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		target, ok := t.resolve(op.Target, mapping)
+		if !ok {
+			return fail("jump target not found")
+		}
+		for _, id := range t.next[n.id] {
+			if id == target.id {
+				return fail("jump already exists")
+			}
+		}
+		t.next[n.id] = append(t.next[n.id], target.id)
+		t.edgesChanged = true
+		if t.cyclic() {
+			return fail("jump would create a cycle")
+		}
+
+	case "unlink":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		target, ok := t.resolve(op.Target, mapping)
+		if !ok {
+			return fail("jump not found")
+		}
+		targets := t.next[n.id]
+		found := false
+		for i, id := range targets {
+			if id == target.id {
+				t.next[n.id] = append(targets[:i:i], targets[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("jump not found")
+		}
+		t.edgesChanged = true
+
+	case "logic":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		n.variable, n.set, n.logicChanged = strings.TrimSpace(op.Variable), op.Set, true
+
+	case "variables":
+		t.variables, t.variablesChanged = op.Variables, true
+
+	case "quiz":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		if n.fragmentID != nil {
+			return fail("fragment blocks can't have quiz data")
+		}
+		if msg := op.Quiz.Check(); msg != "" {
+			return fail(msg)
+		}
+		n.quiz, n.questionID = op.Quiz, ""
+
+	case "ending":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		n.ending, n.endingChanged = strings.TrimSpace(op.Ending), true
+
+	case "endings":
+		t.endings, t.endingsChanged = op.Endings, true
+
+	case "randomize":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		if err := op.Randomize.Validate(); err != nil {
+			return fail(err.Error())
+		}
+		n.randomize, n.randomizeChanged = op.Randomize, true
+
+	case "kind":
+		n, ok := t.resolve(op.ID, mapping)
+		if !ok {
+			return fail("node not found")
+		}
+		if op.Kind != nil {
+			if n.qType != versions.TypeQuestion {
+				return fail("only questions can have a kind")
+			}
+			if err := op.Kind.Validate(); err != nil {
+				return fail(err.Error())
+			}
+		}
+		n.kind, n.questionID = op.Kind, ""
+
+	default:
+		return fail("unknown op " + op.Op)
+	}
+	return nil
+}
+
+func (t *flowTree) addBlock(block Block, key string, index int, mapping map[string]string, jumps map[string][]string) {
+	n := &flowNode{
+		id:        uuid.NewString(),
+		qType:     block.Type,
+		question:  strings.TrimSpace(block.Question),
+		variable:  strings.TrimSpace(block.Variable),
+		set:       block.Set,
+		quiz:      block.Quiz,
+		kind:      block.Kind,
+		ending:    strings.TrimSpace(block.Ending),
+		randomize: block.Randomize,
+		isNew:     true,
+	}
+	if block.Fragment != nil {
+		n.fragmentID, n.fragmentVersion = &block.Fragment.ID, block.Fragment.Version
+	}
+	t.nodes[n.id] = n
+	t.attach(n, key, index)
+
+	if block.ID != "" {
+		mapping[block.ID] = n.id
+	}
+	if len(block.Next) > 0 {
+		jumps[n.id] = block.Next
+	}
+	for i, child := range block.Children {
+		t.addBlock(child, n.id, i, mapping, jumps)
+	}
+}
+
+// isFragment reports whether a children key belongs to a fragment node
+func (t *flowTree) isFragment(key string) bool {
+	n, ok := t.nodes[key]
+	return ok && n.fragmentID != nil
+}
+
+func (t *flowTree) markDeleted(id string) {
+	t.nodes[id].deleted = true
+	for _, child := range t.children[id] {
+		t.markDeleted(child)
+	}
+	delete(t.children, id)
+}
+
+// pruneEdges drops jumps from or to deleted nodes
+func (t *flowTree) pruneEdges() {
+	for source, targets := range t.next {
+		if !t.live(source) {
+			delete(t.next, source)
+			t.edgesChanged = true
+			continue
+		}
+		live := targets[:0:0]
+		for _, id := range targets {
+			if t.live(id) {
+				live = append(live, id)
+			}
+		}
+		if len(live) != len(targets) {
+			t.next[source] = live
+			t.edgesChanged = true
+		}
+	}
+}
+
+func (t *flowTree) live(id string) bool {
+	n, ok := t.nodes[id]
+	return ok && !n.deleted
+}
+
+// cyclic reports whether children and jumps form a cycle
+func (t *flowTree) cyclic() bool {
+	successors := make(map[string][]string, len(t.nodes))
+	for parent, children := range t.children {
+		if parent != "" {
+			successors[parent] = append(successors[parent], children...)
+		}
+	}
+	for source, targets := range t.next {
+		successors[source] = append(successors[source], targets...)
+	}
+	return versions.HasCycle(successors)
+}
+
+// walk visits live nodes parents first, fixing order, depth and terminal flags
+func (t *flowTree) walk(key string, depth int, visit func(*flowNode) error) error {
+	for i, id := range t.children[key] {
+		n := t.nodes[id]
+		n.orderIndex = i
+		n.depthLevel = depth
+		n.isTerminal = len(t.children[id]) == 0 && len(t.next[id]) == 0
+		if err := visit(n); err != nil {
+			return err
+		}
+		if err := t.walk(id, depth+1, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes the changed nodes of the tree to the draft
+func (t *flowTree) save(ctx context.Context, repo *FlowRepository, userID, formID string) error {
+	err := t.walk("", 0, func(n *flowNode) error {
+		if n.questionID == "" {
+			questionID, err := findOrCreateQuestion(ctx, repo, userID, n.qType, n.question, n.quiz, n.kind)
+			if err != nil {
+				return err
+			}
+			n.questionID = questionID
+		}
+		if n.isNew {
+			return repo.InsertNode(ctx, formID, n)
+		}
+		if !n.nodePlacement.equal(n.saved) {
+			if err := repo.UpdateNode(ctx, n); err != nil {
+				return err
+			}
+		}
+		if n.logicChanged {
+			if err := repo.SetLogic(ctx, n.id, n.variable, n.set); err != nil {
+				return err
+			}
+		}
+		if n.endingChanged {
+			if err := repo.SetEnding(ctx, n.id, n.ending); err != nil {
+				return err
+			}
+		}
+		if n.randomizeChanged {
+			return repo.SetRandomize(ctx, n.id, n.randomize)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if t.variablesChanged {
+		if err := repo.SetDraftVariables(ctx, formID, t.variables); err != nil {
+			return err
+		}
+	}
+	if t.endingsChanged {
+		if err := repo.SetDraftEndings(ctx, formID, t.endings); err != nil {
+			return err
+		}
+	}
+
+	// Jumps carry no identity of their own, so changed ones are rewritten
+	if t.edgesChanged {
+		if err := repo.DeleteDraftEdges(ctx, formID); err != nil {
+			return err
+		}
+		for source, targets := range t.next {
+			for i, target := range targets {
+				if err := repo.CreateEdge(ctx, formID, source, target, i); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var deleted []string
+	for _, n := range t.nodes {
+		if n.deleted && !n.isNew {
+			deleted = append(deleted, n.id)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	return repo.DeleteNodes(ctx, deleted)
+}
@@ -2,28 +2,221 @@ package flows
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+	"smart-forms/internal/versions"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// dbtx is satisfied by both the pool and a transaction
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type FlowRepository struct {
-	db *pgxpool.Pool
+	pool *pgxpool.Pool
+	db   dbtx
 }
 
 func NewFlowRepository(db *pgxpool.Pool) *FlowRepository {
-	return &FlowRepository{db: db}
+	return &FlowRepository{pool: db, db: db}
+}
+
+// InTx runs fn with a repository bound to a single transaction. The
+// transaction commits if fn returns nil and rolls back otherwise.
+func (r *FlowRepository) InTx(ctx context.Context, fn func(tx *FlowRepository) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&FlowRepository{pool: r.pool, db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LockDraft locks the form (serializing saves and publishes) and returns the
+// ETag of its draft version. Must run inside InTx. Returns ErrDraftChanged if
+// the form has no draft, e.g. because it was published meanwhile.
+func (r *FlowRepository) LockDraft(ctx context.Context, formID string) (string, error) {
+	var number, revision int
+	err := r.db.QueryRow(ctx, `
+		SELECT v.version_number, v.revision
+		FROM forms f
+		JOIN form_versions v ON v.form_id = f.id AND v.status = 'draft'
+		WHERE f.id = $1
+		FOR UPDATE OF f, v
+	`, formID).Scan(&number, &revision)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrDraftChanged
+		}
+		return "", err
+	}
+	return versions.ETag(number, revision), nil
 }
 
+// BumpRevision records a save of the form's draft and returns its new ETag
+func (r *FlowRepository) BumpRevision(ctx context.Context, formID string) (string, error) {
+	var number, revision int
+	err := r.db.QueryRow(ctx, `
+		UPDATE form_versions
+		SET revision = revision + 1
+		WHERE form_id = $1 AND status = 'draft'
+		RETURNING version_number, revision
+	`, formID).Scan(&number, &revision)
+	if err != nil {
+		return "", err
+	}
+	return versions.ETag(number, revision), nil
+}
+
+// Flows are edited in the form's draft version; the editor shows the draft,
+// or the published version while there is no draft
+const (
+	draftVersionSQL = `(SELECT id FROM form_versions WHERE form_id = $1 AND status = 'draft')`
+	headVersionSQL  = `(SELECT id FROM form_versions WHERE form_id = $1 AND status IN ('draft', 'published') ORDER BY status = 'draft' DESC LIMIT 1)`
+)
+
+// DeleteByFormID clears the flow of the form's draft version
 func (r *FlowRepository) DeleteByFormID(ctx context.Context, formID string) error {
-	_, err := r.db.Exec(ctx, `UPDATE flow_connections SET deleted_at = NOW() WHERE form_id = $1 AND deleted_at IS NULL`, formID)
+	if err := r.DeleteDraftEdges(ctx, formID); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(ctx, `UPDATE flow_connections SET deleted_at = NOW() WHERE form_id = $1 AND version_id = `+draftVersionSQL+` AND deleted_at IS NULL`, formID)
+	return err
+}
+
+// DeleteDraftEdges removes the jumps of the form's draft version
+func (r *FlowRepository) DeleteDraftEdges(ctx context.Context, formID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM flow_edges WHERE version_id = `+draftVersionSQL, formID)
+	return err
+}
+
+// SetFragment makes a draft connection embed a fragment
+func (r *FlowRepository) SetFragment(ctx context.Context, id string, fragment *FragmentRef) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE flow_connections SET fragment_id = $2, fragment_version = $3 WHERE id = $1
+	`, id, fragment.ID, fragment.Version)
+	return err
+}
+
+// SetLogic sets a draft connection's answer variable and assignments
+func (r *FlowRepository) SetLogic(ctx context.Context, id, variable string, set []variables.Assignment) error {
+	assignments, err := versions.EncodeAssignments(set)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		UPDATE flow_connections SET variable = NULLIF($2, ''), assignments = $3 WHERE id = $1
+	`, id, variable, assignments)
+	return err
+}
+
+// SetEnding sets the ending a draft connection leads to ("" = none)
+func (r *FlowRepository) SetEnding(ctx context.Context, id, ending string) error {
+	_, err := r.db.Exec(ctx, `UPDATE flow_connections SET ending = NULLIF($2, '') WHERE id = $1`, id, ending)
+	return err
+}
+
+// SetRandomize sets how a draft connection's children are randomized (nil =
+// not at all)
+func (r *FlowRepository) SetRandomize(ctx context.Context, id string, settings *randomize.Settings) error {
+	_, err := r.db.Exec(ctx, `UPDATE flow_connections SET randomize = $2 WHERE id = $1`, id, settings)
+	return err
+}
+
+// GetHeadVariables returns the variable declarations of the flow the editor
+// sees (none if the form has no versions)
+func (r *FlowRepository) GetHeadVariables(ctx context.Context, formID string) ([]variables.Variable, error) {
+	var data []byte
+	err := r.db.QueryRow(ctx, `SELECT variables FROM form_versions WHERE id = `+headVersionSQL, formID).Scan(&data)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	vars := []variables.Variable{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &vars); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// SetDraftVariables replaces the variable declarations of the form's draft
+func (r *FlowRepository) SetDraftVariables(ctx context.Context, formID string, vars []variables.Variable) error {
+	if vars == nil {
+		vars = []variables.Variable{}
+	}
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `UPDATE form_versions SET variables = $2 WHERE id = `+draftVersionSQL, formID, data)
+	return err
+}
+
+// GetHeadEndings returns the ending screens of the flow the editor sees
+// (none if the form has no versions)
+func (r *FlowRepository) GetHeadEndings(ctx context.Context, formID string) ([]endings.Ending, error) {
+	var data []byte
+	err := r.db.QueryRow(ctx, `SELECT endings FROM form_versions WHERE id = `+headVersionSQL, formID).Scan(&data)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	ends := []endings.Ending{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &ends); err != nil {
+			return nil, err
+		}
+	}
+	return ends, nil
+}
+
+// SetDraftEndings replaces the ending screens of the form's draft
+func (r *FlowRepository) SetDraftEndings(ctx context.Context, formID string, ends []endings.Ending) error {
+	if ends == nil {
+		ends = []endings.Ending{}
+	}
+	data, err := json.Marshal(ends)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `UPDATE form_versions SET endings = $2 WHERE id = `+draftVersionSQL, formID, data)
 	return err
 }
 
+// CreateEdge adds a jump to the form's draft version
+func (r *FlowRepository) CreateEdge(ctx context.Context, formID, sourceID, targetID string, orderIndex int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO flow_edges (form_id, version_id, source_id, target_id, order_index)
+		VALUES ($1, `+draftVersionSQL+`, $2, $3, $4)
+	`, formID, sourceID, targetID, orderIndex)
+	return err
+}
+
+// Create adds a connection to the form's draft version
 func (r *FlowRepository) Create(ctx context.Context, formID, questionID string, parentID *string, orderIndex, depthLevel int, isTerminal bool) (*FlowConnection, error) {
 	var fc FlowConnection
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO flow_connections (form_id, question_id, parent_id, order_index, depth_level, is_terminal)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO flow_connections (form_id, version_id, question_id, parent_id, order_index, depth_level, is_terminal)
+		VALUES ($1, `+draftVersionSQL+`, $2, $3, $4, $5, $6)
 		RETURNING id, form_id, question_id, parent_id, order_index, depth_level, is_terminal, created_at, updated_at
 	`, formID, questionID, parentID, orderIndex, depthLevel, isTerminal).Scan(
 		&fc.ID, &fc.FormID, &fc.QuestionID, &fc.ParentID, &fc.OrderIndex, &fc.DepthLevel, &fc.IsTerminal, &fc.CreatedAt, &fc.UpdatedAt,
@@ -38,7 +231,7 @@ func (r *FlowRepository) GetByFormID(ctx context.Context, formID string) ([]Flow
 	rows, err := r.db.Query(ctx, `
 		SELECT id, form_id, question_id, parent_id, order_index, depth_level, is_terminal, created_at, updated_at
 		FROM flow_connections
-		WHERE form_id = $1 AND deleted_at IS NULL
+		WHERE form_id = $1 AND version_id = `+headVersionSQL+` AND deleted_at IS NULL
 		ORDER BY depth_level, order_index
 	`, formID)
 	if err != nil {
@@ -58,19 +251,40 @@ func (r *FlowRepository) GetByFormID(ctx context.Context, formID string) ([]Flow
 	return connections, nil
 }
 
+// GetFlowWithQuestions returns the flow the editor works on. Fragment nodes
+// come without the nodes a publish expanded them into, as in a draft.
 func (r *FlowRepository) GetFlowWithQuestions(ctx context.Context, formID string) ([]map[string]interface{}, error) {
+	return r.getFlowWithQuestions(ctx, formID, headVersionSQL, false)
+}
+
+// GetPublishedFlowWithQuestions returns the flow of the published version,
+// fragments expanded
+func (r *FlowRepository) GetPublishedFlowWithQuestions(ctx context.Context, formID string) ([]map[string]interface{}, error) {
+	return r.getFlowWithQuestions(ctx, formID, `(SELECT id FROM form_versions WHERE form_id = $1 AND status = 'published')`, true)
+}
+
+func (r *FlowRepository) getFlowWithQuestions(ctx context.Context, formID, versionSQL string, expanded bool) ([]map[string]interface{}, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT
 			fc.id,
 			fc.parent_id,
 			fc.order_index,
 			q.type,
-			q.question_text
+			q.question_text,
+			fc.fragment_id,
+			fc.fragment_version,
+			COALESCE(fc.variable, ''),
+			fc.assignments,
+			q.metadata->'quiz',
+			COALESCE(fc.ending, ''),
+			fc.randomize,
+			q.metadata->'kind'
 		FROM flow_connections fc
 		JOIN questions q ON fc.question_id = q.id
-		WHERE fc.form_id = $1 AND fc.deleted_at IS NULL
+		WHERE fc.form_id = $1 AND fc.version_id = `+versionSQL+` AND fc.deleted_at IS NULL
+		  AND ($2 OR fc.generated_by IS NULL)
 		ORDER BY fc.depth_level, fc.order_index
-	`, formID)
+	`, formID, expanded)
 	if err != nil {
 		return nil, err
 	}
@@ -78,26 +292,85 @@ func (r *FlowRepository) GetFlowWithQuestions(ctx context.Context, formID string
 
 	var items []map[string]interface{}
 	for rows.Next() {
-		var id, qType, questionText string
-		var parentID *string
+		var id, qType, questionText, variable, ending string
+		var parentID, fragmentID *string
 		var orderIndex int
+		var fragmentVersion *int
+		var assignments, scoring, kind []byte
+		var random *randomize.Settings
 
-		err := rows.Scan(&id, &parentID, &orderIndex, &qType, &questionText)
+		err := rows.Scan(&id, &parentID, &orderIndex, &qType, &questionText, &fragmentID, &fragmentVersion, &variable, &assignments, &scoring, &ending, &random, &kind)
 		if err != nil {
 			continue
 		}
 
+		var set []variables.Assignment
+		if assignments != nil {
+			json.Unmarshal(assignments, &set)
+		}
+
+		var fragment *FragmentRef
+		if fragmentID != nil {
+			fragment = &FragmentRef{ID: *fragmentID, Version: fragmentVersion}
+		}
+
 		items = append(items, map[string]interface{}{
 			"id":           id,
 			"parent_id":    parentID,
 			"type":         qType,
 			"question":     questionText,
 			"order_index":  orderIndex,
+			"fragment":     fragment,
+			"variable":     variable,
+			"set":          set,
+			"quiz":         quiz.Decode(scoring),
+			"ending":       ending,
+			"randomize":    random,
+			"kind":         kinds.Decode(kind),
 		})
 	}
+	rows.Close()
+
+	next, err := r.getEdges(ctx, formID, versionSQL)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		targets := next[item["id"].(string)]
+		if targets == nil {
+			targets = []string{}
+		}
+		item["next"] = targets
+	}
 	return items, nil
 }
 
+// getEdges returns the jumps of a version as source ID -> ordered target IDs
+func (r *FlowRepository) getEdges(ctx context.Context, formID, versionSQL string) (map[string][]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.source_id, e.target_id
+		FROM flow_edges e
+		JOIN flow_connections s ON s.id = e.source_id AND s.deleted_at IS NULL
+		JOIN flow_connections t ON t.id = e.target_id AND t.deleted_at IS NULL
+		WHERE e.form_id = $1 AND e.version_id = `+versionSQL+`
+		ORDER BY e.source_id, e.order_index
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	next := make(map[string][]string)
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, err
+		}
+		next[source] = append(next[source], target)
+	}
+	return next, rows.Err()
+}
+
 func (r *FlowRepository) CreateQuestion(ctx context.Context, userID, qType, text string) (string, error) {
 	var id string
 	err := r.db.QueryRow(ctx, `
@@ -108,20 +381,60 @@ func (r *FlowRepository) CreateQuestion(ctx context.Context, userID, qType, text
 	return id, err
 }
 
+// FindQuestionByText finds a question without quiz data or kind by type
+// and text
 func (r *FlowRepository) FindQuestionByText(ctx context.Context, qType, text string) (string, error) {
 	var id string
 	err := r.db.QueryRow(ctx, `
-		SELECT id FROM questions WHERE type = $1 AND question_text = $2 AND deleted_at IS NULL LIMIT 1
+		SELECT id FROM questions
+		WHERE type = $1 AND question_text = $2 AND deleted_at IS NULL
+		  AND metadata->'quiz' IS NULL AND metadata->'kind' IS NULL
+		LIMIT 1
 	`, qType, text).Scan(&id)
 	return id, err
 }
 
+// FindQuizQuestion finds a question by type, text, quiz data and kind (nil
+// for none). Questions are shared between flows, so each distinct scoring
+// or kind gets its own row.
+func (r *FlowRepository) FindQuizQuestion(ctx context.Context, qType, text string, scoring, kind []byte) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		SELECT id FROM questions
+		WHERE type = $1 AND question_text = $2 AND deleted_at IS NULL
+		  AND metadata->'quiz' IS NOT DISTINCT FROM $3::jsonb
+		  AND metadata->'kind' IS NOT DISTINCT FROM $4::jsonb
+		LIMIT 1
+	`, qType, text, scoring, kind).Scan(&id)
+	return id, err
+}
+
+// CreateQuizQuestion creates a question carrying quiz data or a kind in its
+// metadata; the kind also names its input_type
+func (r *FlowRepository) CreateQuizQuestion(ctx context.Context, userID, qType, text string, scoring, kind []byte) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO questions (type, question_text, created_by, input_type, metadata)
+		VALUES ($1, $2, $3, $5::jsonb->>'type',
+		        jsonb_strip_nulls(jsonb_build_object('quiz', $4::jsonb, 'kind', $5::jsonb)))
+		RETURNING id
+	`, qType, text, userID, scoring, kind).Scan(&id)
+	return id, err
+}
+
+// VerifyFormOwnership checks that the user reaches the form through its
+// workspace or as a collaborator (roles are checked by the handler)
 func (r *FlowRepository) VerifyFormOwnership(ctx context.Context, formID, userID string) error {
 	var exists bool
 	err := r.db.QueryRow(ctx, `
 		SELECT EXISTS(
-			SELECT 1 FROM forms
-			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			SELECT 1 FROM forms f
+			WHERE f.id = $1
+			  AND f.deleted_at IS NULL
+			  AND (
+				f.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2)
+				OR f.id IN (SELECT form_id FROM form_collaborators WHERE user_id = $2)
+			  )
 		)
 	`, formID, userID).Scan(&exists)
 
@@ -136,17 +449,79 @@ func (r *FlowRepository) VerifyFormOwnership(ctx context.Context, formID, userID
 	return nil
 }
 
-// GetFormSlugs retrieves slugs for cache invalidation
-func (r *FlowRepository) GetFormSlugs(ctx context.Context, formID string) (*string, *string, error) {
-	var autoSlug, customSlug *string
-	err := r.db.QueryRow(ctx, `
-		SELECT auto_slug, custom_slug
-		FROM forms
-		WHERE id = $1 AND deleted_at IS NULL
-	`, formID).Scan(&autoSlug, &customSlug)
-	
+// ListDraftNodes returns the connections of the form's draft version. Must
+// run inside InTx after LockDraft.
+func (r *FlowRepository) ListDraftNodes(ctx context.Context, formID string) ([]*flowNode, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT fc.id, fc.origin_id, fc.parent_id, fc.question_id, q.type, q.question_text,
+		       fc.order_index, fc.depth_level, fc.is_terminal, fc.fragment_id, fc.fragment_version,
+		       COALESCE(fc.variable, ''), fc.assignments, q.metadata->'quiz', COALESCE(fc.ending, ''),
+		       fc.randomize, q.metadata->'kind'
+		FROM flow_connections fc
+		JOIN questions q ON q.id = fc.question_id
+		WHERE fc.form_id = $1 AND fc.version_id = `+draftVersionSQL+` AND fc.deleted_at IS NULL
+		ORDER BY fc.depth_level, fc.order_index
+	`, formID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return autoSlug, customSlug, nil
+	defer rows.Close()
+
+	var nodes []*flowNode
+	for rows.Next() {
+		n := &flowNode{}
+		var assignments, scoring, kind []byte
+		if err := rows.Scan(&n.id, &n.originID, &n.parentID, &n.questionID, &n.qType, &n.question,
+			&n.orderIndex, &n.depthLevel, &n.isTerminal, &n.fragmentID, &n.fragmentVersion,
+			&n.variable, &assignments, &scoring, &n.ending, &n.randomize, &kind); err != nil {
+			return nil, err
+		}
+		n.quiz = quiz.Decode(scoring)
+		n.kind = kinds.Decode(kind)
+		if assignments != nil {
+			if err := json.Unmarshal(assignments, &n.set); err != nil {
+				return nil, err
+			}
+		}
+		n.saved = n.nodePlacement
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// ListDraftEdges returns the jumps of the form's draft version
+func (r *FlowRepository) ListDraftEdges(ctx context.Context, formID string) (map[string][]string, error) {
+	return r.getEdges(ctx, formID, draftVersionSQL)
+}
+
+// InsertNode adds a connection with a known ID to the form's draft version
+func (r *FlowRepository) InsertNode(ctx context.Context, formID string, n *flowNode) error {
+	assignments, err := versions.EncodeAssignments(n.set)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO flow_connections (id, form_id, version_id, question_id, parent_id, order_index, depth_level, is_terminal,
+		                              fragment_id, fragment_version, variable, assignments, ending, randomize)
+		VALUES ($2, $1, `+draftVersionSQL+`, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13)
+	`, formID, n.id, n.questionID, n.parentID, n.orderIndex, n.depthLevel, n.isTerminal,
+		n.fragmentID, n.fragmentVersion, n.variable, assignments, n.ending, n.randomize)
+	return err
+}
+
+// UpdateNode writes the position and question of a draft connection
+func (r *FlowRepository) UpdateNode(ctx context.Context, n *flowNode) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE flow_connections
+		SET question_id = $2, parent_id = $3, order_index = $4, depth_level = $5, is_terminal = $6,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`, n.id, n.questionID, n.parentID, n.orderIndex, n.depthLevel, n.isTerminal)
+	return err
+}
+
+// DeleteNodes soft deletes draft connections
+func (r *FlowRepository) DeleteNodes(ctx context.Context, ids []string) error {
+	_, err := r.db.Exec(ctx, `UPDATE flow_connections SET deleted_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL`, ids)
+	return err
 }
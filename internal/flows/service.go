@@ -2,96 +2,541 @@ package flows
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 
-	"smart-forms/internal/cache"
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+	"smart-forms/internal/versions"
 )
 
 type FlowService struct {
-	repo  *FlowRepository
-	cache *cache.Cache
+	repo      *FlowRepository
+	versions  *versions.VersionsService
+	fragments FragmentSource
 }
 
-func NewFlowService(repo *FlowRepository, cacheClient *cache.Cache) *FlowService {
+func NewFlowService(repo *FlowRepository, versionService *versions.VersionsService) *FlowService {
 	return &FlowService{
-		repo:  repo,
-		cache: cacheClient,
+		repo:     repo,
+		versions: versionService,
 	}
 }
 
-func (s *FlowService) UpdateFlow(ctx context.Context, userID, formID string, req FlowRequest) (map[string]string, error) {
+// UpdateFlow replaces the draft's flow in one transaction and returns the
+// block ID mapping and the flow's new ETag. ifMatch must match the ETag the
+// editor read (ErrNoIfMatch without one, ErrDraftChanged otherwise).
+func (s *FlowService) UpdateFlow(ctx context.Context, userID, formID, ifMatch string, req FlowRequest) (map[string]string, string, error) {
+	if ifMatch == "" {
+		return nil, "", ErrNoIfMatch
+	}
 	if len(req.Blocks) == 0 {
-		return nil, ErrInvalidInput
+		return nil, "", ErrInvalidInput
+	}
+
+	// Reject bad input before touching the database
+	if err := s.prepareEmbeds(ctx, userID, req.Blocks); err != nil {
+		return nil, "", err
+	}
+	if err := validateBlocks(req.Blocks); err != nil {
+		return nil, "", err
+	}
+	if err := validateGraph(req.Blocks); err != nil {
+		return nil, "", err
 	}
 
 	// Verify user owns the form
 	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Soft delete existing flow
-	if err := s.repo.DeleteByFormID(ctx, formID); err != nil {
-		return nil, err
+	// Edits never touch the published version: they go to the draft, which
+	// goes live when published
+	draftETag, err := s.versions.EnsureDraft(ctx, formID, userID, ifMatch)
+	if err != nil {
+		switch err {
+		case versions.ErrFormNotFound:
+			return nil, "", ErrFormNotFound
+		case versions.ErrPrecondition:
+			return nil, "", ErrDraftChanged
+		}
+		return nil, "", err
 	}
 
-	// Process blocks recursively and collect ID mapping
 	mapping := make(map[string]string)
-	for i, block := range req.Blocks {
-		if err := s.processBlock(ctx, userID, formID, block, nil, i, 0, mapping); err != nil {
+	var etag string
+
+	err = s.repo.InTx(ctx, func(tx *FlowRepository) error {
+		// Another save or a publish may have happened since EnsureDraft
+		current, err := tx.LockDraft(ctx, formID)
+		if err != nil {
+			return err
+		}
+		if ifMatch != "" && current != draftETag {
+			return ErrDraftChanged
+		}
+
+		// Soft delete the draft's flow
+		if err := tx.DeleteByFormID(ctx, formID); err != nil {
+			return err
+		}
+
+		if req.Variables != nil {
+			if err := tx.SetDraftVariables(ctx, formID, req.Variables); err != nil {
+				return err
+			}
+		}
+		if req.Endings != nil {
+			if err := tx.SetDraftEndings(ctx, formID, req.Endings); err != nil {
+				return err
+			}
+		}
+
+		// Process blocks recursively and collect ID mapping
+		jumps := make(map[string][]string)
+		for i, block := range req.Blocks {
+			if err := s.processBlock(ctx, tx, userID, formID, block, nil, i, 0, mapping, jumps); err != nil {
+				return err
+			}
+		}
+
+		// Jumps reference block IDs, known once every block exists
+		for sourceID, targets := range jumps {
+			for i, target := range targets {
+				if err := tx.CreateEdge(ctx, formID, sourceID, mapping[target], i); err != nil {
+					return err
+				}
+			}
+		}
+
+		etag, err = tx.BumpRevision(ctx, formID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return mapping, etag, nil
+}
+
+// ApplyOperations edits the draft's flow in place, in one transaction.
+// Connections not touched by an operation keep their IDs. Returns the ID
+// mapping (added block IDs and translated published IDs -> draft IDs) and
+// the flow's new ETag. ifMatch is required, as for UpdateFlow.
+func (s *FlowService) ApplyOperations(ctx context.Context, userID, formID, ifMatch string, req OperationsRequest) (map[string]string, string, error) {
+	if ifMatch == "" {
+		return nil, "", ErrNoIfMatch
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxOperations {
+		return nil, "", ErrInvalidInput
+	}
+
+	// Verify user owns the form
+	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
+		return nil, "", err
+	}
+
+	for _, op := range req.Operations {
+		if op.Op != "add" || op.Block == nil {
+			continue
+		}
+		blocks := []Block{*op.Block}
+		if err := s.prepareEmbeds(ctx, userID, blocks); err != nil {
+			return nil, "", err
+		}
+		*op.Block = blocks[0]
+	}
+
+	draftETag, err := s.versions.EnsureDraft(ctx, formID, userID, ifMatch)
+	if err != nil {
+		switch err {
+		case versions.ErrFormNotFound:
+			return nil, "", ErrFormNotFound
+		case versions.ErrPrecondition:
+			return nil, "", ErrDraftChanged
+		}
+		return nil, "", err
+	}
+
+	mapping := make(map[string]string)
+	var etag string
+
+	err = s.repo.InTx(ctx, func(tx *FlowRepository) error {
+		current, err := tx.LockDraft(ctx, formID)
+		if err != nil {
+			return err
+		}
+		if ifMatch != "" && current != draftETag {
+			return ErrDraftChanged
+		}
+
+		nodes, err := tx.ListDraftNodes(ctx, formID)
+		if err != nil {
+			return err
+		}
+		next, err := tx.ListDraftEdges(ctx, formID)
+		if err != nil {
+			return err
+		}
+
+		// Each operation sees the tree left by the previous ones; any
+		// rejected operation rolls back the whole request
+		tree := newFlowTree(nodes, next)
+		for i, op := range req.Operations {
+			if err := tree.apply(i, op, mapping); err != nil {
+				return err
+			}
+		}
+
+		if err := tree.save(ctx, tx, userID, formID); err != nil {
+			return err
+		}
+
+		etag, err = tx.BumpRevision(ctx, formID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return mapping, etag, nil
+}
+
+// ValidateFlow lints the given blocks, or the flow the editor sees when no
+// blocks are given, with embedded fragments expanded. Given variables and
+// endings replace the flow's. Publishing runs the same checks on the draft.
+func (s *FlowService) ValidateFlow(ctx context.Context, userID, formID string, req FlowRequest) (*versions.LintReport, error) {
+	// Verify user owns the form
+	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
+		return nil, err
+	}
+
+	blocks := req.Blocks
+	if len(blocks) > 0 {
+		if err := s.prepareEmbeds(ctx, userID, blocks); err != nil {
+			return nil, err
+		}
+	} else {
+		items, err := s.repo.GetFlowWithQuestions(ctx, formID)
+		if err != nil {
 			return nil, err
 		}
+		blocks = buildBlocks(items, nil)
+	}
+
+	vars := req.Variables
+	if vars == nil {
+		var err error
+		if vars, err = s.repo.GetHeadVariables(ctx, formID); err != nil {
+			return nil, err
+		}
+	}
+	ends := req.Endings
+	if ends == nil {
+		var err error
+		if ends, err = s.repo.GetHeadEndings(ctx, formID); err != nil {
+			return nil, err
+		}
+	}
+
+	expanded, err := s.expandFragments(ctx, blocks)
+	if err != nil {
+		return nil, err
+	}
+	return lintBlocks(expanded, vars, ends), nil
+}
+
+// ExportFlow renders the flow the editor sees (draft, else published) in one
+// of the export formats and returns it with the flow's ETag
+func (s *FlowService) ExportFlow(ctx context.Context, userID, formID, format string) ([]byte, string, error) {
+	// Verify user owns the form
+	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
+		return nil, "", err
+	}
+
+	etag, err := s.versions.HeadETag(ctx, formID)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// Invalidate cache after flow structure changes
-	// Delete by form ID
-	s.cache.Delete(cache.FormIDKey(formID))
+	items, err := s.repo.GetFlowWithQuestions(ctx, formID)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// Delete by slugs (if form was published)
-	autoSlug, customSlug, _ := s.repo.GetFormSlugs(ctx, formID)
-	if autoSlug != nil && *autoSlug != "" {
-		s.cache.Delete(cache.FormSlugKey(*autoSlug))
+	vars, err := s.repo.GetHeadVariables(ctx, formID)
+	if err != nil {
+		return nil, "", err
 	}
-	if customSlug != nil && *customSlug != "" {
-		s.cache.Delete(cache.FormSlugKey(*customSlug))
+
+	ends, err := s.repo.GetHeadEndings(ctx, formID)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return mapping, nil
+	doc := &FlowDocument{Version: documentVersion, Variables: vars, Endings: ends, Blocks: buildBlocks(items, nil)}
+	if doc.Blocks == nil {
+		doc.Blocks = []Block{}
+	}
+
+	data, err := renderDocument(doc, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, etag, nil
 }
 
-func (s *FlowService) processBlock(ctx context.Context, userID, formID string, block Block, parentID *string, orderIndex, depthLevel int, mapping map[string]string) error {
-	// Validate
-	block.Question = strings.TrimSpace(block.Question)
-	if block.Question == "" || block.Type == "" {
-		return ErrInvalidInput
+// ImportFlow replaces the draft's flow with a YAML or JSON flow document.
+// The document must pass the save checks and the linter (a LintError carries
+// the report); with dryRun nothing is saved. Returns the block ID mapping,
+// the lint report and the flow's new ETag. ifMatch is required unless
+// dryRun, as for UpdateFlow.
+func (s *FlowService) ImportFlow(ctx context.Context, userID, formID, ifMatch, format string, data []byte, dryRun bool) (map[string]string, *versions.LintReport, string, error) {
+	if ifMatch == "" && !dryRun {
+		return nil, nil, "", ErrNoIfMatch
 	}
 
-	// Find or create question
-	questionID, err := s.repo.FindQuestionByText(ctx, block.Type, block.Question)
+	// Verify user owns the form
+	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
+		return nil, nil, "", err
+	}
+
+	doc, err := parseDocument(data, format)
 	if err != nil {
-		// Question doesn't exist, create it
-		questionID, err = s.repo.CreateQuestion(ctx, userID, block.Type, block.Question)
-		if err != nil {
+		return nil, nil, "", err
+	}
+
+	if err := s.prepareEmbeds(ctx, userID, doc.Blocks); err != nil {
+		return nil, nil, "", err
+	}
+	if err := validateBlocks(doc.Blocks); err != nil {
+		return nil, nil, "", err
+	}
+	if err := validateGraph(doc.Blocks); err != nil {
+		return nil, nil, "", err
+	}
+
+	expanded, err := s.expandFragments(ctx, doc.Blocks)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	// The document replaces the flow's declarations too
+	if doc.Variables == nil {
+		doc.Variables = []variables.Variable{}
+	}
+	if doc.Endings == nil {
+		doc.Endings = []endings.Ending{}
+	}
+	report := lintBlocks(expanded, doc.Variables, doc.Endings)
+	if !report.Valid {
+		return nil, nil, "", &versions.LintError{Report: report}
+	}
+	if dryRun {
+		return nil, report, "", nil
+	}
+
+	mapping, etag, err := s.UpdateFlow(ctx, userID, formID, ifMatch, FlowRequest{Blocks: doc.Blocks, Variables: doc.Variables, Endings: doc.Endings})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return mapping, report, etag, nil
+}
+
+// Simulate runs a sequence of answers through the flow the editor sees
+// (draft, else published) and reports the path taken and where it ends
+func (s *FlowService) Simulate(ctx context.Context, userID, formID string, answers []string) (*SimulationResult, error) {
+	if len(answers) > maxSimulatedAnswers {
+		return nil, ErrInvalidInput
+	}
+
+	blocks, err := s.simulationBlocks(ctx, userID, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := s.repo.GetHeadVariables(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	ends, err := s.repo.GetHeadEndings(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := newSimulator(blocks)
+	result := sim.run(answers)
+	if result.Warnings, err = s.simulationWarnings(ctx, formID, sim); err != nil {
+		return nil, err
+	}
+	result.Lint = lintBlocks(blocks, vars, ends)
+	return result, nil
+}
+
+// SimulatePaths enumerates up to maxPaths paths through the flow the editor
+// sees, to estimate the form's length
+func (s *FlowService) SimulatePaths(ctx context.Context, userID, formID string, maxPaths int) (*PathsResult, error) {
+	if maxPaths <= 0 {
+		maxPaths = defaultMaxPaths
+	}
+	if maxPaths > maxPathsCap {
+		return nil, ErrInvalidInput
+	}
+
+	blocks, err := s.simulationBlocks(ctx, userID, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := s.repo.GetHeadVariables(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	ends, err := s.repo.GetHeadEndings(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := newSimulator(blocks)
+	result := sim.paths(maxPaths)
+	if result.Warnings, err = s.simulationWarnings(ctx, formID, sim); err != nil {
+		return nil, err
+	}
+	result.Lint = lintBlocks(blocks, vars, ends)
+	return result, nil
+}
+
+// simulationWarnings lists what the simulation of the flow doesn't
+// reproduce, including an A/B test serving respondents other versions
+func (s *FlowService) simulationWarnings(ctx context.Context, formID string, sim *simulator) ([]SimulationWarning, error) {
+	warnings := sim.warnings()
+
+	variants, err := s.versions.ListVariants(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) > 0 {
+		warnings = append(warnings, SimulationWarning{
+			Code:    WarnVariants,
+			Message: "The form runs an A/B test: respondents may get a variant's version, the simulation covers the flow the editor sees only",
+		})
+	}
+	return warnings, nil
+}
+
+func (s *FlowService) simulationBlocks(ctx context.Context, userID, formID string) ([]Block, error) {
+	// Verify user owns the form
+	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.GetFlowWithQuestions(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Respondents walk through embedded fragments as publishing expands them
+	return s.expandFragments(ctx, buildBlocks(items, nil))
+}
+
+// validateBlocks checks every block of a flow before anything is written
+func validateBlocks(blocks []Block) error {
+	for _, block := range blocks {
+		if strings.TrimSpace(block.Question) == "" || block.Type == "" {
+			return ErrInvalidInput
+		}
+		if block.Kind != nil {
+			if err := block.Kind.Validate(); err != nil {
+				return err
+			}
+		}
+		if err := validateBlocks(block.Children); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Determine if terminal
-	isTerminal := len(block.Children) == 0
+func findOrCreateQuestion(ctx context.Context, repo *FlowRepository, userID, qType, text string, scoring *quiz.Scoring, kind *kinds.Spec) (string, error) {
+	if !scoring.IsZero() || kind != nil {
+		var data, spec []byte
+		var err error
+		if !scoring.IsZero() {
+			if data, err = json.Marshal(scoring); err != nil {
+				return "", err
+			}
+		}
+		if kind != nil {
+			if spec, err = json.Marshal(kind); err != nil {
+				return "", err
+			}
+		}
+		questionID, err := repo.FindQuizQuestion(ctx, qType, text, data, spec)
+		if err != nil {
+			return repo.CreateQuizQuestion(ctx, userID, qType, text, data, spec)
+		}
+		return questionID, nil
+	}
+
+	questionID, err := repo.FindQuestionByText(ctx, qType, text)
+	if err != nil {
+		// Question doesn't exist, create it
+		return repo.CreateQuestion(ctx, userID, qType, text)
+	}
+	return questionID, nil
+}
+
+func (s *FlowService) processBlock(ctx context.Context, repo *FlowRepository, userID, formID string, block Block, parentID *string, orderIndex, depthLevel int, mapping map[string]string, jumps map[string][]string) error {
+	block.Question = strings.TrimSpace(block.Question)
+
+	questionID, err := findOrCreateQuestion(ctx, repo, userID, block.Type, block.Question, block.Quiz, block.Kind)
+	if err != nil {
+		return err
+	}
+
+	// Determine if terminal (no children and no jumps)
+	isTerminal := len(block.Children) == 0 && len(block.Next) == 0
 
 	// Create flow connection
-	connection, err := s.repo.Create(ctx, formID, questionID, parentID, orderIndex, depthLevel, isTerminal)
+	connection, err := repo.Create(ctx, formID, questionID, parentID, orderIndex, depthLevel, isTerminal)
 	if err != nil {
 		return err
 	}
 
+	if block.Fragment != nil {
+		if err := repo.SetFragment(ctx, connection.ID, block.Fragment); err != nil {
+			return err
+		}
+	}
+	if block.Variable != "" || len(block.Set) > 0 {
+		if err := repo.SetLogic(ctx, connection.ID, strings.TrimSpace(block.Variable), block.Set); err != nil {
+			return err
+		}
+	}
+	if ending := strings.TrimSpace(block.Ending); ending != "" {
+		if err := repo.SetEnding(ctx, connection.ID, ending); err != nil {
+			return err
+		}
+	}
+	if block.Randomize != nil {
+		if err := repo.SetRandomize(ctx, connection.ID, block.Randomize); err != nil {
+			return err
+		}
+	}
+
 	// Store mapping: frontend block ID -> database UUID
 	if block.ID != "" {
 		mapping[block.ID] = connection.ID
 	}
+	if len(block.Next) > 0 {
+		jumps[connection.ID] = block.Next
+	}
 
 	// Process children recursively
 	for i, child := range block.Children {
-		if err := s.processBlock(ctx, userID, formID, child, &connection.ID, i, depthLevel+1, mapping); err != nil {
+		if err := s.processBlock(ctx, repo, userID, formID, child, &connection.ID, i, depthLevel+1, mapping, jumps); err != nil {
 			return err
 		}
 	}
@@ -108,23 +553,42 @@ func (s *FlowService) GetFlow(ctx context.Context, userID, formID string) ([]Flo
 	return s.repo.GetByFormID(ctx, formID)
 }
 
-func (s *FlowService) GetFlowTree(ctx context.Context, userID, formID string) (map[string]interface{}, error) {
+// GetFlowTree returns the flow the editor works on and its ETag
+func (s *FlowService) GetFlowTree(ctx context.Context, userID, formID string) (map[string]interface{}, string, error) {
 	// Verify user owns the form
 	if err := s.repo.VerifyFormOwnership(ctx, formID, userID); err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	// Read the ETag first: a save in between makes it stale, never too new
+	etag, err := s.versions.HeadETag(ctx, formID)
+	if err != nil {
+		return nil, "", err
 	}
 
 	items, err := s.repo.GetFlowWithQuestions(ctx, formID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	vars, err := s.repo.GetHeadVariables(ctx, formID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ends, err := s.repo.GetHeadEndings(ctx, formID)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Build tree structure
 	blocks := s.buildTree(items, nil)
 
 	return map[string]interface{}{
-		"blocks": blocks,
-	}, nil
+		"blocks":    blocks,
+		"variables": vars,
+		"endings":   ends,
+	}, etag, nil
 }
 
 func (s *FlowService) buildTree(items []map[string]interface{}, parentID *string) []map[string]interface{} {
@@ -143,6 +607,28 @@ func (s *FlowService) buildTree(items []map[string]interface{}, parentID *string
 				"type":     item["type"],
 				"question": item["question"],
 				"children": s.buildTree(items, &id),
+				"next":     item["next"],
+			}
+			if fragment := item["fragment"].(*FragmentRef); fragment != nil {
+				block["fragment"] = fragment
+			}
+			if variable := item["variable"].(string); variable != "" {
+				block["variable"] = variable
+			}
+			if set := item["set"].([]variables.Assignment); len(set) > 0 {
+				block["set"] = set
+			}
+			if scoring := item["quiz"].(*quiz.Scoring); scoring != nil {
+				block["quiz"] = scoring
+			}
+			if ending := item["ending"].(string); ending != "" {
+				block["ending"] = ending
+			}
+			if random := item["randomize"].(*randomize.Settings); random != nil {
+				block["randomize"] = random
+			}
+			if kind := item["kind"].(*kinds.Spec); kind != nil {
+				block["kind"] = kind
 			}
 
 			result = append(result, block)
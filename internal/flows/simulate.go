@@ -0,0 +1,318 @@
+package flows
+
+import (
+	"strings"
+
+	"smart-forms/internal/versions"
+)
+
+// Simulation modes
+const (
+	SimulateAnswers = "answers"
+	SimulatePaths   = "paths"
+)
+
+const (
+	maxSimulatedAnswers = 500
+	defaultMaxPaths     = 100
+	maxPathsCap         = 1000
+)
+
+// Simulation outcomes
+const (
+	SimulationCompleted     = "completed"      // reached a block that ends the flow
+	SimulationNeedsAnswer   = "needs_answer"   // answers ran out at a question
+	SimulationInvalidAnswer = "invalid_answer" // an answer matched none of the options
+)
+
+type SimulateRequest struct {
+	Mode     string   `json:"mode"`      // answers (default) | paths
+	Answers  []string `json:"answers"`   // answers mode: one per question, in order
+	MaxPaths int      `json:"max_paths"` // paths mode: cap (default 100, max 1000)
+}
+
+// SimulatedStep is one block on a simulated path
+type SimulatedStep struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+type SimulationIssue struct {
+	Step    int    `json:"step"` // index in path
+	BlockID string `json:"block_id"`
+	Message string `json:"message"`
+}
+
+// Simulation warning codes: flow features the simulation doesn't reproduce
+const (
+	WarnScoreRouting = "score_routing" // routing question answered by quiz score
+	WarnRandomized   = "randomized"    // children shuffled or sampled per respondent
+	WarnVariants     = "variants"      // A/B test serves other versions
+)
+
+// SimulationWarning flags where respondents may not go as simulated
+type SimulationWarning struct {
+	Code    string `json:"code"`
+	BlockID string `json:"block_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// SimulationResult is where a sequence of answers leads
+type SimulationResult struct {
+	Status        string               `json:"status"`
+	Path          []SimulatedStep      `json:"path"`
+	End           *SimulatedStep       `json:"end,omitempty"`
+	Errors        []SimulationIssue    `json:"errors"`
+	UnusedAnswers int                  `json:"unused_answers"`
+	Warnings      []SimulationWarning  `json:"warnings"`
+	Lint          *versions.LintReport `json:"lint"`
+}
+
+// SimulatedPath is one possible path through the flow
+type SimulatedPath struct {
+	Blocks    []string `json:"blocks"`    // block IDs in order
+	Questions int      `json:"questions"` // answers the path asks for
+}
+
+// PathsResult enumerates the paths through a flow to estimate its length
+type PathsResult struct {
+	Paths        []SimulatedPath      `json:"paths"`
+	Total        int                  `json:"total"`
+	Truncated    bool                 `json:"truncated"`
+	MinQuestions int                  `json:"min_questions"`
+	MaxQuestions int                  `json:"max_questions"`
+	AvgQuestions float64              `json:"avg_questions"`
+	Warnings     []SimulationWarning  `json:"warnings"`
+	Lint         *versions.LintReport `json:"lint"`
+}
+
+// simulator walks a flow the way the public form does: start at the first
+// block; a question with options takes the chosen option, any other question
+// takes a free answer; everything else continues with the first child, else
+// the first jump target; a block with neither ends the flow
+type simulator struct {
+	blocks []Block
+	byID   map[string]*Block
+	size   int
+}
+
+func newSimulator(blocks []Block) *simulator {
+	s := &simulator{blocks: blocks, byID: make(map[string]*Block)}
+	var index func(blocks []Block)
+	index = func(blocks []Block) {
+		for i := range blocks {
+			s.byID[blocks[i].ID] = &blocks[i]
+			s.size++
+			index(blocks[i].Children)
+		}
+	}
+	index(blocks)
+	return s
+}
+
+// warnings lists the blocks respondents may not walk as simulated: routing
+// questions are answered by score on submission, and randomized blocks show
+// their children shuffled or sampled. The simulation takes routing answers
+// as given and follows the editor order with every child.
+func (s *simulator) warnings() []SimulationWarning {
+	result := []SimulationWarning{}
+	var walk func(blocks []Block)
+	walk = func(blocks []Block) {
+		for i := range blocks {
+			b := &blocks[i]
+			if b.Type == versions.TypeQuestion && b.Quiz != nil && b.Quiz.Route {
+				result = append(result, SimulationWarning{
+					Code:    WarnScoreRouting,
+					BlockID: b.ID,
+					Message: "Respondents are routed here by quiz score on submission; the simulation takes the answer given (every band for paths)",
+				})
+			}
+			if !b.Randomize.IsZero() {
+				result = append(result, SimulationWarning{
+					Code:    WarnRandomized,
+					BlockID: b.ID,
+					Message: "Children are shuffled or sampled per respondent; the simulation follows the editor order with every child",
+				})
+			}
+			walk(b.Children)
+		}
+	}
+	walk(s.blocks)
+	return result
+}
+
+// options returns the option children of a question
+func options(b *Block) []*Block {
+	if b.Type != versions.TypeQuestion {
+		return nil
+	}
+	var result []*Block
+	for i := range b.Children {
+		if b.Children[i].Type == versions.TypeOption {
+			result = append(result, &b.Children[i])
+		}
+	}
+	return result
+}
+
+// continuation is the block after b when nothing is chosen
+func (s *simulator) continuation(b *Block) *Block {
+	if len(b.Children) > 0 {
+		return &b.Children[0]
+	}
+	for _, id := range b.Next {
+		if next, ok := s.byID[id]; ok {
+			return next
+		}
+	}
+	return nil
+}
+
+// choose matches an answer to an option by ID or by text (case-insensitive)
+func choose(opts []*Block, answer string) *Block {
+	answer = strings.TrimSpace(answer)
+	for _, o := range opts {
+		if o.ID == answer {
+			return o
+		}
+	}
+	for _, o := range opts {
+		if strings.EqualFold(strings.TrimSpace(o.Question), answer) {
+			return o
+		}
+	}
+	return nil
+}
+
+func step(b *Block) SimulatedStep {
+	return SimulatedStep{ID: b.ID, Type: b.Type, Question: b.Question}
+}
+
+// run follows the answers through the flow
+func (s *simulator) run(answers []string) *SimulationResult {
+	result := &SimulationResult{Path: []SimulatedStep{}, Errors: []SimulationIssue{}}
+	if len(s.blocks) == 0 {
+		result.Status = SimulationCompleted
+		return result
+	}
+
+	current := &s.blocks[0]
+	used := 0
+	// A valid flow is acyclic; the cap only guards stored flows that aren't
+	for current != nil && len(result.Path) <= s.size {
+		st := step(current)
+		index := len(result.Path)
+
+		if current.Type == versions.TypeQuestion {
+			if used == len(answers) {
+				result.Path = append(result.Path, st)
+				result.Status = SimulationNeedsAnswer
+				result.End = &result.Path[index]
+				result.UnusedAnswers = 0
+				return result
+			}
+			st.Answer = answers[used]
+			used++
+
+			if opts := options(current); len(opts) > 0 {
+				chosen := choose(opts, st.Answer)
+				result.Path = append(result.Path, st)
+				if chosen == nil {
+					result.Status = SimulationInvalidAnswer
+					result.End = &result.Path[index]
+					result.Errors = append(result.Errors, SimulationIssue{
+						Step:    index,
+						BlockID: current.ID,
+						Message: "Answer \"" + st.Answer + "\" matches none of the options",
+					})
+					result.UnusedAnswers = len(answers) - used
+					return result
+				}
+				current = chosen
+				continue
+			}
+		}
+
+		result.Path = append(result.Path, st)
+		next := s.continuation(current)
+		if next == nil {
+			result.Status = SimulationCompleted
+			result.End = &result.Path[index]
+		}
+		current = next
+	}
+
+	if result.Status == "" {
+		result.Status = SimulationInvalidAnswer
+		result.Errors = append(result.Errors, SimulationIssue{
+			Step:    len(result.Path) - 1,
+			Message: "Flow contains a cycle",
+		})
+	}
+	result.UnusedAnswers = len(answers) - used
+	return result
+}
+
+// paths enumerates paths depth-first, every option of a question being a
+// branch, until maxPaths are found
+func (s *simulator) paths(maxPaths int) *PathsResult {
+	result := &PathsResult{Paths: []SimulatedPath{}}
+	if len(s.blocks) == 0 {
+		return result
+	}
+
+	var walk func(b *Block, path []string, questions int)
+	walk = func(b *Block, path []string, questions int) {
+		if result.Truncated {
+			return
+		}
+		// Guard against cycles in flows saved before validation existed
+		if len(path) > s.size {
+			return
+		}
+		path = append(path, b.ID)
+		if b.Type == versions.TypeQuestion {
+			questions++
+		}
+
+		if opts := options(b); len(opts) > 0 {
+			for _, o := range opts {
+				walk(o, path, questions)
+			}
+			return
+		}
+
+		if next := s.continuation(b); next != nil {
+			walk(next, path, questions)
+			return
+		}
+
+		if len(result.Paths) == maxPaths {
+			result.Truncated = true
+			return
+		}
+		result.Paths = append(result.Paths, SimulatedPath{
+			Blocks:    append([]string(nil), path...),
+			Questions: questions,
+		})
+	}
+	walk(&s.blocks[0], nil, 0)
+
+	result.Total = len(result.Paths)
+	sum := 0
+	for i, p := range result.Paths {
+		if i == 0 || p.Questions < result.MinQuestions {
+			result.MinQuestions = p.Questions
+		}
+		if p.Questions > result.MaxQuestions {
+			result.MaxQuestions = p.Questions
+		}
+		sum += p.Questions
+	}
+	if result.Total > 0 {
+		result.AvgQuestions = float64(sum) / float64(result.Total)
+	}
+	return result
+}
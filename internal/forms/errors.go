@@ -2,4 +2,7 @@ package forms
 
 import "errors"
 
-var ErrNotFound = errors.New("form not found")
+var (
+	ErrNotFound          = errors.New("form not found")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+)
@@ -5,14 +5,18 @@ import (
 	"reflect"
 	"strconv"
 
+	"smart-forms/internal/collaborators"
+	"smart-forms/internal/quiz"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 // FlowRepository interface for flow operations
 type FlowRepository interface {
-	GetFlowWithQuestions(ctx context.Context, formID string) ([]map[string]interface{}, error)
+	GetPublishedFlowWithQuestions(ctx context.Context, formID string) ([]map[string]interface{}, error)
 	DeleteByFormID(ctx context.Context, formID string) error
 	Create(ctx context.Context, formID, questionID string, parentID *string, orderIndex, depthLevel int, isTerminal bool) (interface{}, error)
+	CreateEdge(ctx context.Context, formID, sourceID, targetID string, orderIndex int) error
 	CreateQuestion(ctx context.Context, userID, qType, text string) (string, error)
 	FindQuestionByText(ctx context.Context, qType, text string) (string, error)
 }
@@ -20,12 +24,16 @@ type FlowRepository interface {
 // FormsHandler handles HTTP requests
 type FormsHandler struct {
 	service  *FormsService
+	access   *collaborators.AccessChecker
 	flowRepo FlowRepository
 }
 
 // NewFormsHandler creates handler
-func NewFormsHandler(service *FormsService) *FormsHandler {
-	return &FormsHandler{service: service}
+func NewFormsHandler(service *FormsService, access *collaborators.AccessChecker) *FormsHandler {
+	return &FormsHandler{
+		service: service,
+		access:  access,
+	}
 }
 
 // SetFlowRepo sets the flow repository
@@ -43,6 +51,7 @@ func (h *FormsHandler) Create(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
 	var req struct {
+		WorkspaceID string `json:"workspace_id"` // optional, defaults to the personal workspace
 		Title       string `json:"title"`
 		Description string `json:"description"`
 	}
@@ -54,6 +63,7 @@ func (h *FormsHandler) Create(c *fiber.Ctx) error {
 	form, err := h.service.Create(
 		c.Context(),
 		userID,
+		req.WorkspaceID,
 		req.Title,
 		req.Description,
 	)
@@ -77,10 +87,12 @@ func (h *FormsHandler) List(c *fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 	search := c.Query("search", "")
+	workspaceID := c.Query("workspace_id", "")
 
 	items, total, err := h.service.List(
 		c.Context(),
 		userID,
+		workspaceID,
 		search,
 		limit,
 		offset,
@@ -108,6 +120,10 @@ func (h *FormsHandler) GetByID(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	form, err := h.service.GetByID(
 		c.Context(),
 		userID,
@@ -131,6 +147,10 @@ func (h *FormsHandler) Update(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	var req struct {
 		Title       string `json:"title"`
 		Description string `json:"description"`
@@ -157,6 +177,32 @@ func (h *FormsHandler) Update(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+/*
+========================
+ SET QUIZ SETTINGS
+PUT /forms/:id/quiz
+========================
+*/
+func (h *FormsHandler) SetQuiz(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	var req quiz.Settings
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.service.SetQuiz(c.Context(), userID, formID, req); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(req)
+}
+
 /*
 ========================
  SOFT DELETE FORM
@@ -167,6 +213,10 @@ func (h *FormsHandler) SoftDelete(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ManageForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	err := h.service.SoftDelete(
 		c.Context(),
 		userID,
@@ -180,13 +230,49 @@ func (h *FormsHandler) SoftDelete(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+/*
+========================
+ MOVE FORM TO WORKSPACE
+PATCH /forms/:id/workspace
+========================
+*/
+func (h *FormsHandler) MoveToWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ManageForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	var req struct {
+		WorkspaceID string `json:"workspace_id"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	form, err := h.service.MoveToWorkspace(
+		c.Context(),
+		userID,
+		formID,
+		req.WorkspaceID,
+	)
+
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(form)
+}
+
 /*
 ========================
  TEMPLATE OPERATIONS
 ========================
 */
 
-// ToggleTemplate toggles is_template flag (super admin only)
+// ToggleTemplate toggles is_template flag (templates:moderate)
 // PATCH /admin/forms/:id/template
 func (h *FormsHandler) ToggleTemplate(c *fiber.Ctx) error {
 	formID := c.Params("id")
@@ -244,11 +330,12 @@ func (h *FormsHandler) ListTemplates(c *fiber.Ctx) error {
 	})
 }
 
-// CloneTemplate clones a template to user's account (authenticated)
-// POST /templates/:id/clone
+// CloneTemplate clones a template into a workspace (personal by default)
+// POST /templates/:id/clone?workspace_id=
 func (h *FormsHandler) CloneTemplate(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	templateID := c.Params("id")
+	workspaceID := c.Query("workspace_id", "")
 
 	// 1. Get template metadata
 	template, err := h.service.GetTemplateData(c.Context(), templateID)
@@ -256,15 +343,15 @@ func (h *FormsHandler) CloneTemplate(c *fiber.Ctx) error {
 		return mapServiceError(err)
 	}
 
-	// 2. Get template flow structure
-	flowItems, err := h.flowRepo.GetFlowWithQuestions(c.Context(), templateID)
+	// 2. Get template flow structure (published version, not the draft)
+	flowItems, err := h.flowRepo.GetPublishedFlowWithQuestions(c.Context(), templateID)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 
 	// 3. Create new form with template data
 	newTitle := "Copy of " + template.Title
-	clonedForm, err := h.service.Create(c.Context(), userID, newTitle, template.Description)
+	clonedForm, err := h.service.Create(c.Context(), userID, workspaceID, newTitle, template.Description)
 	if err != nil {
 		return mapServiceError(err)
 	}
@@ -275,11 +362,22 @@ func (h *FormsHandler) CloneTemplate(c *fiber.Ctx) error {
 		blocks := h.buildTree(flowItems, nil)
 
 		// Recreate flow in new form
+		newIDs := make(map[string]string)
 		for i, block := range blocks {
-			if err := h.processBlock(c.Context(), userID, clonedForm.ID, block, nil, i, 0); err != nil {
+			if err := h.processBlock(c.Context(), userID, clonedForm.ID, block, nil, i, 0, newIDs); err != nil {
 				return fiber.ErrInternalServerError
 			}
 		}
+
+		// Recreate jumps between the copied nodes
+		for _, item := range flowItems {
+			targets, _ := item["next"].([]string)
+			for i, target := range targets {
+				if err := h.flowRepo.CreateEdge(c.Context(), clonedForm.ID, newIDs[item["id"].(string)], newIDs[target], i); err != nil {
+					return fiber.ErrInternalServerError
+				}
+			}
+		}
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -305,6 +403,7 @@ func (h *FormsHandler) buildTree(items []map[string]interface{}, parentID *strin
 				"type":     item["type"],
 				"question": item["question"],
 				"children": h.buildTree(items, &id),
+				"next":     item["next"],
 			}
 
 			result = append(result, block)
@@ -314,7 +413,7 @@ func (h *FormsHandler) buildTree(items []map[string]interface{}, parentID *strin
 	return result
 }
 
-func (h *FormsHandler) processBlock(ctx context.Context, userID, formID string, block map[string]interface{}, parentID *string, orderIndex, depthLevel int) error {
+func (h *FormsHandler) processBlock(ctx context.Context, userID, formID string, block map[string]interface{}, parentID *string, orderIndex, depthLevel int, newIDs map[string]string) error {
 	questionText := block["question"].(string)
 	qType := block["type"].(string)
 
@@ -330,7 +429,8 @@ func (h *FormsHandler) processBlock(ctx context.Context, userID, formID string,
 
 	// Get children
 	children, _ := block["children"].([]map[string]interface{})
-	isTerminal := len(children) == 0
+	next, _ := block["next"].([]string)
+	isTerminal := len(children) == 0 && len(next) == 0
 
 	// Create flow connection
 	connection, err := h.flowRepo.Create(ctx, formID, questionID, parentID, orderIndex, depthLevel, isTerminal)
@@ -345,10 +445,11 @@ func (h *FormsHandler) processBlock(ctx context.Context, userID, formID string,
 	}
 	idField := val.FieldByName("ID")
 	connID := idField.String()
+	newIDs[block["id"].(string)] = connID
 
 	// Process children recursively
 	for i, child := range children {
-		if err := h.processBlock(ctx, userID, formID, child, &connID, i, depthLevel+1); err != nil {
+		if err := h.processBlock(ctx, userID, formID, child, &connID, i, depthLevel+1, newIDs); err != nil {
 			return err
 		}
 	}
@@ -367,6 +468,8 @@ func mapServiceError(err error) error {
 		return fiber.ErrBadRequest
 	case ErrNotFound:
 		return fiber.ErrNotFound
+	case ErrWorkspaceNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Workspace not found")
 	default:
 		return fiber.ErrInternalServerError
 	}
@@ -1,20 +1,26 @@
 package forms
 
-import "time"
+import (
+	"time"
+
+	"smart-forms/internal/quiz"
+)
 
 // Form represents a form metadata entity
 type Form struct {
-	ID                 string     `json:"id"`
-	UserID             string     `json:"-"`
-	Title              string     `json:"title"`
-	Description        string     `json:"description"`
-	Status             string     `json:"status"`
-	AutoSlug           *string    `json:"auto_slug,omitempty"`
-	CustomSlug         *string    `json:"custom_slug,omitempty"`
-	AcceptingResponses bool       `json:"accepting_responses"`
-	PublishedAt        *time.Time `json:"published_at,omitempty"`
-	IsTemplate         bool       `json:"is_template"`
-	CreatedAt          time.Time  `json:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at"`
-	DeletedAt          *time.Time `json:"-"`
+	ID                 string         `json:"id"`
+	UserID             string         `json:"-"` // creator
+	WorkspaceID        string         `json:"workspace_id,omitempty"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	Status             string         `json:"status"`
+	AutoSlug           *string        `json:"auto_slug,omitempty"`
+	CustomSlug         *string        `json:"custom_slug,omitempty"`
+	AcceptingResponses bool           `json:"accepting_responses"`
+	PublishedAt        *time.Time     `json:"published_at,omitempty"`
+	IsTemplate         bool           `json:"is_template"`
+	Quiz               *quiz.Settings `json:"quiz,omitempty"` // nil = not a quiz
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          *time.Time     `json:"-"`
 }
@@ -2,6 +2,9 @@ package forms
 
 import (
 	"context"
+	"encoding/json"
+
+	"smart-forms/internal/quiz"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -25,14 +28,22 @@ func NewFormsRepository(db *pgxpool.Pool) *FormsRepository {
 func (r *FormsRepository) Create(
 	ctx context.Context,
 	userID string,
+	workspaceID string,
 	title string,
 	description string,
 ) (*Form, error) {
 
+	// Every form starts with an empty draft version 1
 	const query = `
-		INSERT INTO forms (user_id, title, description)
-		VALUES ($1, $2, $3)
-		RETURNING id, title, description, status, created_at, updated_at
+		WITH f AS (
+			INSERT INTO forms (user_id, workspace_id, title, description)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, workspace_id, title, description, status, created_at, updated_at
+		), v AS (
+			INSERT INTO form_versions (form_id, version_number, status, created_by)
+			SELECT id, 1, 'draft', $1 FROM f
+		)
+		SELECT id, workspace_id, title, description, status, created_at, updated_at FROM f
 	`
 
 	var f Form
@@ -40,10 +51,12 @@ func (r *FormsRepository) Create(
 		ctx,
 		query,
 		userID,
+		workspaceID,
 		title,
 		description,
 	).Scan(
 		&f.ID,
+		&f.WorkspaceID,
 		&f.Title,
 		&f.Description,
 		&f.Status,
@@ -70,11 +83,11 @@ func (r *FormsRepository) GetByID(
 ) (*Form, error) {
 
 	const query = `
-		SELECT id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, created_at, updated_at
+		SELECT id, workspace_id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, quiz, created_at, updated_at
 		FROM forms
 		WHERE
 			id = $1
-			AND user_id = $2
+			AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $2))
 			AND deleted_at IS NULL
 	`
 
@@ -86,6 +99,7 @@ func (r *FormsRepository) GetByID(
 		userID,
 	).Scan(
 		&f.ID,
+		&f.WorkspaceID,
 		&f.Title,
 		&f.Description,
 		&f.Status,
@@ -94,6 +108,7 @@ func (r *FormsRepository) GetByID(
 		&f.AcceptingResponses,
 		&f.PublishedAt,
 		&f.IsTemplate,
+		&f.Quiz,
 		&f.CreatedAt,
 		&f.UpdatedAt,
 	)
@@ -113,6 +128,7 @@ func (r *FormsRepository) GetByID(
 func (r *FormsRepository) List(
 	ctx context.Context,
 	userID string,
+	workspaceID *string,
 	search string,
 	limit int,
 	offset int,
@@ -135,15 +151,16 @@ func (r *FormsRepository) List(
 	if search == "" {
 		// -------- NO SEARCH --------
 		listQuery := `
-			SELECT id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, created_at, updated_at
+			SELECT id, workspace_id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, created_at, updated_at
 			FROM forms
-			WHERE user_id = $1
+			WHERE (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $1))
+			  AND ($2::uuid IS NULL OR workspace_id = $2)
 			  AND deleted_at IS NULL
 			ORDER BY updated_at DESC
-			LIMIT $2 OFFSET $3
+			LIMIT $3 OFFSET $4
 		`
 
-		rows, err = r.db.Query(ctx, listQuery, userID, limit, offset)
+		rows, err = r.db.Query(ctx, listQuery, userID, workspaceID, limit, offset)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -152,11 +169,12 @@ func (r *FormsRepository) List(
 		countQuery := `
 			SELECT COUNT(*)
 			FROM forms
-			WHERE user_id = $1
+			WHERE (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $1))
+			  AND ($2::uuid IS NULL OR workspace_id = $2)
 			  AND deleted_at IS NULL
 		`
 
-		err = r.db.QueryRow(ctx, countQuery, userID).Scan(&total)
+		err = r.db.QueryRow(ctx, countQuery, userID, workspaceID).Scan(&total)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -164,16 +182,17 @@ func (r *FormsRepository) List(
 	} else {
 		// -------- WITH SEARCH --------
 		listQuery := `
-			SELECT id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, created_at, updated_at
+			SELECT id, workspace_id, title, description, status, auto_slug, custom_slug, accepting_responses, published_at, is_template, created_at, updated_at
 			FROM forms
-			WHERE user_id = $1
+			WHERE (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $1))
+			  AND ($2::uuid IS NULL OR workspace_id = $2)
 			  AND deleted_at IS NULL
-			  AND title ILIKE '%' || $2 || '%'
+			  AND title ILIKE '%' || $3 || '%'
 			ORDER BY updated_at DESC
-			LIMIT $3 OFFSET $4
+			LIMIT $4 OFFSET $5
 		`
 
-		rows, err = r.db.Query(ctx, listQuery, userID, search, limit, offset)
+		rows, err = r.db.Query(ctx, listQuery, userID, workspaceID, search, limit, offset)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -182,12 +201,13 @@ func (r *FormsRepository) List(
 		countQuery := `
 			SELECT COUNT(*)
 			FROM forms
-			WHERE user_id = $1
+			WHERE (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $1))
+			  AND ($2::uuid IS NULL OR workspace_id = $2)
 			  AND deleted_at IS NULL
-			  AND title ILIKE '%' || $2 || '%'
+			  AND title ILIKE '%' || $3 || '%'
 		`
 
-		err = r.db.QueryRow(ctx, countQuery, userID, search).Scan(&total)
+		err = r.db.QueryRow(ctx, countQuery, userID, workspaceID, search).Scan(&total)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -197,6 +217,7 @@ func (r *FormsRepository) List(
 		var f Form
 		if err := rows.Scan(
 			&f.ID,
+			&f.WorkspaceID,
 			&f.Title,
 			&f.Description,
 			&f.Status,
@@ -244,7 +265,7 @@ func (r *FormsRepository) Update(
 			updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
 		WHERE
 			id = $4
-			AND user_id = $5
+			AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $5) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $5))
 			AND deleted_at IS NULL
 	`
 
@@ -269,6 +290,51 @@ func (r *FormsRepository) Update(
 	return nil
 }
 
+/*
+========================
+ SET QUIZ
+========================
+*/
+// SetQuiz replaces the form's quiz settings; nil turns quiz mode off
+func (r *FormsRepository) SetQuiz(
+	ctx context.Context,
+	userID string,
+	formID string,
+	settings *quiz.Settings,
+) error {
+
+	const query = `
+		UPDATE forms
+		SET
+			quiz = $1,
+			updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE
+			id = $2
+			AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $3) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $3))
+			AND deleted_at IS NULL
+	`
+
+	var settingsJSON []byte
+	if settings != nil {
+		var err error
+		settingsJSON, err = json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	cmd, err := r.db.Exec(ctx, query, settingsJSON, formID, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 /*
 ========================
  SOFT DELETE
@@ -285,7 +351,7 @@ func (r *FormsRepository) SoftDelete(
 		SET deleted_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
 		WHERE
 			id = $1
-			AND user_id = $2
+			AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $2))
 			AND deleted_at IS NULL
 	`
 
@@ -301,6 +367,42 @@ func (r *FormsRepository) SoftDelete(
 	return nil
 }
 
+/*
+========================
+ MOVE TO WORKSPACE
+========================
+*/
+func (r *FormsRepository) MoveToWorkspace(
+	ctx context.Context,
+	userID string,
+	formID string,
+	workspaceID string,
+) error {
+
+	// Membership of the target workspace is checked by the service
+	const query = `
+		UPDATE forms
+		SET
+			workspace_id = $3,
+			updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE
+			id = $1
+			AND workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2)
+			AND deleted_at IS NULL
+	`
+
+	cmd, err := r.db.Exec(ctx, query, formID, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 /*
 ========================
  GET FORM SLUGS
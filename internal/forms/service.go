@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"smart-forms/internal/cache"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/workspaces"
 )
 
 // Allowed form statuses (v1)
@@ -25,15 +27,17 @@ var (
 
 // FormsService coordinates business logic
 type FormsService struct {
-	repo  *FormsRepository
-	cache *cache.Cache
+	repo       *FormsRepository
+	cache      *cache.Cache
+	workspaces *workspaces.WorkspacesService
 }
 
 // NewFormsService creates service
-func NewFormsService(repo *FormsRepository, cacheClient *cache.Cache) *FormsService {
+func NewFormsService(repo *FormsRepository, cacheClient *cache.Cache, workspaceService *workspaces.WorkspacesService) *FormsService {
 	return &FormsService{
-		repo:  repo,
-		cache: cacheClient,
+		repo:       repo,
+		cache:      cacheClient,
+		workspaces: workspaceService,
 	}
 }
 
@@ -42,9 +46,12 @@ func NewFormsService(repo *FormsRepository, cacheClient *cache.Cache) *FormsServ
  CREATE FORM
 ========================
 */
+// Create creates a form in workspaceID, or in the user's personal workspace
+// when workspaceID is empty
 func (s *FormsService) Create(
 	ctx context.Context,
 	userID string,
+	workspaceID string,
 	title string,
 	description string,
 ) (*Form, error) {
@@ -56,7 +63,12 @@ func (s *FormsService) Create(
 		return nil, ErrInvalidInput
 	}
 
-	return s.repo.Create(ctx, userID, title, description)
+	workspaceID, err := s.resolveWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, userID, workspaceID, title, description)
 }
 
 /*
@@ -78,9 +90,12 @@ func (s *FormsService) GetByID(
  LIST FORMS
 ========================
 */
+// List lists forms of all the user's workspaces and forms shared with them,
+// or only those of one workspace
 func (s *FormsService) List(
 	ctx context.Context,
 	userID string,
+	workspaceID string,
 	search string,
 	limit int,
 	offset int,
@@ -88,7 +103,12 @@ func (s *FormsService) List(
 
 	search = strings.TrimSpace(search)
 
-	return s.repo.List(ctx, userID, search, limit, offset)
+	var workspaceFilter *string
+	if workspaceID = strings.TrimSpace(workspaceID); workspaceID != "" {
+		workspaceFilter = &workspaceID
+	}
+
+	return s.repo.List(ctx, userID, workspaceFilter, search, limit, offset)
 }
 
 /*
@@ -149,6 +169,40 @@ func (s *FormsService) Update(
 	return nil
 }
 
+/*
+========================
+ SET QUIZ SETTINGS
+========================
+*/
+func (s *FormsService) SetQuiz(
+	ctx context.Context,
+	userID string,
+	formID string,
+	settings quiz.Settings,
+) error {
+
+	if err := settings.Validate(); err != nil {
+		return ErrInvalidInput
+	}
+
+	autoSlug, customSlug, _ := s.repo.GetFormSlugs(ctx, formID)
+
+	if err := s.repo.SetQuiz(ctx, userID, formID, &settings); err != nil {
+		return err
+	}
+
+	// Scoring applies from the next submission; cached copies go now
+	s.cache.Delete(cache.FormIDKey(formID))
+	if autoSlug != nil && *autoSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*autoSlug))
+	}
+	if customSlug != nil && *customSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*customSlug))
+	}
+
+	return nil
+}
+
 /*
 ========================
  SOFT DELETE
@@ -193,11 +247,50 @@ func (s *FormsService) SoftDelete(
 	return nil
 }
 
+/*
+========================
+ MOVE TO WORKSPACE
+========================
+*/
+func (s *FormsService) MoveToWorkspace(
+	ctx context.Context,
+	userID string,
+	formID string,
+	workspaceID string,
+) (*Form, error) {
+
+	workspaceID = strings.TrimSpace(workspaceID)
+	if workspaceID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	workspaceID, err := s.resolveWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MoveToWorkspace(ctx, userID, formID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, userID, formID)
+}
+
 /*
 ========================
  HELPERS
 ========================
 */
+
+// resolveWorkspace checks membership of workspaceID (personal workspace if empty)
+func (s *FormsService) resolveWorkspace(ctx context.Context, userID, workspaceID string) (string, error) {
+	id, err := s.workspaces.ResolveFormWorkspace(ctx, userID, workspaceID)
+	if errors.Is(err, workspaces.ErrNotFound) {
+		return "", ErrWorkspaceNotFound
+	}
+	return id, err
+}
+
 func isValidStatus(status string) bool {
 	switch status {
 	case StatusDraft, StatusPublished:
@@ -0,0 +1,11 @@
+package fragments
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("fragment not found")
+	ErrVersionNotFound = errors.New("fragment version not found")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrNameTaken       = errors.New("fragment name already used in this workspace")
+	ErrForbidden       = errors.New("insufficient workspace role")
+)
@@ -0,0 +1,202 @@
+package fragments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"smart-forms/internal/flows"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/versions"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// embed is a fragment node of a draft
+type embed struct {
+	id         string
+	fragmentID string
+	version    *int
+	depth      int
+	next       []string
+}
+
+// ExpandDraft implements versions.Expander: every fragment node of the draft
+// gets its fragment's blocks (pinned version, else the latest) as generated
+// child nodes. Where the fragment ends, the flow continues at the fragment
+// node's jump targets.
+func (r *FragmentsRepository) ExpandDraft(ctx context.Context, tx pgx.Tx, formID, versionID, userID string) error {
+	embeds, err := listEmbeds(ctx, tx, versionID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range embeds {
+		v, err := getVersion(ctx, tx, e.fragmentID, e.version)
+		if err != nil {
+			return err
+		}
+
+		expanded := flows.ExpandFragment(flows.Block{ID: e.id, Next: e.next}, v.Blocks, e.id+"/")
+		if len(expanded.Children) == 0 {
+			continue
+		}
+
+		x := &expansion{tx: tx, formID: formID, versionID: versionID, userID: userID, embedID: e.id,
+			ids: make(map[string]string), jumps: make(map[string][]string)}
+		for i, b := range expanded.Children {
+			if err := x.insert(ctx, b, e.id, i, e.depth+1); err != nil {
+				return err
+			}
+		}
+		if err := x.insertJumps(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE flow_connections SET is_terminal = FALSE WHERE id = $1
+		`, e.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listEmbeds returns the fragment nodes of a version with their jump targets
+func listEmbeds(ctx context.Context, tx pgx.Tx, versionID string) ([]embed, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT fc.id, fc.fragment_id, fc.fragment_version, fc.depth_level,
+		       COALESCE(ARRAY(
+		           SELECT e.target_id::text FROM flow_edges e
+		           WHERE e.source_id = fc.id
+		           ORDER BY e.order_index
+		       ), '{}')
+		FROM flow_connections fc
+		WHERE fc.version_id = $1 AND fc.fragment_id IS NOT NULL
+		  AND fc.generated_by IS NULL AND fc.deleted_at IS NULL
+		ORDER BY fc.depth_level, fc.order_index
+	`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeds []embed
+	for rows.Next() {
+		var e embed
+		if err := rows.Scan(&e.id, &e.fragmentID, &e.version, &e.depth, &e.next); err != nil {
+			return nil, err
+		}
+		embeds = append(embeds, e)
+	}
+	return embeds, rows.Err()
+}
+
+// expansion writes one expanded fragment into the draft
+type expansion struct {
+	tx        pgx.Tx
+	formID    string
+	versionID string
+	userID    string
+	embedID   string
+
+	ids   map[string]string   // expanded block ID -> new connection ID
+	jumps map[string][]string // new connection ID -> jump targets (block IDs or connection IDs)
+}
+
+func (x *expansion) insert(ctx context.Context, b flows.Block, parentID string, orderIndex, depth int) error {
+	questionID, err := x.question(ctx, b.Type, strings.TrimSpace(b.Question), b.Quiz, b.Kind)
+	if err != nil {
+		return err
+	}
+
+	assignments, err := versions.EncodeAssignments(b.Set)
+	if err != nil {
+		return err
+	}
+
+	id := uuid.NewString()
+	if _, err := x.tx.Exec(ctx, `
+		INSERT INTO flow_connections (id, form_id, version_id, question_id, parent_id, order_index, depth_level, is_terminal, generated_by,
+		                              variable, assignments, ending, randomize)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13)
+	`, id, x.formID, x.versionID, questionID, parentID, orderIndex, depth,
+		len(b.Children) == 0 && len(b.Next) == 0, x.embedID,
+		strings.TrimSpace(b.Variable), assignments, strings.TrimSpace(b.Ending), b.Randomize); err != nil {
+		return err
+	}
+
+	if b.ID != "" {
+		x.ids[b.ID] = id
+	}
+	if len(b.Next) > 0 {
+		x.jumps[id] = b.Next
+	}
+	for i, child := range b.Children {
+		if err := x.insert(ctx, child, id, i, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertJumps adds the jumps of the expanded blocks: targets inside the
+// fragment by block ID, the fragment node's own targets by connection ID
+func (x *expansion) insertJumps(ctx context.Context) error {
+	for source, targets := range x.jumps {
+		for i, target := range targets {
+			if id, ok := x.ids[target]; ok {
+				target = id
+			}
+			if _, err := x.tx.Exec(ctx, `
+				INSERT INTO flow_edges (form_id, version_id, source_id, target_id, order_index)
+				VALUES ($1, $2, $3, $4, $5)
+			`, x.formID, x.versionID, source, target, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// question finds or creates the question of an expanded block; quiz data
+// and the kind are matched too, as by the flow editor
+func (x *expansion) question(ctx context.Context, qType, text string, scoring *quiz.Scoring, kind *kinds.Spec) (string, error) {
+	var data, spec []byte
+	var err error
+	if !scoring.IsZero() {
+		if data, err = json.Marshal(scoring); err != nil {
+			return "", err
+		}
+	}
+	if kind != nil {
+		if spec, err = json.Marshal(kind); err != nil {
+			return "", err
+		}
+	}
+
+	var id string
+	err = x.tx.QueryRow(ctx, `
+		SELECT id FROM questions
+		WHERE type = $1 AND question_text = $2 AND deleted_at IS NULL
+		  AND metadata->'quiz' IS NOT DISTINCT FROM $3::jsonb
+		  AND metadata->'kind' IS NOT DISTINCT FROM $4::jsonb
+		LIMIT 1
+	`, qType, text, data, spec).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+
+	err = x.tx.QueryRow(ctx, `
+		INSERT INTO questions (type, question_text, created_by, input_type, metadata)
+		VALUES ($1, $2, $3, $5::jsonb->>'type', jsonb_strip_nulls(jsonb_build_object('quiz', $4::jsonb, 'kind', $5::jsonb)))
+		RETURNING id
+	`, qType, text, x.userID, data, spec).Scan(&id)
+	return id, err
+}
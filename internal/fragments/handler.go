@@ -0,0 +1,183 @@
+package fragments
+
+import (
+	"errors"
+	"strconv"
+
+	"smart-forms/internal/flows"
+	"smart-forms/internal/versions"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FragmentsHandler struct {
+	service *FragmentsService
+}
+
+func NewFragmentsHandler(service *FragmentsService) *FragmentsHandler {
+	return &FragmentsHandler{service: service}
+}
+
+// ListFragments lists the caller's fragments
+// GET /fragments?workspace_id=
+func (h *FragmentsHandler) ListFragments(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	items, err := h.service.ListFragments(c.Context(), userID, c.Query("workspace_id"))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// CreateFragment creates a fragment
+// POST /fragments
+func (h *FragmentsHandler) CreateFragment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req CreateFragmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	f, err := h.service.CreateFragment(c.Context(), userID, req)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(f)
+}
+
+// GetFragment retrieves a fragment with its latest blocks
+// GET /fragments/:id
+func (h *FragmentsHandler) GetFragment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	f, err := h.service.GetFragment(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(f)
+}
+
+// UpdateFragment renames or redescribes a fragment
+// PATCH /fragments/:id
+func (h *FragmentsHandler) UpdateFragment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req UpdateFragmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	f, err := h.service.UpdateFragment(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(f)
+}
+
+// DeleteFragment deletes a fragment
+// DELETE /fragments/:id
+func (h *FragmentsHandler) DeleteFragment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.DeleteFragment(c.Context(), userID, c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Fragment deleted successfully",
+	})
+}
+
+// ListVersions lists a fragment's versions
+// GET /fragments/:id/versions
+func (h *FragmentsHandler) ListVersions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	items, err := h.service.ListVersions(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// CreateVersion adds a version with new blocks
+// POST /fragments/:id/versions
+func (h *FragmentsHandler) CreateVersion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req CreateVersionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	v, err := h.service.CreateVersion(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(v)
+}
+
+// GetVersion returns a fragment version with its blocks
+// GET /fragments/:id/versions/:version
+func (h *FragmentsHandler) GetVersion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	number, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	v, err := h.service.GetVersion(c.Context(), userID, c.Params("id"), number)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(v)
+}
+
+// fail answers blocks rejected by the linter with 422 and the lint report
+func (h *FragmentsHandler) fail(c *fiber.Ctx, err error) error {
+	var lintErr *versions.LintError
+	if errors.As(err, &lintErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Fragment has errors; nothing was saved",
+			"lint":    lintErr.Report,
+		})
+	}
+	return mapServiceError(err)
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Fragment not found")
+	case ErrVersionNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Fragment version not found")
+	case ErrInvalidInput, flows.ErrInvalidInput:
+		return fiber.ErrBadRequest
+	case flows.ErrJumpTarget:
+		return fiber.NewError(fiber.StatusBadRequest, "Jump target not found in fragment")
+	case flows.ErrFlowCycle:
+		return fiber.NewError(fiber.StatusBadRequest, "Fragment contains a cycle")
+	case ErrNameTaken:
+		return fiber.NewError(fiber.StatusConflict, "A fragment with this name already exists in the workspace")
+	case ErrForbidden:
+		return fiber.NewError(fiber.StatusForbidden, "Only the fragment's creator or a workspace owner or admin can delete it")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
@@ -0,0 +1,52 @@
+package fragments
+
+import (
+	"time"
+
+	"smart-forms/internal/flows"
+)
+
+// Fragment is a named piece of flow that forms embed by reference. Editing
+// it adds a version; drafts embedding it without a pinned version get the
+// latest one when they are published.
+type Fragment struct {
+	ID            string        `json:"id"`
+	WorkspaceID   string        `json:"workspace_id"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	LatestVersion int           `json:"latest_version"`
+	UsageCount    int           `json:"usage_count"` // forms whose draft or published flow embeds it
+	Role          string        `json:"role"`        // caller's workspace role
+	CreatedBy     *string       `json:"created_by,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Blocks        []flows.Block `json:"blocks,omitempty"` // latest version, when reading one fragment
+}
+
+// FragmentVersion is one frozen revision of a fragment's blocks
+type FragmentVersion struct {
+	FragmentID string        `json:"fragment_id"`
+	Number     int           `json:"version"`
+	CreatedBy  *string       `json:"created_by,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Blocks     []flows.Block `json:"blocks,omitempty"` // omitted in lists
+}
+
+// CreateFragmentRequest creates a fragment with its first version
+type CreateFragmentRequest struct {
+	WorkspaceID string        `json:"workspace_id"` // defaults to the personal workspace
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Blocks      []flows.Block `json:"blocks"`
+}
+
+// UpdateFragmentRequest renames or redescribes a fragment
+type UpdateFragmentRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// CreateVersionRequest adds a version with new blocks
+type CreateVersionRequest struct {
+	Blocks []flows.Block `json:"blocks"`
+}
@@ -0,0 +1,283 @@
+package fragments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"smart-forms/internal/flows"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FragmentsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFragmentsRepository(db *pgxpool.Pool) *FragmentsRepository {
+	return &FragmentsRepository{db: db}
+}
+
+// dbtx is satisfied by both the pool and a transaction
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Columns of a fragment as seen by workspace member $1
+const fragmentSelect = `
+	SELECT f.id, f.workspace_id, f.name, f.description, f.latest_version,
+	       (SELECT COUNT(DISTINCT fc.form_id)
+	        FROM flow_connections fc
+	        JOIN form_versions v ON v.id = fc.version_id AND v.status IN ('draft', 'published')
+	        WHERE fc.fragment_id = f.id AND fc.deleted_at IS NULL),
+	       m.role, f.created_by, f.created_at, f.updated_at
+	FROM flow_fragments f
+	JOIN workspace_members m ON m.workspace_id = f.workspace_id AND m.user_id = $1
+`
+
+func scanFragment(row pgx.Row) (*Fragment, error) {
+	var f Fragment
+	err := row.Scan(
+		&f.ID,
+		&f.WorkspaceID,
+		&f.Name,
+		&f.Description,
+		&f.LatestVersion,
+		&f.UsageCount,
+		&f.Role,
+		&f.CreatedBy,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+/*
+========================
+ FRAGMENTS
+========================
+*/
+
+// ListForUser lists the fragments of the user's workspaces, optionally of one
+// workspace only
+func (r *FragmentsRepository) ListForUser(ctx context.Context, userID, workspaceID string) ([]Fragment, error) {
+	rows, err := r.db.Query(ctx, fragmentSelect+`
+		WHERE f.deleted_at IS NULL AND ($2 = '' OR f.workspace_id::text = $2)
+		ORDER BY lower(f.name)
+	`, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fragments := []Fragment{}
+	for rows.Next() {
+		f, err := scanFragment(rows)
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, *f)
+	}
+
+	return fragments, rows.Err()
+}
+
+// GetForUser retrieves a fragment if userID is a member of its workspace
+func (r *FragmentsRepository) GetForUser(ctx context.Context, fragmentID, userID string) (*Fragment, error) {
+	f, err := scanFragment(r.db.QueryRow(ctx, fragmentSelect+`
+		WHERE f.id = $2 AND f.deleted_at IS NULL
+	`, userID, fragmentID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// GetEmbeddable retrieves a fragment a flow of userID may embed: one of the
+// user's workspaces, deleted or not, since flows embedding a fragment keep it
+// when they are saved again
+func (r *FragmentsRepository) GetEmbeddable(ctx context.Context, fragmentID, userID string) (*Fragment, error) {
+	f, err := scanFragment(r.db.QueryRow(ctx, fragmentSelect+`
+		WHERE f.id = $2
+	`, userID, fragmentID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Create adds a fragment and its first version
+func (r *FragmentsRepository) Create(ctx context.Context, workspaceID, userID, name, description string, blocks []flows.Block) (string, error) {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO flow_fragments (workspace_id, name, description, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, workspaceID, name, description, userID).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", ErrNameTaken
+		}
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO flow_fragment_versions (fragment_id, version_number, blocks, created_by)
+		VALUES ($1, 1, $2, $3)
+	`, id, data, userID); err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// Update sets a fragment's name and description
+func (r *FragmentsRepository) Update(ctx context.Context, fragmentID, name, description string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE flow_fragments
+		SET name = $2, description = $3, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND deleted_at IS NULL
+	`, fragmentID, name, description)
+	if isUniqueViolation(err) {
+		return ErrNameTaken
+	}
+	return err
+}
+
+// Delete soft deletes a fragment. Flows embedding it keep working: their
+// embeds still expand on publish.
+func (r *FragmentsRepository) Delete(ctx context.Context, fragmentID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE flow_fragments
+		SET deleted_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND deleted_at IS NULL
+	`, fragmentID)
+	return err
+}
+
+/*
+========================
+ VERSIONS
+========================
+*/
+
+// ListVersions lists a fragment's versions, newest first, without blocks
+func (r *FragmentsRepository) ListVersions(ctx context.Context, fragmentID string) ([]FragmentVersion, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT fragment_id, version_number, created_by, created_at
+		FROM flow_fragment_versions
+		WHERE fragment_id = $1
+		ORDER BY version_number DESC
+	`, fragmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []FragmentVersion{}
+	for rows.Next() {
+		var v FragmentVersion
+		if err := rows.Scan(&v.FragmentID, &v.Number, &v.CreatedBy, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetVersion returns a fragment version with its blocks; a nil number means
+// the latest. Deleted fragments resolve too, for the flows still embedding
+// them.
+func (r *FragmentsRepository) GetVersion(ctx context.Context, fragmentID string, number *int) (*FragmentVersion, error) {
+	return getVersion(ctx, r.db, fragmentID, number)
+}
+
+func getVersion(ctx context.Context, q dbtx, fragmentID string, number *int) (*FragmentVersion, error) {
+	var v FragmentVersion
+	var data []byte
+	err := q.QueryRow(ctx, `
+		SELECT v.fragment_id, v.version_number, v.created_by, v.created_at, v.blocks
+		FROM flow_fragment_versions v
+		JOIN flow_fragments f ON f.id = v.fragment_id
+		WHERE v.fragment_id = $1 AND v.version_number = COALESCE($2, f.latest_version)
+	`, fragmentID, number).Scan(&v.FragmentID, &v.Number, &v.CreatedBy, &v.CreatedAt, &data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVersionNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &v.Blocks); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// AddVersion adds a version and makes it the latest
+func (r *FragmentsRepository) AddVersion(ctx context.Context, fragmentID, userID string, blocks []flows.Block) (int, error) {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Row lock on the fragment numbers concurrent versions one after another
+	var number int
+	err = tx.QueryRow(ctx, `
+		UPDATE flow_fragments
+		SET latest_version = latest_version + 1, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING latest_version
+	`, fragmentID).Scan(&number)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO flow_fragment_versions (fragment_id, version_number, blocks, created_by)
+		VALUES ($1, $2, $3, $4)
+	`, fragmentID, number, data, userID); err != nil {
+		return 0, err
+	}
+
+	return number, tx.Commit(ctx)
+}
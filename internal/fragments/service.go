@@ -0,0 +1,186 @@
+package fragments
+
+import (
+	"context"
+	"strings"
+
+	"smart-forms/internal/flows"
+	"smart-forms/internal/workspaces"
+)
+
+type FragmentsService struct {
+	repo       *FragmentsRepository
+	workspaces *workspaces.WorkspacesService
+}
+
+func NewFragmentsService(repo *FragmentsRepository, workspacesService *workspaces.WorkspacesService) *FragmentsService {
+	return &FragmentsService{
+		repo:       repo,
+		workspaces: workspacesService,
+	}
+}
+
+// ListFragments lists the fragments of the caller's workspaces (or of one)
+func (s *FragmentsService) ListFragments(ctx context.Context, userID, workspaceID string) ([]Fragment, error) {
+	return s.repo.ListForUser(ctx, userID, workspaceID)
+}
+
+// GetFragment retrieves a fragment with the blocks of its latest version
+func (s *FragmentsService) GetFragment(ctx context.Context, userID, fragmentID string) (*Fragment, error) {
+	f, err := s.repo.GetForUser(ctx, fragmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.GetVersion(ctx, fragmentID, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.Blocks = v.Blocks
+
+	return f, nil
+}
+
+// CreateFragment creates a fragment in a workspace of the caller (personal
+// workspace by default) with its blocks as version 1
+func (s *FragmentsService) CreateFragment(ctx context.Context, userID string, req CreateFragmentRequest) (*Fragment, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidInput
+	}
+	if err := flows.ValidateFragment(req.Blocks); err != nil {
+		return nil, err
+	}
+
+	workspaceID, err := s.workspaces.ResolveFormWorkspace(ctx, userID, req.WorkspaceID)
+	if err != nil {
+		if err == workspaces.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	id, err := s.repo.Create(ctx, workspaceID, userID, name, strings.TrimSpace(req.Description), req.Blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetFragment(ctx, userID, id)
+}
+
+// UpdateFragment renames or redescribes a fragment
+func (s *FragmentsService) UpdateFragment(ctx context.Context, userID, fragmentID string, req UpdateFragmentRequest) (*Fragment, error) {
+	f, err := s.repo.GetForUser(ctx, fragmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, description := f.Name, f.Description
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			return nil, ErrInvalidInput
+		}
+	}
+	if req.Description != nil {
+		description = strings.TrimSpace(*req.Description)
+	}
+
+	if err := s.repo.Update(ctx, fragmentID, name, description); err != nil {
+		return nil, err
+	}
+
+	return s.GetFragment(ctx, userID, fragmentID)
+}
+
+// DeleteFragment deletes a fragment (its creator, or a workspace owner or
+// admin). Flows embedding it keep their embeds and can still be saved.
+func (s *FragmentsService) DeleteFragment(ctx context.Context, userID, fragmentID string) error {
+	f, err := s.repo.GetForUser(ctx, fragmentID, userID)
+	if err != nil {
+		return err
+	}
+	if f.Role == workspaces.RoleMember && (f.CreatedBy == nil || *f.CreatedBy != userID) {
+		return ErrForbidden
+	}
+
+	return s.repo.Delete(ctx, fragmentID)
+}
+
+/*
+========================
+ VERSIONS
+========================
+*/
+
+// ListVersions lists a fragment's versions, newest first
+func (s *FragmentsService) ListVersions(ctx context.Context, userID, fragmentID string) ([]FragmentVersion, error) {
+	if _, err := s.repo.GetForUser(ctx, fragmentID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListVersions(ctx, fragmentID)
+}
+
+// GetVersion returns a fragment version with its blocks
+func (s *FragmentsService) GetVersion(ctx context.Context, userID, fragmentID string, number int) (*FragmentVersion, error) {
+	if _, err := s.repo.GetForUser(ctx, fragmentID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetVersion(ctx, fragmentID, &number)
+}
+
+// CreateVersion adds a version with new blocks. It becomes the latest, so
+// every draft embedding the fragment unpinned gets it when published;
+// published versions keep what they were published with.
+func (s *FragmentsService) CreateVersion(ctx context.Context, userID, fragmentID string, req CreateVersionRequest) (*FragmentVersion, error) {
+	if err := flows.ValidateFragment(req.Blocks); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetForUser(ctx, fragmentID, userID); err != nil {
+		return nil, err
+	}
+
+	number, err := s.repo.AddVersion(ctx, fragmentID, userID, req.Blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetVersion(ctx, fragmentID, &number)
+}
+
+/*
+========================
+ EMBEDDING
+========================
+*/
+
+// CheckFragment implements flows.FragmentSource: the caller must be a member
+// of the fragment's workspace, and a pinned version must exist
+func (s *FragmentsService) CheckFragment(ctx context.Context, userID, fragmentID string, version *int) (string, error) {
+	f, err := s.repo.GetEmbeddable(ctx, fragmentID, userID)
+	if err != nil {
+		if err == ErrNotFound {
+			return "", flows.ErrFragmentNotFound
+		}
+		return "", err
+	}
+	if version != nil && (*version < 1 || *version > f.LatestVersion) {
+		return "", flows.ErrFragmentNotFound
+	}
+	return f.Name, nil
+}
+
+// FragmentBlocks implements flows.FragmentSource
+func (s *FragmentsService) FragmentBlocks(ctx context.Context, fragmentID string, version *int) ([]flows.Block, error) {
+	v, err := s.repo.GetVersion(ctx, fragmentID, version)
+	if err != nil {
+		if err == ErrVersionNotFound {
+			return nil, flows.ErrFragmentNotFound
+		}
+		return nil, err
+	}
+	return v.Blocks, nil
+}
@@ -0,0 +1,266 @@
+package kinds
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Count is how many answers gave one value
+type Count struct {
+	Value interface{} `json:"value"`
+	Count int         `json:"count"`
+}
+
+// MatrixSummary counts, for every row, the answers per column
+type MatrixSummary struct {
+	Rows []MatrixRow `json:"rows"`
+}
+
+// MatrixRow is one row of a matrix: how many rated it and how, and the
+// average column position (1 = first column)
+type MatrixRow struct {
+	Row     string  `json:"row"`
+	Answers int     `json:"answers"`
+	Columns []Count `json:"columns"`
+	Average float64 `json:"average"`
+}
+
+// RatingSummary is the average rating and how many gave each number of stars
+type RatingSummary struct {
+	Average      float64 `json:"average"`
+	Distribution []Count `json:"distribution"`
+}
+
+// NPSSummary splits NPS answers into detractors (0-6), passives (7-8) and
+// promoters (9-10). Score is the percentage of promoters minus that of
+// detractors, -100 to 100.
+type NPSSummary struct {
+	Score        float64 `json:"score"`
+	Promoters    int     `json:"promoters"`
+	Passives     int     `json:"passives"`
+	Detractors   int     `json:"detractors"`
+	Distribution []Count `json:"distribution"`
+}
+
+// RankingSummary lists the items by average rank, best first
+type RankingSummary struct {
+	Items []RankedItem `json:"items"`
+}
+
+// RankedItem is how respondents ranked one item (1 = first)
+type RankedItem struct {
+	Item        string  `json:"item"`
+	AverageRank float64 `json:"average_rank"`
+	First       int     `json:"first"` // times ranked first
+}
+
+// SliderSummary describes the values chosen on a slider
+type SliderSummary struct {
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+}
+
+// DateTimeSummary is the range of the answers, and for dates and datetimes
+// how many fell on each weekday (Sunday first)
+type DateTimeSummary struct {
+	Earliest string `json:"earliest,omitempty"`
+	Latest   string `json:"latest,omitempty"`
+	Weekdays []int  `json:"weekdays,omitempty"`
+}
+
+// YesNoSummary counts yes and no answers
+type YesNoSummary struct {
+	Yes     int     `json:"yes"`
+	No      int     `json:"no"`
+	YesRate float64 `json:"yes_rate"`
+}
+
+// FileSummary counts the files attached and their total size in bytes
+type FileSummary struct {
+	Files          int     `json:"files"`
+	FilesPerAnswer float64 `json:"files_per_answer"`
+	TotalSize      int64   `json:"total_size"`
+}
+
+func aggregateMatrix(s *Spec, values []map[string]interface{}) interface{} {
+	counts := make(map[string]map[string]int, len(s.Rows))
+	for _, v := range values {
+		for row, col := range v["rows"].(map[string]interface{}) {
+			if counts[row] == nil {
+				counts[row] = make(map[string]int)
+			}
+			counts[row][col.(string)]++
+		}
+	}
+
+	summary := &MatrixSummary{Rows: make([]MatrixRow, len(s.Rows))}
+	for i, row := range s.Rows {
+		r := MatrixRow{Row: row, Columns: make([]Count, len(s.Columns))}
+		var sum int
+		for j, col := range s.Columns {
+			n := counts[row][col]
+			r.Columns[j] = Count{Value: col, Count: n}
+			r.Answers += n
+			sum += n * (j + 1)
+		}
+		if r.Answers > 0 {
+			r.Average = round(float64(sum) / float64(r.Answers))
+		}
+		summary.Rows[i] = r
+	}
+	return summary
+}
+
+func aggregateRating(s *Spec, values []map[string]interface{}) interface{} {
+	summary := &RatingSummary{Distribution: scale(1, s.stars())}
+	var sum int
+	for _, v := range values {
+		n, _ := integer(v["rating"])
+		summary.Distribution[n-1].Count++
+		sum += n
+	}
+	if len(values) > 0 {
+		summary.Average = round(float64(sum) / float64(len(values)))
+	}
+	return summary
+}
+
+func aggregateNPS(_ *Spec, values []map[string]interface{}) interface{} {
+	summary := &NPSSummary{Distribution: scale(0, 10)}
+	for _, v := range values {
+		n, _ := integer(v["score"])
+		summary.Distribution[n].Count++
+		switch {
+		case n >= 9:
+			summary.Promoters++
+		case n >= 7:
+			summary.Passives++
+		default:
+			summary.Detractors++
+		}
+	}
+	if len(values) > 0 {
+		summary.Score = round(float64(summary.Promoters-summary.Detractors) * 100 / float64(len(values)))
+	}
+	return summary
+}
+
+func aggregateRanking(s *Spec, values []map[string]interface{}) interface{} {
+	ranks := make(map[string]int, len(s.Items))
+	first := make(map[string]int, len(s.Items))
+	for _, v := range values {
+		for i, item := range v["ranking"].([]interface{}) {
+			ranks[item.(string)] += i + 1
+			if i == 0 {
+				first[item.(string)]++
+			}
+		}
+	}
+
+	summary := &RankingSummary{Items: make([]RankedItem, len(s.Items))}
+	for i, item := range s.Items {
+		summary.Items[i] = RankedItem{Item: item, First: first[item]}
+		if len(values) > 0 {
+			summary.Items[i].AverageRank = round(float64(ranks[item]) / float64(len(values)))
+		}
+	}
+	sort.SliceStable(summary.Items, func(i, j int) bool {
+		return summary.Items[i].AverageRank < summary.Items[j].AverageRank
+	})
+	return summary
+}
+
+func aggregateSlider(_ *Spec, values []map[string]interface{}) interface{} {
+	summary := &SliderSummary{}
+	if len(values) == 0 {
+		return summary
+	}
+	nums := make([]float64, len(values))
+	var sum float64
+	for i, v := range values {
+		nums[i] = v["value"].(float64)
+		sum += nums[i]
+	}
+	sort.Float64s(nums)
+
+	summary.Average = round(sum / float64(len(nums)))
+	summary.Min, summary.Max = nums[0], nums[len(nums)-1]
+	if mid := len(nums) / 2; len(nums)%2 == 0 {
+		summary.Median = round((nums[mid-1] + nums[mid]) / 2)
+	} else {
+		summary.Median = nums[mid]
+	}
+	return summary
+}
+
+func aggregateDateTime(s *Spec, values []map[string]interface{}) interface{} {
+	layout := layouts[s.mode()]
+	summary := &DateTimeSummary{}
+	if s.mode() != "time" {
+		summary.Weekdays = make([]int, 7)
+	}
+	var earliest, latest time.Time
+	for i, v := range values {
+		t, _ := time.Parse(layout, v["value"].(string))
+		if i == 0 || t.Before(earliest) {
+			earliest = t
+		}
+		if i == 0 || t.After(latest) {
+			latest = t
+		}
+		if summary.Weekdays != nil {
+			summary.Weekdays[t.Weekday()]++
+		}
+	}
+	if len(values) > 0 {
+		summary.Earliest, summary.Latest = earliest.Format(layout), latest.Format(layout)
+	}
+	return summary
+}
+
+func aggregateYesNo(_ *Spec, values []map[string]interface{}) interface{} {
+	summary := &YesNoSummary{}
+	for _, v := range values {
+		if v["value"].(bool) {
+			summary.Yes++
+		} else {
+			summary.No++
+		}
+	}
+	if len(values) > 0 {
+		summary.YesRate = round(float64(summary.Yes) / float64(len(values)))
+	}
+	return summary
+}
+
+func aggregateFile(_ *Spec, values []map[string]interface{}) interface{} {
+	summary := &FileSummary{}
+	for _, v := range values {
+		for _, f := range v["files"].([]interface{}) {
+			size, _ := integer(f.(map[string]interface{})["size"])
+			summary.Files++
+			summary.TotalSize += int64(size)
+		}
+	}
+	if len(values) > 0 {
+		summary.FilesPerAnswer = round(float64(summary.Files) / float64(len(values)))
+	}
+	return summary
+}
+
+// scale lists zero counts for the whole numbers from..to
+func scale(from, to int) []Count {
+	counts := make([]Count, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		counts = append(counts, Count{Value: n})
+	}
+	return counts
+}
+
+// round keeps two decimals
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,73 @@
+package kinds
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Upload limits of file questions. Questions set theirs in validation_rules
+// ("max_file_size" in bytes, "allowed_mime_types" like "image/*"); without
+// them files up to DefaultFileSize of any type are accepted.
+const (
+	DefaultFileSize = 10 << 20
+	MaxFileSize     = 25 << 20
+	MaxMIMETypes    = 50
+)
+
+// FileLimits is what one file uploaded to a file question may be
+type FileLimits struct {
+	MaxSize int64
+	Types   []string // media types or "type/*"; empty = any
+}
+
+// DecodeFileLimits reads a file question's limits from its validation
+// rules. Rules are free form, so anything unusable falls back to the
+// defaults.
+func DecodeFileLimits(rules map[string]interface{}) FileLimits {
+	limits, err := ParseFileLimits(rules)
+	if err != nil {
+		return FileLimits{MaxSize: DefaultFileSize}
+	}
+	return limits
+}
+
+// ParseFileLimits reads and checks a file question's limits
+func ParseFileLimits(rules map[string]interface{}) (FileLimits, error) {
+	limits := FileLimits{MaxSize: DefaultFileSize}
+	if raw, ok := rules["max_file_size"]; ok {
+		size, ok := integer(raw)
+		if !ok || size < 1 || size > MaxFileSize {
+			return limits, fmt.Errorf("%w: max_file_size must be 1-%d bytes", ErrInvalidSpec, MaxFileSize)
+		}
+		limits.MaxSize = int64(size)
+	}
+	if raw, ok := rules["allowed_mime_types"]; ok {
+		list, ok := raw.([]interface{})
+		if !ok || len(list) > MaxMIMETypes {
+			return limits, fmt.Errorf("%w: allowed_mime_types must list up to %d media types", ErrInvalidSpec, MaxMIMETypes)
+		}
+		for _, v := range list {
+			t, _ := v.(string)
+			t = strings.ToLower(strings.TrimSpace(t))
+			if _, _, err := mime.ParseMediaType(t); err != nil || !strings.Contains(t, "/") || strings.HasPrefix(t, "*") {
+				return limits, fmt.Errorf("%w: %q isn't a media type", ErrInvalidSpec, v)
+			}
+			limits.Types = append(limits.Types, t)
+		}
+	}
+	return limits, nil
+}
+
+// Allows reports whether a file of this media type may be uploaded
+func (l FileLimits) Allows(contentType string) bool {
+	if len(l.Types) == 0 {
+		return true
+	}
+	for _, t := range l.Types {
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
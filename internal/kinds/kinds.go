@@ -0,0 +1,459 @@
+package kinds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Question kinds. A question's input_type names its kind; the kind's
+// configuration is stored under "kind" in the question's metadata.
+const (
+	Matrix   = "matrix"   // Likert grid: rows rated on a shared column scale
+	Rating   = "rating"   // star rating, 1 to Stars
+	NPS      = "nps"      // Net Promoter Score, 0-10
+	Ranking  = "ranking"  // put every item in order
+	Slider   = "slider"   // a number between Min and Max, on Step
+	DateTime = "datetime" // a date, a time of day or both
+	YesNo    = "yes_no"   // yes or no
+	File     = "file"     // attached files
+)
+
+// Limits of a kind's configuration
+const (
+	MaxRows     = 50
+	MaxColumns  = 20
+	MaxStars    = 10
+	MaxItems    = 50
+	MaxFiles    = 20
+	MaxLabelLen = 200
+)
+
+// Formats of date/time answers and bounds, by mode
+var layouts = map[string]string{
+	"date":     "2006-01-02",
+	"time":     "15:04",
+	"datetime": "2006-01-02T15:04",
+}
+
+var (
+	ErrInvalidSpec   = errors.New("invalid question kind")
+	ErrInvalidAnswer = errors.New("answer doesn't fit the question kind")
+)
+
+// Spec is a question's kind and its configuration. Kinds share the struct;
+// each accepts only the fields listed in the registry.
+type Spec struct {
+	Type string `json:"type" yaml:"type"`
+	// Matrix: the statements rated (rows) and the scale (columns)
+	Rows    []string `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Columns []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+	// Rating: number of stars (default 5)
+	Stars int `json:"stars,omitempty" yaml:"stars,omitempty"`
+	// Ranking: the items to put in order
+	Items []string `json:"items,omitempty" yaml:"items,omitempty"`
+	// Slider: range (default 0-100) and step (default 1)
+	Min  *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Step float64  `json:"step,omitempty" yaml:"step,omitempty"`
+	// Date/time: date, time or datetime (default date) and optional bounds
+	// in the same format as answers
+	Mode     string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Earliest string `json:"earliest,omitempty" yaml:"earliest,omitempty"`
+	Latest   string `json:"latest,omitempty" yaml:"latest,omitempty"`
+	// File: how many files one answer may attach (default 1)
+	MaxFiles int `json:"max_files,omitempty" yaml:"max_files,omitempty"`
+	// Labels of the scale's ends (rating, NPS, slider) or of yes and no
+	MinLabel string `json:"min_label,omitempty" yaml:"min_label,omitempty"`
+	MaxLabel string `json:"max_label,omitempty" yaml:"max_label,omitempty"`
+}
+
+// kind is what the registry knows about one question kind
+type kind struct {
+	fields    []string // Spec fields the kind accepts, besides type
+	validate  func(s *Spec) error
+	check     func(s *Spec, value map[string]interface{}) error
+	aggregate func(s *Spec, values []map[string]interface{}) interface{}
+}
+
+var registry = map[string]kind{
+	Matrix:   {[]string{"rows", "columns"}, validateMatrix, checkMatrix, aggregateMatrix},
+	Rating:   {[]string{"stars", "min_label", "max_label"}, validateRating, checkRating, aggregateRating},
+	NPS:      {[]string{"min_label", "max_label"}, validateNone, checkNPS, aggregateNPS},
+	Ranking:  {[]string{"items"}, validateRanking, checkRanking, aggregateRanking},
+	Slider:   {[]string{"min", "max", "step", "min_label", "max_label"}, validateSlider, checkSlider, aggregateSlider},
+	DateTime: {[]string{"mode", "earliest", "latest"}, validateDateTime, checkDateTime, aggregateDateTime},
+	YesNo:    {[]string{"min_label", "max_label"}, validateNone, checkYesNo, aggregateYesNo},
+	File:     {[]string{"max_files"}, validateFile, checkFile, aggregateFile},
+}
+
+// Names returns the registered kinds, sorted
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registered reports whether name is a question kind
+func Registered(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
+
+// Decode reads the "kind" entry of a question's metadata. Metadata is free
+// form (questions can be edited directly), so anything that isn't a valid
+// spec counts as none.
+func Decode(raw []byte) *Spec {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s Spec
+	if err := json.Unmarshal(raw, &s); err != nil || s.Validate() != nil {
+		return nil
+	}
+	return &s
+}
+
+// Validate checks that the kind is registered, that the spec sets only the
+// kind's fields and that they're in range
+func (s *Spec) Validate() error {
+	k, ok := registry[s.Type]
+	if !ok {
+		return fmt.Errorf("%w: unknown kind %q (one of %s)", ErrInvalidSpec, s.Type, strings.Join(Names(), ", "))
+	}
+	allowed := make(map[string]bool, len(k.fields))
+	for _, f := range k.fields {
+		allowed[f] = true
+	}
+	for _, f := range s.set() {
+		if !allowed[f] {
+			return fmt.Errorf("%w: %s doesn't take %s", ErrInvalidSpec, s.Type, f)
+		}
+	}
+	if len(s.MinLabel) > MaxLabelLen || len(s.MaxLabel) > MaxLabelLen {
+		return fmt.Errorf("%w: labels are limited to %d characters", ErrInvalidSpec, MaxLabelLen)
+	}
+	return k.validate(s)
+}
+
+// set lists the fields the spec sets, besides type
+func (s *Spec) set() []string {
+	var fields []string
+	add := func(name string, set bool) {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+	add("rows", len(s.Rows) > 0)
+	add("columns", len(s.Columns) > 0)
+	add("stars", s.Stars != 0)
+	add("items", len(s.Items) > 0)
+	add("min", s.Min != nil)
+	add("max", s.Max != nil)
+	add("step", s.Step != 0)
+	add("mode", s.Mode != "")
+	add("earliest", s.Earliest != "")
+	add("latest", s.Latest != "")
+	add("max_files", s.MaxFiles != 0)
+	add("min_label", s.MinLabel != "")
+	add("max_label", s.MaxLabel != "")
+	return fields
+}
+
+// Check reports whether an answer's value has the kind's shape and fits
+// its configuration
+func (s *Spec) Check(value map[string]interface{}) error {
+	if value == nil {
+		return fmt.Errorf("%w: %s answers need an answer_value", ErrInvalidAnswer, s.Type)
+	}
+	return registry[s.Type].check(s, value)
+}
+
+// Aggregate summarizes the values of a question's answers; values that
+// don't fit the spec are skipped
+func (s *Spec) Aggregate(values []map[string]interface{}) interface{} {
+	valid := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		if s.Check(v) == nil {
+			valid = append(valid, v)
+		}
+	}
+	return registry[s.Type].aggregate(s, valid)
+}
+
+/*
+========================
+ CONFIGURATION
+========================
+*/
+
+func validateNone(*Spec) error { return nil }
+
+func validateMatrix(s *Spec) error {
+	if err := labels("rows", s.Rows, 1, MaxRows); err != nil {
+		return err
+	}
+	return labels("columns", s.Columns, 2, MaxColumns)
+}
+
+func validateRating(s *Spec) error {
+	if s.Stars != 0 && (s.Stars < 2 || s.Stars > MaxStars) {
+		return fmt.Errorf("%w: stars must be 2-%d", ErrInvalidSpec, MaxStars)
+	}
+	return nil
+}
+
+func validateRanking(s *Spec) error {
+	return labels("items", s.Items, 2, MaxItems)
+}
+
+func validateSlider(s *Spec) error {
+	min, max, step := s.slider()
+	if math.IsInf(min, 0) || math.IsInf(max, 0) || min >= max {
+		return fmt.Errorf("%w: slider min must be below max", ErrInvalidSpec)
+	}
+	if step <= 0 || step > max-min {
+		return fmt.Errorf("%w: slider step must be positive and fit the range", ErrInvalidSpec)
+	}
+	return nil
+}
+
+func validateDateTime(s *Spec) error {
+	layout, ok := layouts[s.mode()]
+	if !ok {
+		return fmt.Errorf("%w: mode must be date, time or datetime", ErrInvalidSpec)
+	}
+	var earliest, latest time.Time
+	var err error
+	if s.Earliest != "" {
+		if earliest, err = time.Parse(layout, s.Earliest); err != nil {
+			return fmt.Errorf("%w: earliest must look like %s", ErrInvalidSpec, layout)
+		}
+	}
+	if s.Latest != "" {
+		if latest, err = time.Parse(layout, s.Latest); err != nil {
+			return fmt.Errorf("%w: latest must look like %s", ErrInvalidSpec, layout)
+		}
+	}
+	if s.Earliest != "" && s.Latest != "" && latest.Before(earliest) {
+		return fmt.Errorf("%w: latest is before earliest", ErrInvalidSpec)
+	}
+	return nil
+}
+
+func validateFile(s *Spec) error {
+	if s.MaxFiles < 0 || s.MaxFiles > MaxFiles {
+		return fmt.Errorf("%w: max_files must be 1-%d", ErrInvalidSpec, MaxFiles)
+	}
+	return nil
+}
+
+// labels checks a list of distinct, non-empty labels
+func labels(field string, list []string, min, max int) error {
+	if len(list) < min || len(list) > max {
+		return fmt.Errorf("%w: %s needs %d-%d entries", ErrInvalidSpec, field, min, max)
+	}
+	seen := make(map[string]bool, len(list))
+	for _, l := range list {
+		l = strings.TrimSpace(l)
+		if l == "" || len(l) > MaxLabelLen || seen[l] {
+			return fmt.Errorf("%w: %s must be distinct and 1-%d characters", ErrInvalidSpec, field, MaxLabelLen)
+		}
+		seen[l] = true
+	}
+	return nil
+}
+
+// stars is the rating's scale, 5 unless set
+func (s *Spec) stars() int {
+	if s.Stars == 0 {
+		return 5
+	}
+	return s.Stars
+}
+
+// slider is the slider's range and step, 0-100 by 1 unless set
+func (s *Spec) slider() (min, max, step float64) {
+	min, max, step = 0, 100, 1
+	if s.Min != nil {
+		min = *s.Min
+	}
+	if s.Max != nil {
+		max = *s.Max
+	}
+	if s.Step != 0 {
+		step = s.Step
+	}
+	return min, max, step
+}
+
+// mode is what a date/time question asks, date unless set
+func (s *Spec) mode() string {
+	if s.Mode == "" {
+		return "date"
+	}
+	return s.Mode
+}
+
+// maxFiles is how many files an answer may attach, 1 unless set
+func (s *Spec) maxFiles() int {
+	if s.MaxFiles == 0 {
+		return 1
+	}
+	return s.MaxFiles
+}
+
+/*
+========================
+ ANSWERS
+========================
+*/
+
+// Answer shapes, by kind:
+//
+//	matrix    {"rows": {"<row>": "<column>", ...}}   at least one row
+//	rating    {"rating": 1..stars}
+//	nps       {"score": 0..10}
+//	ranking   {"ranking": ["<item>", ...]}          every item once
+//	slider    {"value": min..max on step}
+//	datetime  {"value": "2006-01-02" | "15:04" | "2006-01-02T15:04"}
+//	yes_no    {"value": true | false}
+//	file      {"files": [{"id", "name", "size", "content_type"}, ...]}
+
+func checkMatrix(s *Spec, value map[string]interface{}) error {
+	rows, ok := value["rows"].(map[string]interface{})
+	if !ok || len(rows) == 0 {
+		return invalid(s, `"rows" must map rows to columns`)
+	}
+	for row, col := range rows {
+		c, ok := col.(string)
+		if !contains(s.Rows, row) || !ok || !contains(s.Columns, c) {
+			return invalid(s, "unknown row or column")
+		}
+	}
+	return nil
+}
+
+func checkRating(s *Spec, value map[string]interface{}) error {
+	if n, ok := integer(value["rating"]); !ok || n < 1 || n > s.stars() {
+		return invalid(s, fmt.Sprintf(`"rating" must be 1-%d`, s.stars()))
+	}
+	return nil
+}
+
+func checkNPS(s *Spec, value map[string]interface{}) error {
+	if n, ok := integer(value["score"]); !ok || n < 0 || n > 10 {
+		return invalid(s, `"score" must be 0-10`)
+	}
+	return nil
+}
+
+func checkRanking(s *Spec, value map[string]interface{}) error {
+	ranking, ok := value["ranking"].([]interface{})
+	if !ok || len(ranking) != len(s.Items) {
+		return invalid(s, `"ranking" must list every item`)
+	}
+	seen := make(map[string]bool, len(ranking))
+	for _, v := range ranking {
+		item, ok := v.(string)
+		if !ok || !contains(s.Items, item) || seen[item] {
+			return invalid(s, `"ranking" must list every item once`)
+		}
+		seen[item] = true
+	}
+	return nil
+}
+
+func checkSlider(s *Spec, value map[string]interface{}) error {
+	min, max, step := s.slider()
+	v, ok := value["value"].(float64)
+	if !ok || v < min || v > max {
+		return invalid(s, fmt.Sprintf(`"value" must be %g-%g`, min, max))
+	}
+	steps := (v - min) / step
+	if math.Abs(steps-math.Round(steps)) > 1e-9 {
+		return invalid(s, fmt.Sprintf(`"value" must be on steps of %g`, step))
+	}
+	return nil
+}
+
+func checkDateTime(s *Spec, value map[string]interface{}) error {
+	layout := layouts[s.mode()]
+	raw, _ := value["value"].(string)
+	t, err := time.Parse(layout, raw)
+	if err != nil {
+		return invalid(s, `"value" must look like `+layout)
+	}
+	if s.Earliest != "" {
+		if earliest, _ := time.Parse(layout, s.Earliest); t.Before(earliest) {
+			return invalid(s, `"value" is before `+s.Earliest)
+		}
+	}
+	if s.Latest != "" {
+		if latest, _ := time.Parse(layout, s.Latest); t.After(latest) {
+			return invalid(s, `"value" is after `+s.Latest)
+		}
+	}
+	return nil
+}
+
+func checkYesNo(s *Spec, value map[string]interface{}) error {
+	if _, ok := value["value"].(bool); !ok {
+		return invalid(s, `"value" must be true or false`)
+	}
+	return nil
+}
+
+func checkFile(s *Spec, value map[string]interface{}) error {
+	files, ok := value["files"].([]interface{})
+	if !ok || len(files) == 0 || len(files) > s.maxFiles() {
+		return invalid(s, fmt.Sprintf(`"files" must list 1-%d files`, s.maxFiles()))
+	}
+	for _, f := range files {
+		file, ok := f.(map[string]interface{})
+		if !ok {
+			return invalid(s, "files must be objects")
+		}
+		id, _ := file["id"].(string)
+		name, _ := file["name"].(string)
+		size, ok := integer(file["size"])
+		if strings.TrimSpace(id) == "" || strings.TrimSpace(name) == "" || !ok || size < 0 {
+			return invalid(s, "files need an id, a name and a size")
+		}
+		if ct, present := file["content_type"]; present {
+			if _, ok := ct.(string); !ok {
+				return invalid(s, "content_type must be a string")
+			}
+		}
+	}
+	return nil
+}
+
+func invalid(s *Spec, msg string) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidAnswer, s.Type, msg)
+}
+
+func contains(list []string, v string) bool {
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+// integer reads a whole JSON number
+func integer(v interface{}) (int, bool) {
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, false
+	}
+	return int(f), true
+}
@@ -5,15 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"smart-forms/internal/collaborators"
+	"smart-forms/internal/versions"
+
 	"github.com/gofiber/fiber/v2"
 )
 
+// respondentCookie keeps a public respondent's A/B test assignment for a year
+const (
+	respondentCookie       = "sf_respondent"
+	respondentCookieMaxAge = 365 * 24 * 60 * 60
+)
+
 type LinksHandler struct {
 	service *LinksService
+	access  *collaborators.AccessChecker
 }
 
-func NewLinksHandler(service *LinksService) *LinksHandler {
-	return &LinksHandler{service: service}
+func NewLinksHandler(service *LinksService, access *collaborators.AccessChecker) *LinksHandler {
+	return &LinksHandler{
+		service: service,
+		access:  access,
+	}
 }
 
 // PublishForm handles publishing a form
@@ -22,6 +35,10 @@ func (h *LinksHandler) PublishForm(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	var req PublishRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
@@ -34,6 +51,9 @@ func (h *LinksHandler) PublishForm(c *fiber.Ctx) error {
 
 	autoSlug, customSlugResult, err := h.service.PublishForm(c.Context(), formID, userID, customSlug)
 	if err != nil {
+		if ok, resp := versions.LintFailed(c, err); ok {
+			return resp
+		}
 		return mapServiceError(err)
 	}
 
@@ -60,6 +80,10 @@ func (h *LinksHandler) ToggleAcceptingResponses(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
 
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
 	var req ToggleRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
@@ -77,15 +101,33 @@ func (h *LinksHandler) ToggleAcceptingResponses(c *fiber.Ctx) error {
 }
 
 // GetPublicForm handles getting a public form by slug
-// GET /f/:slug
+// GET /f/:slug?seed=&respondent_key=
 func (h *LinksHandler) GetPublicForm(c *fiber.Ctx) error {
 	slug := c.Params("slug")
 
-	form, err := h.service.GetPublicForm(c.Context(), slug)
+	// A/B test assignment sticks to the respondent key, from the client or
+	// the cookie set on their first visit
+	respondentKey := c.Query("respondent_key")
+	if respondentKey == "" {
+		respondentKey = c.Cookies(respondentCookie)
+	}
+
+	form, err := h.service.GetPublicForm(c.Context(), slug, c.Query("seed"), respondentKey)
 	if err != nil {
 		return fiber.ErrNotFound
 	}
 
+	if form.RespondentKey != "" {
+		c.Cookie(&fiber.Cookie{
+			Name:     respondentCookie,
+			Value:    form.RespondentKey,
+			Path:     "/f",
+			MaxAge:   respondentCookieMaxAge,
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+	}
+
 	// Generate ETag based on form content
 	etag := generateETag(form)
 
@@ -101,7 +143,12 @@ func (h *LinksHandler) GetPublicForm(c *fiber.Ctx) error {
 	// no-cache: Browser must revalidate with server (but can use ETag for 304)
 	// s-maxage=600: CDN (Vercel) caches for 10 minutes
 	// This ensures form updates are immediately visible while still benefiting from CDN
-	c.Set("Cache-Control", "no-cache, s-maxage=600")
+	// Forms arranged or assigned for one respondent stay out of shared caches
+	if form.Personalized() {
+		c.Set("Cache-Control", "private, no-cache")
+	} else {
+		c.Set("Cache-Control", "no-cache, s-maxage=600")
+	}
 	c.Set("ETag", etag)
 
 	return c.JSON(form)
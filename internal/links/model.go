@@ -1,12 +1,38 @@
 package links
 
+import "smart-forms/internal/versions"
+
 // PublicForm represents the public view of a form
 type PublicForm struct {
 	ID                 string                 `json:"id"`
+	VersionID          string                 `json:"version_id,omitempty"` // published version the flow belongs to
 	Title              string                 `json:"title"`
 	Description        string                 `json:"description"`
 	AcceptingResponses bool                   `json:"accepting_responses"`
 	Flow               map[string]interface{} `json:"flow"`
+	// Seed arranges randomized blocks for one respondent; send it back with
+	// the response (empty if the flow randomizes nothing)
+	Seed string `json:"seed,omitempty"`
+	// Variant is the A/B test variant served, assigned by RespondentKey;
+	// send the key back to keep the assignment (both empty without a test)
+	Variant       string `json:"variant,omitempty"`
+	RespondentKey string `json:"respondent_key,omitempty"`
+
+	randomized bool
+	variants   []versions.Variant
+	flows      map[string]variantFlow // by variant key
+}
+
+// variantFlow is what a variant serves in place of the published flow
+type variantFlow struct {
+	flow       map[string]interface{}
+	randomized bool
+}
+
+// Personalized reports whether the form was arranged or assigned for one
+// respondent (and mustn't be cached for others)
+func (f *PublicForm) Personalized() bool {
+	return f.Seed != "" || f.Variant != ""
 }
 
 // PublishRequest represents the request to publish a form
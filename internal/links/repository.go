@@ -14,7 +14,8 @@ func NewLinksRepository(db *pgxpool.Pool) *LinksRepository {
 	return &LinksRepository{db: db}
 }
 
-// PublishForm updates form to published status with slugs
+// PublishForm updates form to published status with slugs.
+// Reachable by workspace members and collaborators; roles are checked by the handler.
 func (r *LinksRepository) PublishForm(ctx context.Context, formID, userID, autoSlug string, customSlug *string) error {
 	result, err := r.db.Exec(ctx, `
 		UPDATE forms
@@ -24,7 +25,9 @@ func (r *LinksRepository) PublishForm(ctx context.Context, formID, userID, autoS
 		    accepting_responses = true,
 		    published_at = NOW(),
 		    updated_at = NOW()
-		WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+		WHERE id = $3
+		  AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $4) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $4))
+		  AND deleted_at IS NULL
 	`, autoSlug, customSlug, formID, userID)
 	if err != nil {
 		return err
@@ -44,7 +47,9 @@ func (r *LinksRepository) ToggleAcceptingResponses(ctx context.Context, formID,
 		UPDATE forms
 		SET accepting_responses = $1,
 		    updated_at = NOW()
-		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+		WHERE id = $2
+		  AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $3) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $3))
+		  AND deleted_at IS NULL
 	`, accepting, formID, userID)
 	if err != nil {
 		return err
@@ -76,47 +81,6 @@ func (r *LinksRepository) GetFormBySlug(ctx context.Context, slug string) (strin
 	return formID, title, description, acceptingResponses, nil
 }
 
-// GetFlowForPublicForm retrieves flow structure for a form
-func (r *LinksRepository) GetFlowForPublicForm(ctx context.Context, formID string) ([]map[string]interface{}, error) {
-	rows, err := r.db.Query(ctx, `
-		SELECT
-			fc.id,
-			fc.parent_id,
-			fc.order_index,
-			q.type,
-			q.question_text
-		FROM flow_connections fc
-		JOIN questions q ON fc.question_id = q.id
-		WHERE fc.form_id = $1 AND fc.deleted_at IS NULL
-		ORDER BY fc.depth_level, fc.order_index
-	`, formID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var items []map[string]interface{}
-	for rows.Next() {
-		var id, qType, questionText string
-		var parentID *string
-		var orderIndex int
-
-		err := rows.Scan(&id, &parentID, &orderIndex, &qType, &questionText)
-		if err != nil {
-			continue
-		}
-
-		items = append(items, map[string]interface{}{
-			"id":          id,
-			"parent_id":   parentID,
-			"type":        qType,
-			"question":    questionText,
-			"order_index": orderIndex,
-		})
-	}
-	return items, nil
-}
-
 // CheckSlugExists checks if a slug (auto or custom) is already taken
 func (r *LinksRepository) CheckSlugExists(ctx context.Context, slug string) (bool, error) {
 	var exists bool
@@ -137,7 +101,9 @@ func (r *LinksRepository) GetFormSlugs(ctx context.Context, formID, userID strin
 	err := r.db.QueryRow(ctx, `
 		SELECT auto_slug, custom_slug
 		FROM forms
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		WHERE id = $1
+		  AND (workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2) OR id IN (SELECT form_id FROM form_collaborators WHERE user_id = $2))
+		  AND deleted_at IS NULL
 	`, formID, userID).Scan(&autoSlug, &customSlug)
 	return autoSlug, customSlug, err
 }
@@ -9,17 +9,22 @@ import (
 	"time"
 
 	"smart-forms/internal/cache"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+	"smart-forms/internal/versions"
 )
 
 type LinksService struct {
-	repo  *LinksRepository
-	cache *cache.Cache
+	repo     *LinksRepository
+	cache    *cache.Cache
+	versions *versions.VersionsService
 }
 
-func NewLinksService(repo *LinksRepository, cacheClient *cache.Cache) *LinksService {
+func NewLinksService(repo *LinksRepository, cacheClient *cache.Cache, versionService *versions.VersionsService) *LinksService {
 	return &LinksService{
-		repo:  repo,
-		cache: cacheClient,
+		repo:     repo,
+		cache:    cacheClient,
+		versions: versionService,
 	}
 }
 
@@ -52,6 +57,14 @@ func (s *LinksService) PublishForm(ctx context.Context, formID, userID string, c
 	// Get old slugs before publishing (for cache invalidation)
 	oldAutoSlug, oldCustomSlug, _ := s.repo.GetFormSlugs(ctx, formID, userID)
 
+	// Freeze the draft as the live version (nothing to do if it's already live)
+	if _, err := s.versions.PublishDraft(ctx, formID, userID); err != nil && err != versions.ErrNoDraft {
+		if err == versions.ErrFormNotFound {
+			return "", nil, ErrFormNotFound
+		}
+		return "", nil, err
+	}
+
 	// Publish the form
 	err := s.repo.PublishForm(ctx, formID, userID, autoSlug, customSlug)
 	if err != nil {
@@ -105,8 +118,20 @@ func (s *LinksService) ToggleAcceptingResponses(ctx context.Context, formID, use
 	return nil
 }
 
-// GetPublicForm retrieves a form by slug for public view
-func (s *LinksService) GetPublicForm(ctx context.Context, slug string) (*PublicForm, error) {
+// GetPublicForm retrieves a form by slug for public view. Forms running an
+// A/B test serve the variant the respondent key is assigned (a new key if
+// it's empty or invalid); randomized blocks are arranged with the given
+// seed (likewise).
+func (s *LinksService) GetPublicForm(ctx context.Context, slug, seed, respondentKey string) (*PublicForm, error) {
+	form, err := s.getPublicForm(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return form.assign(respondentKey).arrange(seed), nil
+}
+
+// getPublicForm returns the form as cached, before arranging
+func (s *LinksService) getPublicForm(ctx context.Context, slug string) (*PublicForm, error) {
 	// Generate cache key
 	cacheKey := cache.FormSlugKey(slug)
 
@@ -123,23 +148,51 @@ func (s *LinksService) GetPublicForm(ctx context.Context, slug string) (*PublicF
 		return nil, err
 	}
 
-	// Get flow structure
-	items, err := s.repo.GetFlowForPublicForm(ctx, formID)
-	if err != nil {
+	// Serve the frozen snapshot of the published version
+	versionID, blocks, vars, err := s.versions.PublishedFlow(ctx, formID)
+	if err != nil && err != versions.ErrNotFound {
 		return nil, err
 	}
-
-	// Build tree structure
-	blocks := s.buildTree(items, nil)
+	if blocks == nil {
+		blocks = []map[string]interface{}{}
+	}
+	if vars == nil {
+		vars = []variables.Variable{}
+	}
 
 	form := &PublicForm{
 		ID:                 formID,
+		VersionID:          versionID,
 		Title:              title,
 		Description:        description,
 		AcceptingResponses: acceptingResponses,
 		Flow: map[string]interface{}{
-			"blocks": blocks,
+			"blocks":    blocks,
+			"variables": vars,
 		},
+		randomized: versions.Randomized(blocks),
+	}
+
+	// A/B test variants serve their own versions
+	form.variants, err = s.versions.ListVariants(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	if len(form.variants) > 0 {
+		form.flows = make(map[string]variantFlow, len(form.variants))
+	}
+	for _, v := range form.variants {
+		blocks, vars, err := s.versions.VersionFlow(ctx, v.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		form.flows[v.Key] = variantFlow{
+			flow: map[string]interface{}{
+				"blocks":    blocks,
+				"variables": vars,
+			},
+			randomized: versions.Randomized(blocks),
+		}
 	}
 
 	// Store in cache with 5 minute TTL
@@ -152,30 +205,48 @@ func (s *LinksService) GetPublicForm(ctx context.Context, slug string) (*PublicF
 	return form, nil
 }
 
-// buildTree recursively builds nested block structure
-func (s *LinksService) buildTree(items []map[string]interface{}, parentID *string) []map[string]interface{} {
-	var result []map[string]interface{}
-
-	for _, item := range items {
-		itemParentID := item["parent_id"].(*string)
-
-		// Check if this item belongs to current parent
-		if (parentID == nil && itemParentID == nil) ||
-			(parentID != nil && itemParentID != nil && *parentID == *itemParentID) {
+// assign returns a respondent's copy of a form running an A/B test, serving
+// the variant their key is assigned; other forms are returned as they are
+func (f *PublicForm) assign(respondentKey string) *PublicForm {
+	if len(f.variants) == 0 {
+		return f
+	}
+	if !versions.ValidRespondentKey(respondentKey) {
+		respondentKey = randomize.NewSeed()
+	}
 
-			id := item["id"].(string)
-			block := map[string]interface{}{
-				"id":       id,
-				"type":     item["type"],
-				"question": item["question"],
-				"children": s.buildTree(items, &id),
-			}
+	v := versions.AssignVariant(f.variants, f.ID, respondentKey)
+	if v == nil {
+		return f
+	}
+	form := *f
+	form.VersionID = v.VersionID
+	form.Flow = f.flows[v.Key].flow
+	form.randomized = f.flows[v.Key].randomized
+	form.Variant = v.Key
+	form.RespondentKey = respondentKey
+	return &form
+}
 
-			result = append(result, block)
-		}
+// arrange returns a respondent's copy of a form with randomized blocks
+// arranged by seed; forms without randomization are returned as they are
+func (f *PublicForm) arrange(seed string) *PublicForm {
+	if !f.randomized {
+		return f
+	}
+	if !randomize.ValidSeed(seed) {
+		seed = randomize.NewSeed()
 	}
 
-	return result
+	form := *f
+	form.Seed = seed
+	form.Flow = make(map[string]interface{}, len(f.Flow))
+	for k, v := range f.Flow {
+		form.Flow[k] = v
+	}
+	blocks, _ := f.Flow["blocks"].([]map[string]interface{})
+	form.Flow["blocks"] = versions.ArrangeBlocks(blocks, seed)
+	return &form
 }
 
 // GetFormSlugs retrieves the slugs for a form
@@ -25,7 +25,7 @@ func (h *PlansHandler) ListActivePlans(c *fiber.Ctx) error {
 	})
 }
 
-// ListAllPlans retrieves all plans including inactive (super admin only)
+// ListAllPlans retrieves all plans including inactive (plans:write)
 // GET /admin/plans
 func (h *PlansHandler) ListAllPlans(c *fiber.Ctx) error {
 	plans, err := h.service.ListPlans(c.Context(), false)
@@ -51,7 +51,7 @@ func (h *PlansHandler) GetPlan(c *fiber.Ctx) error {
 	return c.JSON(plan)
 }
 
-// CreatePlan creates a new plan (super admin only)
+// CreatePlan creates a new plan (plans:write)
 // POST /admin/plans
 func (h *PlansHandler) CreatePlan(c *fiber.Ctx) error {
 	var req CreatePlanRequest
@@ -67,7 +67,7 @@ func (h *PlansHandler) CreatePlan(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(plan)
 }
 
-// UpdatePlan updates a plan (super admin only)
+// UpdatePlan updates a plan (plans:write)
 // PATCH /admin/plans/:id
 func (h *PlansHandler) UpdatePlan(c *fiber.Ctx) error {
 	planID := c.Params("id")
@@ -85,7 +85,7 @@ func (h *PlansHandler) UpdatePlan(c *fiber.Ctx) error {
 	return c.JSON(plan)
 }
 
-// DeletePlan soft deletes a plan (super admin only)
+// DeletePlan soft deletes a plan (plans:write)
 // DELETE /admin/plans/:id
 func (h *PlansHandler) DeletePlan(c *fiber.Ctx) error {
 	planID := c.Params("id")
@@ -6,4 +6,5 @@ var (
 	ErrInvalidInput = errors.New("invalid input")
 	ErrNotFound     = errors.New("question not found")
 	ErrInvalidType  = errors.New("invalid question type")
+	ErrInvalidKind  = errors.New("invalid question kind")
 )
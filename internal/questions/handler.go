@@ -1,8 +1,11 @@
 package questions
 
 import (
+	"errors"
 	"strconv"
 
+	"smart-forms/internal/kinds"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -70,6 +73,12 @@ func (h *QuestionHandler) List(c *fiber.Ctx) error {
 	})
 }
 
+// Kinds lists the question kinds an input_type can name
+// GET /questions/kinds
+func (h *QuestionHandler) Kinds(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"kinds": h.service.Kinds()})
+}
+
 func (h *QuestionHandler) GetByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 
@@ -125,8 +134,11 @@ func (h *QuestionHandler) Delete(c *fiber.Ctx) error {
 }
 
 func mapServiceError(err error) error {
+	if errors.Is(err, kinds.ErrInvalidSpec) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 	switch err {
-	case ErrInvalidInput, ErrInvalidType:
+	case ErrInvalidInput, ErrInvalidType, ErrInvalidKind:
 		return fiber.ErrBadRequest
 	case ErrNotFound:
 		return fiber.ErrNotFound
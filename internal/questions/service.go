@@ -2,12 +2,16 @@ package questions
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
+
+	"smart-forms/internal/kinds"
 )
 
 const (
 	TypeQuestion = "question"
 	TypeOption   = "option"
+	TypeInput    = "input"
 )
 
 type QuestionService struct {
@@ -35,6 +39,11 @@ func (s *QuestionService) Create(ctx context.Context, userID, qType, text, input
 		metadata = make(map[string]any)
 	}
 
+	inputType, err := resolveKind(qType, inputType, validationRules, metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.repo.Create(ctx, userID, qType, text, inputType, validationRules, metadata)
 }
 
@@ -70,6 +79,11 @@ func (s *QuestionService) Update(ctx context.Context, id, qType, text, inputType
 		metadata = make(map[string]any)
 	}
 
+	inputType, err := resolveKind(qType, inputType, validationRules, metadata)
+	if err != nil {
+		return err
+	}
+
 	return s.repo.Update(ctx, id, qType, text, inputType, validationRules, metadata)
 }
 
@@ -77,6 +91,54 @@ func (s *QuestionService) Delete(ctx context.Context, id string) error {
 	return s.repo.Delete(ctx, id)
 }
 
+// isValidType reports whether questions of this type can be created here;
+// fragment questions only come from flows
 func isValidType(qType string) bool {
-	return qType == TypeQuestion || qType == TypeOption
+	return qType == TypeQuestion || qType == TypeOption || qType == TypeInput
+}
+
+// Kinds returns the registered question kinds
+func (s *QuestionService) Kinds() []string {
+	return kinds.Names()
+}
+
+// resolveKind checks a question's kind. A registered input_type or a
+// "kind" entry in metadata makes the question that kind; the entry is
+// validated and stored completed with its type, and the input_type follows
+// it. Other input types are kept as they are.
+func resolveKind(qType, inputType string, validationRules, metadata map[string]any) (string, error) {
+	raw, ok := metadata["kind"]
+	if !ok && !kinds.Registered(inputType) {
+		return inputType, nil
+	}
+	if qType == TypeOption {
+		return "", ErrInvalidKind
+	}
+
+	var spec kinds.Spec
+	if ok {
+		data, err := json.Marshal(raw)
+		if err != nil || json.Unmarshal(data, &spec) != nil {
+			return "", ErrInvalidKind
+		}
+	}
+	if spec.Type == "" {
+		spec.Type = inputType
+	}
+	if inputType != "" && inputType != spec.Type {
+		return "", ErrInvalidKind
+	}
+	if err := spec.Validate(); err != nil {
+		return "", err
+	}
+
+	// File questions take their upload limits from validation_rules
+	if spec.Type == kinds.File {
+		if _, err := kinds.ParseFileLimits(validationRules); err != nil {
+			return "", err
+		}
+	}
+
+	metadata["kind"] = spec
+	return spec.Type, nil
 }
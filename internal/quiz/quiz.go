@@ -0,0 +1,191 @@
+package quiz
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+)
+
+// MaxPoints bounds the points of one option
+const MaxPoints = 1000
+
+// MaxFeedbackLength bounds a feedback text
+const MaxFeedbackLength = 2000
+
+var ErrInvalidSettings = errors.New("invalid quiz settings")
+
+// Settings is a form's quiz mode. Forms without one aren't scored.
+type Settings struct {
+	Enabled bool `json:"enabled"`
+	// PassPercent is the score (0-100) needed to pass; nil = no pass/fail
+	PassPercent *float64 `json:"pass_percent,omitempty"`
+	// ShowFeedback returns per-question results and feedback to the
+	// respondent; otherwise they only see their score and outcome
+	ShowFeedback bool `json:"show_feedback"`
+}
+
+// Validate checks the settings' ranges
+func (s *Settings) Validate() error {
+	if s.PassPercent != nil && !percent(*s.PassPercent) {
+		return ErrInvalidSettings
+	}
+	return nil
+}
+
+// Scoring is the quiz data of a block, stored under "quiz" in its question's
+// metadata. On options: whether the answer is correct, the points it earns
+// and its feedback. On a question: the feedback shown whatever was chosen,
+// or Route to pick its options by score band (each option's MinPercent).
+type Scoring struct {
+	Correct    bool     `json:"correct,omitempty" yaml:"correct,omitempty"`
+	Points     *float64 `json:"points,omitempty" yaml:"points,omitempty"`
+	Feedback   string   `json:"feedback,omitempty" yaml:"feedback,omitempty"`
+	Route      bool     `json:"route,omitempty" yaml:"route,omitempty"`
+	MinPercent *float64 `json:"min_percent,omitempty" yaml:"min_percent,omitempty"`
+}
+
+// Decode reads the "quiz" entry of a question's metadata. Metadata is free
+// form (questions can be edited directly), so anything that isn't valid
+// scoring counts as none.
+func Decode(raw []byte) *Scoring {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s Scoring
+	if err := json.Unmarshal(raw, &s); err != nil || s.IsZero() {
+		return nil
+	}
+	return &s
+}
+
+// IsZero reports whether the scoring carries nothing worth storing
+func (s *Scoring) IsZero() bool {
+	return s == nil || (!s.Correct && s.Points == nil && strings.TrimSpace(s.Feedback) == "" &&
+		!s.Route && s.MinPercent == nil)
+}
+
+// Scored reports whether an option takes part in scoring
+func (s *Scoring) Scored() bool {
+	return s != nil && (s.Correct || s.Points != nil)
+}
+
+// Earned is what choosing an option earns: its points, else 1 when correct
+func (s *Scoring) Earned() float64 {
+	if s == nil {
+		return 0
+	}
+	if s.Points != nil {
+		return *s.Points
+	}
+	if s.Correct {
+		return 1
+	}
+	return 0
+}
+
+// Check returns what is wrong with a block's scoring, or "" when it is fine
+func (s *Scoring) Check() string {
+	if s == nil {
+		return ""
+	}
+	if s.Points != nil && (math.IsNaN(*s.Points) || math.Abs(*s.Points) > MaxPoints) {
+		return "points must be between -1000 and 1000"
+	}
+	if s.MinPercent != nil && !percent(*s.MinPercent) {
+		return "min_percent must be between 0 and 100"
+	}
+	if len(s.Feedback) > MaxFeedbackLength {
+		return "feedback is too long"
+	}
+	return ""
+}
+
+// Public strips what would give answers away: the correct flags, points and
+// feedback. Score routing stays, so clients can follow it.
+func (s *Scoring) Public() *Scoring {
+	if s == nil || (!s.Route && s.MinPercent == nil) {
+		return nil
+	}
+	return &Scoring{Route: s.Route, MinPercent: s.MinPercent}
+}
+
+// Band is one option of a routing question
+type Band struct {
+	ID         string
+	MinPercent float64
+}
+
+// Pick returns the band a percentage falls in: the highest minimum it
+// reaches, or "" when it reaches none
+func Pick(bands []Band, percent float64) string {
+	best, found := "", false
+	var bestMin float64
+	for _, b := range bands {
+		if percent >= b.MinPercent && (!found || b.MinPercent > bestMin) {
+			best, bestMin, found = b.ID, b.MinPercent, true
+		}
+	}
+	return best
+}
+
+// Result is a respondent's score
+type Result struct {
+	Score    float64 `json:"score"`
+	MaxScore float64 `json:"max_score"`
+	Percent  float64 `json:"percent"`
+	// Passed is set when the form has a pass threshold
+	Passed *bool `json:"passed,omitempty"`
+	// Outcome is the score band reached: the routing question's option
+	// (node ID and text) chosen for the score
+	OutcomeID string `json:"outcome_id,omitempty"`
+	Outcome   string `json:"outcome,omitempty"`
+	// Routes maps each routing question to the option chosen for the score
+	Routes    map[string]string `json:"routes,omitempty"`
+	Questions []QuestionResult  `json:"questions,omitempty"`
+}
+
+// QuestionResult is the score of one question the respondent reached
+type QuestionResult struct {
+	NodeID    string  `json:"node_id"`
+	OptionID  string  `json:"option_id,omitempty"` // chosen option, empty if unanswered
+	Correct   bool    `json:"correct"`
+	Points    float64 `json:"points"`
+	MaxPoints float64 `json:"max_points"`
+	Feedback  string  `json:"feedback,omitempty"`
+}
+
+// Finish computes the totals from the question results and applies the
+// pass threshold
+func (r *Result) Finish(settings *Settings) {
+	r.Score, r.MaxScore = 0, 0
+	for _, q := range r.Questions {
+		r.Score += q.Points
+		r.MaxScore += q.MaxPoints
+	}
+	r.Percent = 0
+	if r.MaxScore > 0 {
+		r.Percent = math.Round(r.Score/r.MaxScore*10000) / 100
+	}
+	if settings != nil && settings.PassPercent != nil {
+		passed := r.Percent >= *settings.PassPercent
+		r.Passed = &passed
+	}
+}
+
+// ForRespondent is what a respondent sees of their result: everything with
+// ShowFeedback, otherwise the totals and outcome only
+func (r *Result) ForRespondent(settings *Settings) *Result {
+	if r == nil {
+		return nil
+	}
+	out := *r
+	if settings == nil || !settings.ShowFeedback {
+		out.Questions = nil
+	}
+	return &out
+}
+
+func percent(v float64) bool {
+	return !math.IsNaN(v) && v >= 0 && v <= 100
+}
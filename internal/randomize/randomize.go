@@ -0,0 +1,113 @@
+// Package randomize arranges a block's children per respondent to reduce
+// order bias: options shuffled (keeping "Other" and the like last) and
+// groups sampled down to a few of their questions. The arrangement is
+// derived from a seed handed to the respondent, so the public form and the
+// submission agree on what was shown without storing anything up front.
+package randomize
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	mrand "math/rand/v2"
+	"regexp"
+)
+
+// Limits of the settings
+const (
+	MaxPinLast = 10
+	MaxSample  = 100
+)
+
+var ErrInvalidSettings = errors.New("invalid randomization settings")
+
+// Settings randomize the children of one block
+type Settings struct {
+	// Shuffle shows the children in random order
+	Shuffle bool `json:"shuffle,omitempty" yaml:"shuffle,omitempty"`
+	// PinLast keeps the last n children in place, after the shuffled ones
+	PinLast int `json:"pin_last,omitempty" yaml:"pin_last,omitempty"`
+	// Sample shows only n of the children not pinned, picked at random
+	// (0 = all)
+	Sample int `json:"sample,omitempty" yaml:"sample,omitempty"`
+}
+
+// IsZero reports whether the settings leave the children as they are
+func (s *Settings) IsZero() bool {
+	return s == nil || (!s.Shuffle && s.Sample == 0)
+}
+
+// Validate checks the settings' ranges
+func (s *Settings) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.PinLast < 0 || s.PinLast > MaxPinLast || s.Sample < 0 || s.Sample > MaxSample {
+		return ErrInvalidSettings
+	}
+	if s.PinLast > 0 && s.IsZero() {
+		return ErrInvalidSettings
+	}
+	return nil
+}
+
+// Arrange returns the children a respondent is shown, in order. The same
+// seed, block and children always give the same arrangement.
+func (s *Settings) Arrange(seed, blockID string, children []string) []string {
+	result := append([]string(nil), children...)
+	if s.IsZero() || len(children) == 0 {
+		return result
+	}
+
+	pinned := min(s.PinLast, len(result))
+	free, fixed := result[:len(result)-pinned], result[len(result)-pinned:]
+	rng := source(seed, blockID)
+
+	if s.Sample > 0 && s.Sample < len(free) {
+		// Pick Sample positions; without shuffling they keep their order
+		picked := rng.Perm(len(free))[:s.Sample]
+		keep := make([]bool, len(free))
+		for _, i := range picked {
+			keep[i] = true
+		}
+		sampled := make([]string, 0, s.Sample)
+		for i, id := range free {
+			if keep[i] {
+				sampled = append(sampled, id)
+			}
+		}
+		free = sampled
+	} else {
+		free = append([]string(nil), free...)
+	}
+
+	if s.Shuffle {
+		rng.Shuffle(len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+	}
+	return append(free, fixed...)
+}
+
+// source is the random source for one block of one respondent
+func source(seed, blockID string) *mrand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	a := h.Sum64()
+	h.Write([]byte{0})
+	h.Write([]byte(blockID))
+	return mrand.New(mrand.NewPCG(a, h.Sum64()))
+}
+
+var seedPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidSeed reports whether seed can arrange a form
+func ValidSeed(seed string) bool {
+	return seedPattern.MatchString(seed)
+}
+
+// NewSeed returns a fresh random seed
+func NewSeed() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,14 @@
+package rbac
+
+import "errors"
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrInvalidRoleName   = errors.New("invalid role name")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrSystemRole        = errors.New("system roles cannot be deleted")
+	ErrImmutableRole     = errors.New("role permissions cannot be changed")
+	ErrRoleInUse         = errors.New("role is still assigned to users")
+	ErrLastRoleManager   = errors.New("change would leave no active user able to manage roles")
+)
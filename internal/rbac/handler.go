@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type RBACHandler struct {
+	service *RBACService
+}
+
+func NewRBACHandler(service *RBACService) *RBACHandler {
+	return &RBACHandler{service: service}
+}
+
+// ListRoles lists all roles with their permissions
+// GET /admin/roles
+func (h *RBACHandler) ListRoles(c *fiber.Ctx) error {
+	roles, err := h.service.ListRoles(c.Context())
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(roles)
+}
+
+// GetRole retrieves a single role
+// GET /admin/roles/:name
+func (h *RBACHandler) GetRole(c *fiber.Ctx) error {
+	role, err := h.service.GetRole(c.Context(), c.Params("name"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(role)
+}
+
+// ListPermissions lists the permission catalog
+// GET /admin/permissions
+func (h *RBACHandler) ListPermissions(c *fiber.Ctx) error {
+	permissions, err := h.service.ListPermissions(c.Context())
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(permissions)
+}
+
+// CreateRole defines a new role
+// POST /admin/roles
+func (h *RBACHandler) CreateRole(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	var req CreateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	role, err := h.service.CreateRole(c.Context(), actorID, req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(role)
+}
+
+// UpdateRole changes a role's description and/or permissions
+// PATCH /admin/roles/:name
+func (h *RBACHandler) UpdateRole(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	var req UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	role, err := h.service.UpdateRole(c.Context(), actorID, c.Params("name"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(role)
+}
+
+// DeleteRole removes a custom role
+// DELETE /admin/roles/:name
+func (h *RBACHandler) DeleteRole(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	if err := h.service.DeleteRole(c.Context(), actorID, c.Params("name")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Role deleted successfully",
+	})
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrRoleNotFound:
+		return fiber.ErrNotFound
+	case ErrInvalidRoleName:
+		return fiber.NewError(fiber.StatusBadRequest, "Role name must be 2-50 lowercase letters, digits or underscores")
+	case ErrUnknownPermission:
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown permission")
+	case ErrRoleAlreadyExists:
+		return fiber.NewError(fiber.StatusConflict, "Role with this name already exists")
+	case ErrSystemRole:
+		return fiber.NewError(fiber.StatusForbidden, "System roles cannot be deleted")
+	case ErrImmutableRole:
+		return fiber.NewError(fiber.StatusForbidden, "The super_admin role always has every permission")
+	case ErrRoleInUse:
+		return fiber.NewError(fiber.StatusConflict, "Role is still assigned to users")
+	case ErrLastRoleManager:
+		return fiber.NewError(fiber.StatusConflict, "Change would leave no active user with roles:manage")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
@@ -0,0 +1,37 @@
+package rbac
+
+import "time"
+
+// Role whose permissions are fixed (always grants the full catalog)
+const RoleSuperAdmin = "super_admin"
+
+// Role is a named set of permissions assignable to users
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	UserCount   int       `json:"user_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Permission is an entry of the permission catalog
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateRoleRequest defines a new role
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest changes a role; nil fields are left untouched.
+// Permissions replaces the whole set.
+type UpdateRoleRequest struct {
+	Description *string   `json:"description,omitempty"`
+	Permissions *[]string `json:"permissions,omitempty"`
+}
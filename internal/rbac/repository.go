@@ -0,0 +1,214 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RBACRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRBACRepository(db *pgxpool.Pool) *RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// Columns of a role with its permissions and number of assigned users
+const roleSelect = `
+	SELECT r.name, r.description, r.is_system,
+	       COALESCE(
+	           (SELECT array_agg(rp.permission ORDER BY rp.permission)
+	            FROM role_permissions rp
+	            WHERE rp.role_name = r.name),
+	           '{}'
+	       ),
+	       (SELECT COUNT(*) FROM users u WHERE u.role = r.name),
+	       r.created_at, r.updated_at
+	FROM roles r
+`
+
+func scanRole(row pgx.Row) (*Role, error) {
+	var r Role
+	err := row.Scan(
+		&r.Name,
+		&r.Description,
+		&r.IsSystem,
+		&r.Permissions,
+		&r.UserCount,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListRoles retrieves all roles (system roles first)
+func (r *RBACRepository) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := r.db.Query(ctx, roleSelect+" ORDER BY r.is_system DESC, r.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetRole retrieves a role by name
+func (r *RBACRepository) GetRole(ctx context.Context, name string) (*Role, error) {
+	role, err := scanRole(r.db.QueryRow(ctx, roleSelect+" WHERE r.name = $1", name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// ListPermissions retrieves the permission catalog
+func (r *RBACRepository) ListPermissions(ctx context.Context) ([]Permission, error) {
+	rows, err := r.db.Query(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []Permission{}
+	for rows.Next() {
+		var p Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// CountKnownPermissions counts how many of names exist in the catalog
+func (r *RBACRepository) CountKnownPermissions(ctx context.Context, names []string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM permissions WHERE name = ANY($1)`, names).Scan(&count)
+	return count, err
+}
+
+// CountActiveRoleManagersOutside counts active users holding perm through a
+// role other than role
+func (r *RBACRepository) CountActiveRoleManagersOutside(ctx context.Context, role, perm string) (int, error) {
+	const query = `
+		SELECT COUNT(*)
+		FROM users u
+		JOIN role_permissions rp ON rp.role_name = u.role
+		WHERE rp.permission = $1 AND u.is_active = true AND u.role <> $2
+	`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, perm, role).Scan(&count)
+	return count, err
+}
+
+// CreateRole inserts a role with its permissions
+func (r *RBACRepository) CreateRole(ctx context.Context, req CreateRoleRequest) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO roles (name, description) VALUES ($1, $2)`, req.Name, req.Description)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrRoleAlreadyExists
+		}
+		return err
+	}
+
+	if err := insertPermissions(ctx, tx, req.Name, req.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateRole updates the description and/or replaces the permission set
+func (r *RBACRepository) UpdateRole(ctx context.Context, name string, req UpdateRoleRequest) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const query = `
+		UPDATE roles
+		SET description = COALESCE($2, description),
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE name = $1
+	`
+
+	cmd, err := tx.Exec(ctx, query, name, req.Description)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+
+	if req.Permissions != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_name = $1`, name); err != nil {
+			return err
+		}
+		if err := insertPermissions(ctx, tx, name, *req.Permissions); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteRole removes a non-system role that no user holds
+func (r *RBACRepository) DeleteRole(ctx context.Context, name string) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM roles WHERE name = $1 AND is_system = false`, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		// users.role -> roles.name
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrRoleInUse
+		}
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+func insertPermissions(ctx context.Context, tx pgx.Tx, role string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_name, permission)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, role, permissions)
+	return err
+}
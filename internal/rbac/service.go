@@ -0,0 +1,191 @@
+package rbac
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"smart-forms/internal/audit"
+	"smart-forms/internal/auth"
+)
+
+// Role names: lowercase letters, digits and underscores
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type RBACService struct {
+	repo  *RBACRepository
+	audit *audit.AuditService
+}
+
+func NewRBACService(repo *RBACRepository, auditService *audit.AuditService) *RBACService {
+	return &RBACService{
+		repo:  repo,
+		audit: auditService,
+	}
+}
+
+// ListRoles retrieves all roles with their permissions
+func (s *RBACService) ListRoles(ctx context.Context) ([]Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// GetRole retrieves a role by name
+func (s *RBACService) GetRole(ctx context.Context, name string) (*Role, error) {
+	return s.repo.GetRole(ctx, name)
+}
+
+// ListPermissions retrieves the permission catalog
+func (s *RBACService) ListPermissions(ctx context.Context) ([]Permission, error) {
+	return s.repo.ListPermissions(ctx)
+}
+
+// CreateRole defines a new custom role
+func (s *RBACService) CreateRole(ctx context.Context, actorID string, req CreateRoleRequest) (*Role, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrInvalidRoleName
+	}
+
+	permissions, err := s.normalizePermissions(ctx, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	req.Permissions = permissions
+
+	if err := s.repo.CreateRole(ctx, req); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actorID, audit.ActionRoleCreate, map[string]interface{}{
+		"role":        req.Name,
+		"permissions": permissions,
+	})
+
+	return s.repo.GetRole(ctx, req.Name)
+}
+
+// UpdateRole changes a role's description and/or permissions.
+// Holders of the role get the new permissions on their next token refresh.
+func (s *RBACService) UpdateRole(ctx context.Context, actorID, name string, req UpdateRoleRequest) (*Role, error) {
+	role, err := s.repo.GetRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		*req.Description = strings.TrimSpace(*req.Description)
+	}
+
+	if req.Permissions != nil {
+		// super_admin always holds the full catalog
+		if role.Name == RoleSuperAdmin {
+			return nil, ErrImmutableRole
+		}
+
+		permissions, err := s.normalizePermissions(ctx, *req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		req.Permissions = &permissions
+
+		if containsPermission(role.Permissions, auth.PermRolesManage) &&
+			!containsPermission(permissions, auth.PermRolesManage) {
+			count, err := s.repo.CountActiveRoleManagersOutside(ctx, role.Name, auth.PermRolesManage)
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, ErrLastRoleManager
+			}
+		}
+	}
+
+	if err := s.repo.UpdateRole(ctx, name, req); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{"role": name}
+	if req.Permissions != nil {
+		metadata["permissions"] = *req.Permissions
+		metadata["previous_permissions"] = role.Permissions
+	}
+	s.record(ctx, actorID, audit.ActionRoleUpdate, metadata)
+
+	return s.repo.GetRole(ctx, name)
+}
+
+// DeleteRole removes a custom role that is no longer assigned to anyone
+func (s *RBACService) DeleteRole(ctx context.Context, actorID, name string) error {
+	role, err := s.repo.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+	if role.UserCount > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.repo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+
+	s.record(ctx, actorID, audit.ActionRoleDelete, map[string]interface{}{
+		"role":        name,
+		"permissions": role.Permissions,
+	})
+
+	return nil
+}
+
+// normalizePermissions trims, de-duplicates and sorts permission names and
+// rejects names missing from the catalog
+func (s *RBACService) normalizePermissions(ctx context.Context, permissions []string) ([]string, error) {
+	seen := make(map[string]bool, len(permissions))
+	normalized := []string{}
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		normalized = append(normalized, p)
+	}
+	sort.Strings(normalized)
+
+	if len(normalized) == 0 {
+		return normalized, nil
+	}
+
+	known, err := s.repo.CountKnownPermissions(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if known != len(normalized) {
+		return nil, ErrUnknownPermission
+	}
+
+	return normalized, nil
+}
+
+// record writes a best-effort audit entry (failures are logged by the audit service)
+func (s *RBACService) record(ctx context.Context, actorID, action string, metadata map[string]interface{}) {
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:  &actorID,
+		Action:   action,
+		Metadata: metadata,
+	})
+}
+
+func containsPermission(permissions []string, perm string) bool {
+	for _, p := range permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"time"
 
+	"smart-forms/internal/quiz"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,9 +15,16 @@ import (
 type ResponseData struct {
 	ResponseID      string
 	FormID          string
+	VersionID       string
 	TotalTimeSpent  int
 	FlowPath        []string
 	Metadata        map[string]interface{}
+	Variables       map[string]interface{} // computed flow variables, nil if the flow has none
+	Quiz            *quiz.Result           // score, nil unless the form is a quiz
+	Ending          *string                // ID of the ending shown, nil if none
+	Seed            *string                // seed randomized blocks were arranged with
+	DisplayOrder    map[string][]string    // children each randomized block showed, nil if none
+	Variant         *string                // A/B test variant served, nil without a test
 	Answers         []AnswerData
 }
 
@@ -134,10 +143,27 @@ func (rb *ResponseBuffer) insertBatch(batch []ResponseData) error {
 		flowPathJSON, _ := json.Marshal(data.FlowPath)
 		metadataJSON, _ := json.Marshal(data.Metadata)
 
+		var variablesJSON []byte
+		if data.Variables != nil {
+			variablesJSON, _ = json.Marshal(data.Variables)
+		}
+
+		var quizJSON []byte
+		if data.Quiz != nil {
+			quizJSON, _ = json.Marshal(data.Quiz)
+		}
+
+		var orderJSON []byte
+		if data.DisplayOrder != nil {
+			orderJSON, _ = json.Marshal(data.DisplayOrder)
+		}
+
 		_, err := tx.Exec(ctx, `
-			INSERT INTO form_responses (id, form_id, total_time_spent, flow_path, metadata)
-			VALUES ($1, $2, $3, $4, $5)
-		`, data.ResponseID, data.FormID, data.TotalTimeSpent, flowPathJSON, metadataJSON)
+			INSERT INTO form_responses (id, form_id, version_id, total_time_spent, flow_path, metadata, variables, quiz, ending,
+			                            seed, display_order, variant)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, data.ResponseID, data.FormID, data.VersionID, data.TotalTimeSpent, flowPathJSON, metadataJSON, variablesJSON, quizJSON, data.Ending,
+			data.Seed, orderJSON, data.Variant)
 		if err != nil {
 			return err
 		}
@@ -8,4 +8,5 @@ var (
 	ErrFormNotAccepting    = errors.New("form is not accepting responses")
 	ErrInvalidInput        = errors.New("invalid input")
 	ErrInvalidFlowConnection = errors.New("invalid flow connection id")
+	ErrInvalidUpload       = errors.New("upload not found or already attached")
 )
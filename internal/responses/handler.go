@@ -1,17 +1,25 @@
 package responses
 
 import (
+	"errors"
 	"strconv"
 
+	"smart-forms/internal/collaborators"
+	"smart-forms/internal/kinds"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type ResponsesHandler struct {
 	service *ResponsesService
+	access  *collaborators.AccessChecker
 }
 
-func NewResponsesHandler(service *ResponsesService) *ResponsesHandler {
-	return &ResponsesHandler{service: service}
+func NewResponsesHandler(service *ResponsesService, access *collaborators.AccessChecker) *ResponsesHandler {
+	return &ResponsesHandler{
+		service: service,
+		access:  access,
+	}
 }
 
 // SubmitResponse handles form response submission (public endpoint)
@@ -24,21 +32,41 @@ func (h *ResponsesHandler) SubmitResponse(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 
-	responseID, err := h.service.SubmitResponse(c.Context(), slug, req)
+	result, err := h.service.SubmitResponse(c.Context(), slug, req)
 	if err != nil {
 		return mapServiceError(err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(SubmitResponse{
-		Message:    "Response submitted successfully",
-		ResponseID: responseID,
-	})
+	result.Message = "Response submitted successfully"
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// Evaluate computes flow variables and resolves question texts for the
+// answers so far (public endpoint, nothing is stored)
+// POST /f/:slug/evaluate
+func (h *ResponsesHandler) Evaluate(c *fiber.Ctx) error {
+	var req SubmitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	result, err := h.service.Evaluate(c.Context(), c.Params("slug"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(result)
 }
 
 // GetFormResponses retrieves all responses for a form (protected endpoint)
 // GET /forms/:form_id/responses
 func (h *ResponsesHandler) GetFormResponses(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewResponses); err != nil {
+		return collaborators.AccessError(err)
+	}
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
@@ -58,6 +86,7 @@ func (h *ResponsesHandler) GetFormResponses(c *fiber.Ctx) error {
 // GetResponseDetails retrieves a single response with all answers (protected endpoint)
 // GET /responses/:response_id
 func (h *ResponsesHandler) GetResponseDetails(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 	responseID := c.Params("response_id")
 
 	response, answers, err := h.service.GetResponseDetails(c.Context(), responseID)
@@ -65,6 +94,15 @@ func (h *ResponsesHandler) GetResponseDetails(c *fiber.Ctx) error {
 		return mapServiceError(err)
 	}
 
+	// Checked after loading: the form is only known from the response.
+	// Lack of access is reported as not found so response IDs can't be probed.
+	if err := h.access.Require(c.Context(), userID, response.FormID, collaborators.ViewResponses); err != nil {
+		if err == collaborators.ErrInsufficientRole {
+			return collaborators.AccessError(err)
+		}
+		return fiber.ErrNotFound
+	}
+
 	return c.JSON(fiber.Map{
 		"response": response,
 		"answers":  answers,
@@ -72,6 +110,9 @@ func (h *ResponsesHandler) GetResponseDetails(c *fiber.Ctx) error {
 }
 
 func mapServiceError(err error) error {
+	if errors.Is(err, kinds.ErrInvalidAnswer) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 	switch err {
 	case ErrFormNotFound:
 		return fiber.ErrNotFound
@@ -83,6 +124,8 @@ func mapServiceError(err error) error {
 		return fiber.ErrBadRequest
 	case ErrInvalidFlowConnection:
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid flow connection id")
+	case ErrInvalidUpload:
+		return fiber.NewError(fiber.StatusBadRequest, "Upload not found or already attached")
 	default:
 		return fiber.ErrInternalServerError
 	}
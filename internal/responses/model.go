@@ -1,15 +1,29 @@
 package responses
 
-import "time"
+import (
+	"time"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/quiz"
+)
 
 // FormResponse represents a submitted response to a form
 type FormResponse struct {
 	ID              string                 `json:"id"`
 	FormID          string                 `json:"form_id"`
+	VersionID       *string                `json:"version_id,omitempty"` // form version the response was filled against
+	Version         *int                   `json:"version,omitempty"`
 	SubmittedAt     time.Time              `json:"submitted_at"`
 	TotalTimeSpent  int                    `json:"total_time_spent"`
 	FlowPath        []string               `json:"flow_path"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"` // flow variables as computed on submission
+	Quiz            *quiz.Result           `json:"quiz,omitempty"`      // score, for quiz forms
+	Ending          *string                `json:"ending,omitempty"`    // ID of the ending shown
+	Seed            *string                `json:"seed,omitempty"`      // seed randomized blocks were arranged with
+	// DisplayOrder is the children each randomized block showed, in order
+	DisplayOrder map[string][]string `json:"display_order,omitempty"`
+	Variant      *string             `json:"variant,omitempty"` // A/B test variant served
 }
 
 // ResponseAnswer represents an answer to a specific question in a response
@@ -40,10 +54,22 @@ type AnswerInput struct {
 type MetadataInput struct {
 	TotalTimeSpent int      `json:"total_time_spent"`
 	FlowPath       []string `json:"flow_path"`
+	Seed           string   `json:"seed,omitempty"` // from the public form, for randomized flows
+}
+
+// EvaluateResult is a respondent's flow variables so far and the question
+// texts they resolve, by flow connection ID (only texts with {{variables}})
+type EvaluateResult struct {
+	VersionID string                 `json:"version_id"`
+	Variables map[string]interface{} `json:"variables"`
+	Questions map[string]string      `json:"questions"`
 }
 
 // SubmitResponse represents the response after successful submission
 type SubmitResponse struct {
-	Message    string `json:"message"`
-	ResponseID string `json:"response_id"`
+	Message    string       `json:"message"`
+	ResponseID string       `json:"response_id"`
+	Quiz       *quiz.Result `json:"quiz,omitempty"` // the respondent's score, for quiz forms
+	// Ending is the screen to show, rendered with the respondent's answers
+	Ending *endings.Rendered `json:"ending,omitempty"`
 }
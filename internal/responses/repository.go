@@ -3,7 +3,11 @@ package responses
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
+	"smart-forms/internal/quiz"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -33,25 +37,76 @@ func (r *ResponsesRepository) GetFormBySlug(ctx context.Context, slug string) (s
 	return formID, acceptingResponses, nil
 }
 
-// VerifyFlowConnection checks if flow_connection_id exists for the form
-func (r *ResponsesRepository) VerifyFlowConnection(ctx context.Context, formID, flowConnectionID string) error {
-	var exists bool
+// GetVariant returns the key of the A/B test variant serving a version of
+// the form, or nil when none does
+func (r *ResponsesRepository) GetVariant(ctx context.Context, formID, versionID string) (*string, error) {
+	var key string
 	err := r.db.QueryRow(ctx, `
-		SELECT EXISTS(
-			SELECT 1 FROM flow_connections
-			WHERE id = $1 AND form_id = $2 AND deleted_at IS NULL
-		)
-	`, flowConnectionID, formID).Scan(&exists)
+		SELECT key FROM form_variants WHERE form_id = $1 AND version_id = $2
+	`, formID, versionID).Scan(&key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
 
+// GetQuizSettings returns the form's quiz mode, or nil when it isn't a quiz
+func (r *ResponsesRepository) GetQuizSettings(ctx context.Context, formID string) (*quiz.Settings, error) {
+	var settings *quiz.Settings
+	err := r.db.QueryRow(ctx, `SELECT quiz FROM forms WHERE id = $1`, formID).Scan(&settings)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if settings == nil || !settings.Enabled {
+		return nil, nil
+	}
+	return settings, nil
+}
 
-	if !exists {
-		return ErrInvalidFlowConnection
+// ResolveVersion returns the form version the given flow_connection_ids
+// belong to. All of them must belong to the same published or archived
+// version (respondents who loaded an older version can still submit).
+func (r *ResponsesRepository) ResolveVersion(ctx context.Context, formID string, flowConnectionIDs []string) (string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT fc.version_id, COUNT(DISTINCT fc.id)
+		FROM flow_connections fc
+		JOIN form_versions v ON v.id = fc.version_id
+		WHERE fc.form_id = $1
+		  AND fc.id = ANY($2::uuid[])
+		  AND fc.deleted_at IS NULL
+		  AND v.status <> 'draft'
+		GROUP BY fc.version_id
+	`, formID, flowConnectionIDs)
+	if err != nil {
+		return "", err
 	}
+	defer rows.Close()
 
-	return nil
+	var versionID string
+	var matched, groups int
+	for rows.Next() {
+		if err := rows.Scan(&versionID, &matched); err != nil {
+			return "", err
+		}
+		groups++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	unique := make(map[string]struct{}, len(flowConnectionIDs))
+	for _, id := range flowConnectionIDs {
+		unique[id] = struct{}{}
+	}
+
+	if groups != 1 || matched != len(unique) {
+		return "", ErrInvalidFlowConnection
+	}
+
+	return versionID, nil
 }
 
 // CreateResponse creates a new form response
@@ -87,10 +142,12 @@ func (r *ResponsesRepository) CreateAnswer(ctx context.Context, responseID, flow
 // GetResponsesByFormID retrieves all responses for a form
 func (r *ResponsesRepository) GetResponsesByFormID(ctx context.Context, formID string, limit, offset int) ([]FormResponse, int, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, form_id, submitted_at, total_time_spent, flow_path, metadata
-		FROM form_responses
-		WHERE form_id = $1
-		ORDER BY submitted_at DESC
+		SELECT r.id, r.form_id, r.version_id, v.version_number, r.submitted_at, r.total_time_spent, r.flow_path, r.metadata, r.variables, r.quiz, r.ending,
+		       r.seed, r.display_order, r.variant
+		FROM form_responses r
+		LEFT JOIN form_versions v ON v.id = r.version_id
+		WHERE r.form_id = $1
+		ORDER BY r.submitted_at DESC
 		LIMIT $2 OFFSET $3
 	`, formID, limit, offset)
 	if err != nil {
@@ -101,15 +158,21 @@ func (r *ResponsesRepository) GetResponsesByFormID(ctx context.Context, formID s
 	var responses []FormResponse
 	for rows.Next() {
 		var r FormResponse
-		var flowPathJSON, metadataJSON []byte
+		var flowPathJSON, metadataJSON, variablesJSON, quizJSON []byte
 
-		err := rows.Scan(&r.ID, &r.FormID, &r.SubmittedAt, &r.TotalTimeSpent, &flowPathJSON, &metadataJSON)
+		err := rows.Scan(&r.ID, &r.FormID, &r.VersionID, &r.Version, &r.SubmittedAt, &r.TotalTimeSpent, &flowPathJSON, &metadataJSON, &variablesJSON, &quizJSON, &r.Ending, &r.Seed, &r.DisplayOrder, &r.Variant)
 		if err != nil {
 			continue
 		}
 
 		json.Unmarshal(flowPathJSON, &r.FlowPath)
 		json.Unmarshal(metadataJSON, &r.Metadata)
+		if len(variablesJSON) > 0 {
+			json.Unmarshal(variablesJSON, &r.Variables)
+		}
+		if len(quizJSON) > 0 {
+			json.Unmarshal(quizJSON, &r.Quiz)
+		}
 
 		responses = append(responses, r)
 	}
@@ -124,13 +187,15 @@ func (r *ResponsesRepository) GetResponsesByFormID(ctx context.Context, formID s
 // GetResponseByID retrieves a single response by ID
 func (r *ResponsesRepository) GetResponseByID(ctx context.Context, responseID string) (*FormResponse, error) {
 	var resp FormResponse
-	var flowPathJSON, metadataJSON []byte
+	var flowPathJSON, metadataJSON, variablesJSON, quizJSON []byte
 
 	err := r.db.QueryRow(ctx, `
-		SELECT id, form_id, submitted_at, total_time_spent, flow_path, metadata
-		FROM form_responses
-		WHERE id = $1
-	`, responseID).Scan(&resp.ID, &resp.FormID, &resp.SubmittedAt, &resp.TotalTimeSpent, &flowPathJSON, &metadataJSON)
+		SELECT r.id, r.form_id, r.version_id, v.version_number, r.submitted_at, r.total_time_spent, r.flow_path, r.metadata, r.variables, r.quiz, r.ending,
+		       r.seed, r.display_order, r.variant
+		FROM form_responses r
+		LEFT JOIN form_versions v ON v.id = r.version_id
+		WHERE r.id = $1
+	`, responseID).Scan(&resp.ID, &resp.FormID, &resp.VersionID, &resp.Version, &resp.SubmittedAt, &resp.TotalTimeSpent, &flowPathJSON, &metadataJSON, &variablesJSON, &quizJSON, &resp.Ending, &resp.Seed, &resp.DisplayOrder, &resp.Variant)
 
 	if err != nil {
 		return nil, err
@@ -138,6 +203,12 @@ func (r *ResponsesRepository) GetResponseByID(ctx context.Context, responseID st
 
 	json.Unmarshal(flowPathJSON, &resp.FlowPath)
 	json.Unmarshal(metadataJSON, &resp.Metadata)
+	if len(variablesJSON) > 0 {
+		json.Unmarshal(variablesJSON, &resp.Variables)
+	}
+	if len(quizJSON) > 0 {
+		json.Unmarshal(quizJSON, &resp.Quiz)
+	}
 
 	return &resp, nil
 }
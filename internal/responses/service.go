@@ -4,73 +4,149 @@ import (
 	"context"
 	"strings"
 
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
 	"smart-forms/internal/responses/buffer"
+	"smart-forms/internal/versions"
 
 	"github.com/google/uuid"
 )
 
 type ResponsesService struct {
-	repo   *ResponsesRepository
-	buffer *buffer.ResponseBuffer
+	repo     *ResponsesRepository
+	buffer   *buffer.ResponseBuffer
+	versions *versions.VersionsService
+	files    FileAttacher
 }
 
-func NewResponsesService(repo *ResponsesRepository, buf *buffer.ResponseBuffer) *ResponsesService {
+func NewResponsesService(repo *ResponsesRepository, buf *buffer.ResponseBuffer, versionsService *versions.VersionsService) *ResponsesService {
 	return &ResponsesService{
-		repo:   repo,
-		buffer: buf,
+		repo:     repo,
+		buffer:   buf,
+		versions: versionsService,
 	}
 }
 
-// SubmitResponse handles form response submission
-func (s *ResponsesService) SubmitResponse(ctx context.Context, slug string, req SubmitRequest) (string, error) {
+// SubmitResponse handles form response submission. The result carries the
+// response ID, the score for quiz forms and the ending to show.
+func (s *ResponsesService) SubmitResponse(ctx context.Context, slug string, req SubmitRequest) (*SubmitResponse, error) {
 	// Validate input
 	if len(req.Responses) == 0 {
-		return "", ErrInvalidInput
+		return nil, ErrInvalidInput
 	}
 
 	if req.Metadata.TotalTimeSpent < 0 {
-		return "", ErrInvalidInput
+		return nil, ErrInvalidInput
 	}
 
 	if len(req.Metadata.FlowPath) == 0 {
-		return "", ErrInvalidInput
+		return nil, ErrInvalidInput
 	}
 
 	// Get form by slug
 	formID, acceptingResponses, err := s.repo.GetFormBySlug(ctx, slug)
 	if err != nil {
-		return "", ErrFormNotFound
+		return nil, ErrFormNotFound
 	}
 
 	// Check if form is accepting responses
 	if !acceptingResponses {
-		return "", ErrFormNotAccepting
+		return nil, ErrFormNotAccepting
 	}
 
-	// Verify all flow_connection_ids exist
-	for _, answer := range req.Responses {
+	// Validate answers
+	flowConnectionIDs := make([]string, len(req.Responses))
+	for i, answer := range req.Responses {
 		if strings.TrimSpace(answer.FlowConnectionID) == "" {
-			return "", ErrInvalidInput
+			return nil, ErrInvalidInput
 		}
 
 		if strings.TrimSpace(answer.AnswerText) == "" {
-			return "", ErrInvalidInput
+			return nil, ErrInvalidInput
 		}
 
 		// Validate UUID format
 		if _, err := uuid.Parse(answer.FlowConnectionID); err != nil {
-			return "", ErrInvalidFlowConnection
+			return nil, ErrInvalidFlowConnection
 		}
 
-		err := s.repo.VerifyFlowConnection(ctx, formID, answer.FlowConnectionID)
-		if err != nil {
-			return "", err
+		flowConnectionIDs[i] = answer.FlowConnectionID
+	}
+
+	// All answers must belong to one published (or since archived) version
+	versionID, err := s.repo.ResolveVersion(ctx, formID, flowConnectionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flow variables are computed here, against the version answered
+	logic, err := s.versions.VersionLogic(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Answers to questions with a kind (rating, NPS, matrix...) must carry
+	// a value of the kind's shape
+	for _, answer := range req.Responses {
+		if err := logic.CheckAnswer(answer.FlowConnectionID, answer.AnswerValue); err != nil {
+			return nil, err
+		}
+	}
+
+	// The seed gives back what randomized blocks showed the respondent;
+	// they can't have answered what was sampled out
+	var seed *string
+	var order map[string][]string
+	if req.Metadata.Seed != "" {
+		if !randomize.ValidSeed(req.Metadata.Seed) {
+			return nil, ErrInvalidInput
 		}
+		if order = logic.Order(req.Metadata.Seed); len(order) > 0 {
+			if logic.Unshown(order, req.Metadata.FlowPath) != "" {
+				return nil, ErrInvalidInput
+			}
+			seed = &req.Metadata.Seed
+		} else {
+			order = nil
+		}
+	}
+	env := logic.Evaluate(req.Metadata.FlowPath, answerTexts(req.Responses))
+	var computed map[string]interface{}
+	if len(env) > 0 {
+		computed = env
+	}
+
+	// The version answered tells which A/B test variant was served
+	variant, err := s.repo.GetVariant(ctx, formID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// So is the score, when the form is a quiz
+	settings, err := s.repo.GetQuizSettings(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	var score *quiz.Result
+	if settings != nil {
+		score = logic.Score(settings, req.Metadata.FlowPath, answerTexts(req.Responses))
 	}
 
 	// Generate response ID immediately
 	responseID := uuid.New().String()
 
+	// The ending is picked from where the respondent finished, or their score
+	ending := logic.Ending(req.Metadata.FlowPath, env, score, responseID)
+	var endingID *string
+	if ending != nil {
+		endingID = &ending.ID
+	}
+
+	// Files uploaded for file answers become the response's
+	if err := s.attachFiles(ctx, logic, formID, responseID, req.Responses); err != nil {
+		return nil, err
+	}
+
 	// Prepare answers for buffering
 	answers := make([]buffer.AnswerData, len(req.Responses))
 	for i, answer := range req.Responses {
@@ -87,19 +163,96 @@ func (s *ResponsesService) SubmitResponse(ctx context.Context, slug string, req
 	responseData := buffer.ResponseData{
 		ResponseID:     responseID,
 		FormID:         formID,
+		VersionID:      versionID,
 		TotalTimeSpent: req.Metadata.TotalTimeSpent,
 		FlowPath:       req.Metadata.FlowPath,
 		Metadata:       nil,
+		Variables:      computed,
+		Quiz:           score,
+		Ending:         endingID,
+		Seed:           seed,
+		DisplayOrder:   order,
+		Variant:        variant,
 		Answers:        answers,
 	}
 
 	err = s.buffer.Enqueue(responseData)
 	if err != nil {
-		return "", err
+		if s.files != nil {
+			s.files.Detach(ctx, responseID)
+		}
+		return nil, err
 	}
 
 	// Return immediately to user (data will be inserted in batch)
-	return responseID, nil
+	return &SubmitResponse{
+		ResponseID: responseID,
+		Quiz:       score.ForRespondent(settings),
+		Ending:     ending,
+	}, nil
+}
+
+// Evaluate computes a respondent's flow variables from the answers so far,
+// without storing anything, and resolves the question texts that use them.
+// Answers must belong to one published or archived version; with none, the
+// published version starts from its initial values.
+func (s *ResponsesService) Evaluate(ctx context.Context, slug string, req SubmitRequest) (*EvaluateResult, error) {
+	formID, _, err := s.repo.GetFormBySlug(ctx, slug)
+	if err != nil {
+		return nil, ErrFormNotFound
+	}
+
+	var versionID string
+	var logic *versions.Logic
+	if len(req.Responses) == 0 {
+		versionID, logic, err = s.versions.PublishedLogic(ctx, formID)
+		if err == versions.ErrNotFound {
+			return nil, ErrFormNotPublished
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ids := make([]string, len(req.Responses))
+		for i, answer := range req.Responses {
+			if _, err := uuid.Parse(answer.FlowConnectionID); err != nil {
+				return nil, ErrInvalidFlowConnection
+			}
+			ids[i] = answer.FlowConnectionID
+		}
+
+		versionID, err = s.repo.ResolveVersion(ctx, formID, ids)
+		if err != nil {
+			return nil, err
+		}
+		logic, err = s.versions.VersionLogic(ctx, versionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	answers := answerTexts(req.Responses)
+	env := logic.Evaluate(req.Metadata.FlowPath, answers)
+	result := &EvaluateResult{
+		VersionID: versionID,
+		Variables: env,
+		Questions: logic.Render(env),
+	}
+
+	// Quiz forms aren't scored here: the answers and flow_path are the
+	// client's, so any result derived from the score would probe the
+	// answer key. Score bands are resolved on submission only.
+	return result, nil
+}
+
+// answerTexts maps flow connection IDs to answer texts; a connection
+// answered twice keeps its last answer
+func answerTexts(answers []AnswerInput) map[string]string {
+	texts := make(map[string]string, len(answers))
+	for _, a := range answers {
+		texts[a.FlowConnectionID] = a.AnswerText
+	}
+	return texts
 }
 
 // GetResponses retrieves responses for a form (owner only)
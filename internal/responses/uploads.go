@@ -0,0 +1,61 @@
+package responses
+
+import (
+	"context"
+
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/uploads"
+	"smart-forms/internal/versions"
+)
+
+// FileAttacher attaches the files respondents uploaded before submitting
+// (implemented by the uploads package)
+type FileAttacher interface {
+	// Attach attaches unattached uploads of a flow connection to a
+	// response, all or none, and returns them as stored
+	Attach(ctx context.Context, formID, flowConnectionID, responseID string, ids []string) ([]uploads.File, error)
+	// Detach releases a response's uploads
+	Detach(ctx context.Context, responseID string) error
+}
+
+// SetUploads enables file answers; without an attacher their values are
+// stored as sent
+func (s *ResponsesService) SetUploads(files FileAttacher) {
+	s.files = files
+}
+
+// attachFiles attaches the uploads file answers list to the response and
+// puts the stored file details in their values
+func (s *ResponsesService) attachFiles(ctx context.Context, logic *versions.Logic, formID, responseID string, answers []AnswerInput) error {
+	if s.files == nil {
+		return nil
+	}
+	attached := false
+	for i := range answers {
+		spec := logic.Kind(answers[i].FlowConnectionID)
+		if spec == nil || spec.Type != kinds.File {
+			continue
+		}
+
+		// The value's shape was checked against the kind
+		listed, _ := answers[i].AnswerValue["files"].([]interface{})
+		ids := make([]string, len(listed))
+		for j, f := range listed {
+			ids[j], _ = f.(map[string]interface{})["id"].(string)
+		}
+
+		files, err := s.files.Attach(ctx, formID, answers[i].FlowConnectionID, responseID, ids)
+		if err != nil {
+			if attached {
+				s.files.Detach(ctx, responseID)
+			}
+			if err == uploads.ErrInvalidUpload {
+				return ErrInvalidUpload
+			}
+			return err
+		}
+		attached = true
+		answers[i].AnswerValue = map[string]interface{}{"files": files}
+	}
+	return nil
+}
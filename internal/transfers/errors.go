@@ -0,0 +1,14 @@
+package transfers
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("transfer not found")
+	ErrFormNotFound      = errors.New("form not found")
+	ErrUserNotFound      = errors.New("recipient not found")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrSameOwner         = errors.New("recipient already owns the form")
+	ErrTransferPending   = errors.New("form already has a pending transfer")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrCannotTransfer    = errors.New("only workspace owners and admins can transfer the form")
+)
@@ -0,0 +1,183 @@
+package transfers
+
+import (
+	"smart-forms/internal/collaborators"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TransfersHandler struct {
+	service *TransfersService
+}
+
+func NewTransfersHandler(service *TransfersService) *TransfersHandler {
+	return &TransfersHandler{service: service}
+}
+
+/*
+========================
+ OWNER SIDE
+========================
+*/
+
+// StartTransfer offers a form to another user
+// POST /forms/:form_id/transfers
+func (h *TransfersHandler) StartTransfer(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req StartTransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	transfer, err := h.service.StartTransfer(c.Context(), userID, c.Params("form_id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(transfer)
+}
+
+// ListFormTransfers lists a form's transfers
+// GET /forms/:form_id/transfers
+func (h *TransfersHandler) ListFormTransfers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	transfers, err := h.service.ListFormTransfers(c.Context(), userID, c.Params("form_id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(transfers)
+}
+
+// CancelTransfer cancels a pending transfer
+// DELETE /forms/:form_id/transfers/:id
+func (h *TransfersHandler) CancelTransfer(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.CancelTransfer(c.Context(), userID, c.Params("form_id"), c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Transfer cancelled",
+	})
+}
+
+/*
+========================
+ ADMIN
+========================
+*/
+
+// AdminStartTransfer offers any form to another user
+// POST /admin/forms/:id/transfers
+func (h *TransfersHandler) AdminStartTransfer(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	var req StartTransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	transfer, err := h.service.AdminStartTransfer(c.Context(), actorID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(transfer)
+}
+
+// AdminCancelTransfer cancels any pending transfer
+// DELETE /admin/forms/:id/transfers/:transfer_id
+func (h *TransfersHandler) AdminCancelTransfer(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	if err := h.service.AdminCancelTransfer(c.Context(), actorID, c.Params("id"), c.Params("transfer_id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Transfer cancelled",
+	})
+}
+
+/*
+========================
+ RECIPIENT SIDE
+========================
+*/
+
+// ListIncoming lists pending transfers for the caller
+// GET /transfers
+func (h *TransfersHandler) ListIncoming(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	transfers, err := h.service.ListIncoming(c.Context(), userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(transfers)
+}
+
+// AcceptTransfer takes ownership of the form
+// POST /transfers/:id/accept
+func (h *TransfersHandler) AcceptTransfer(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	// Body is optional (default: personal workspace)
+	var req AcceptTransferRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.ErrBadRequest
+		}
+	}
+
+	transfer, err := h.service.AcceptTransfer(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(transfer)
+}
+
+// DeclineTransfer declines a transfer
+// POST /transfers/:id/decline
+func (h *TransfersHandler) DeclineTransfer(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.DeclineTransfer(c.Context(), userID, c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Transfer declined",
+	})
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case collaborators.ErrNoAccess, collaborators.ErrInsufficientRole:
+		return collaborators.AccessError(err)
+	case ErrNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Transfer not found or expired")
+	case ErrFormNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Form not found")
+	case ErrUserNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "No active user with this email")
+	case ErrWorkspaceNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Workspace not found")
+	case ErrInvalidInput:
+		return fiber.NewError(fiber.StatusBadRequest, "email is required")
+	case ErrSameOwner:
+		return fiber.NewError(fiber.StatusBadRequest, "Recipient already owns this form")
+	case ErrCannotTransfer:
+		return fiber.NewError(fiber.StatusForbidden, "Only the workspace's owners and admins can transfer this form")
+	case ErrTransferPending:
+		return fiber.NewError(fiber.StatusConflict, "Form already has a pending transfer")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
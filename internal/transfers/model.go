@@ -0,0 +1,49 @@
+package transfers
+
+import "time"
+
+// Transfer statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+// How long a pending transfer can be accepted
+const transferTTL = 14 * 24 * time.Hour
+
+// Transfer hands a form (with its links, responses and analytics) to another user
+type Transfer struct {
+	ID            string     `json:"id"`
+	FormID        string     `json:"form_id"`
+	FormTitle     string     `json:"form_title,omitempty"`
+	FromUserID    *string    `json:"from_user_id,omitempty"`
+	ToUserID      string     `json:"to_user_id"`
+	ToEmail       string     `json:"to_email"`
+	InitiatedBy   *string    `json:"initiated_by,omitempty"`
+	Status        string     `json:"status"`
+	ToWorkspaceID *string    `json:"to_workspace_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+// StartTransferRequest names the recipient by email
+type StartTransferRequest struct {
+	Email string `json:"email"`
+}
+
+// AcceptTransferRequest optionally picks the workspace the form moves into
+// (defaults to the recipient's personal workspace)
+type AcceptTransferRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+}
+
+// transferredForm holds what the service needs to invalidate caches
+type transferredForm struct {
+	Transfer   *Transfer
+	AutoSlug   *string
+	CustomSlug *string
+	IsTemplate bool
+}
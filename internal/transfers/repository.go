@@ -0,0 +1,281 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TransfersRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTransfersRepository(db *pgxpool.Pool) *TransfersRepository {
+	return &TransfersRepository{db: db}
+}
+
+const transferSelect = `
+	SELECT t.id, t.form_id, f.title, t.from_user_id, t.to_user_id, u.email, t.initiated_by,
+	       t.status, t.to_workspace_id, t.created_at, t.expires_at, t.resolved_at
+	FROM form_transfers t
+	JOIN forms f ON f.id = t.form_id
+	JOIN users u ON u.id = t.to_user_id
+`
+
+func scanTransfer(row pgx.Row) (*Transfer, error) {
+	var t Transfer
+	err := row.Scan(
+		&t.ID, &t.FormID, &t.FormTitle, &t.FromUserID, &t.ToUserID, &t.ToEmail, &t.InitiatedBy,
+		&t.Status, &t.ToWorkspaceID, &t.CreatedAt, &t.ExpiresAt, &t.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TransfersRepository) queryTransfers(ctx context.Context, query string, args ...interface{}) ([]Transfer, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *t)
+	}
+
+	return transfers, rows.Err()
+}
+
+/*
+========================
+ LOOKUPS
+========================
+*/
+
+// GetFormOwner returns forms.user_id of a live form
+func (r *TransfersRepository) GetFormOwner(ctx context.Context, formID string) (string, error) {
+	var ownerID string
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id FROM forms WHERE id = $1 AND deleted_at IS NULL
+	`, formID).Scan(&ownerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrFormNotFound
+		}
+		return "", err
+	}
+	return ownerID, nil
+}
+
+// CanTransferOut reports whether the user may hand the form out of its
+// workspace: an owner or admin of a team workspace, or the form's owner in a
+// personal workspace
+func (r *TransfersRepository) CanTransferOut(ctx context.Context, formID, userID string) (bool, error) {
+	var can bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM forms f
+			JOIN workspaces w ON w.id = f.workspace_id
+			WHERE f.id = $1 AND f.deleted_at IS NULL
+			  AND CASE
+				WHEN w.personal_user_id IS NOT NULL THEN f.user_id = $2
+				ELSE EXISTS (
+					SELECT 1 FROM workspace_members m
+					WHERE m.workspace_id = w.id AND m.user_id = $2
+					  AND m.role IN ('owner', 'admin')
+				)
+			  END
+		)
+	`, formID, userID).Scan(&can)
+	return can, err
+}
+
+// FindActiveUserIDByEmail resolves an active account by email
+func (r *TransfersRepository) FindActiveUserIDByEmail(ctx context.Context, email string) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		SELECT id FROM users WHERE lower(email) = lower($1) AND is_active = true
+	`, email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+/*
+========================
+ CREATE / LIST
+========================
+*/
+
+// Create starts a pending transfer. Expired pending transfers of the form are
+// cancelled first so they don't block a new one.
+func (r *TransfersRepository) Create(
+	ctx context.Context,
+	formID, fromUserID, toUserID, initiatedBy string,
+	expiresAt time.Time,
+) (*Transfer, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE form_transfers
+		SET status = 'cancelled', resolved_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE form_id = $1 AND status = 'pending' AND expires_at <= NOW()
+	`, formID); err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO form_transfers (form_id, from_user_id, to_user_id, initiated_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, formID, fromUserID, toUserID, initiatedBy, expiresAt).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrTransferPending
+		}
+		return nil, err
+	}
+
+	t, err := scanTransfer(tx.QueryRow(ctx, transferSelect+` WHERE t.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit(ctx)
+}
+
+// ListForForm lists a form's transfers, newest first
+func (r *TransfersRepository) ListForForm(ctx context.Context, formID string) ([]Transfer, error) {
+	return r.queryTransfers(ctx, transferSelect+`
+		WHERE t.form_id = $1
+		ORDER BY t.created_at DESC
+	`, formID)
+}
+
+// ListIncoming lists pending, unexpired transfers addressed to the user
+func (r *TransfersRepository) ListIncoming(ctx context.Context, userID string) ([]Transfer, error) {
+	return r.queryTransfers(ctx, transferSelect+`
+		WHERE t.to_user_id = $1
+		  AND t.status = 'pending'
+		  AND t.expires_at > NOW()
+		  AND f.deleted_at IS NULL
+		ORDER BY t.created_at DESC
+	`, userID)
+}
+
+/*
+========================
+ RESOLVE
+========================
+*/
+
+// Cancel cancels a pending transfer of the form
+func (r *TransfersRepository) Cancel(ctx context.Context, formID, transferID string) (*Transfer, error) {
+	return r.resolve(ctx, `
+		UPDATE form_transfers
+		SET status = 'cancelled', resolved_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND form_id = $2 AND status = 'pending'
+		RETURNING id
+	`, transferID, formID)
+}
+
+// Decline declines a pending transfer addressed to the user
+func (r *TransfersRepository) Decline(ctx context.Context, transferID, userID string) (*Transfer, error) {
+	return r.resolve(ctx, `
+		UPDATE form_transfers
+		SET status = 'declined', resolved_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND to_user_id = $2 AND status = 'pending' AND expires_at > NOW()
+		RETURNING id
+	`, transferID, userID)
+}
+
+func (r *TransfersRepository) resolve(ctx context.Context, query string, args ...interface{}) (*Transfer, error) {
+	var id string
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return scanTransfer(r.db.QueryRow(ctx, transferSelect+` WHERE t.id = $1`, id))
+}
+
+// Accept hands the form to the recipient in one transaction: forms.user_id and
+// workspace_id change, the recipient's collaborator row (now redundant) is
+// dropped and the transfer is marked accepted. Links, responses and analytics
+// are keyed by form ID and move with the form.
+func (r *TransfersRepository) Accept(ctx context.Context, transferID, userID, workspaceID string) (*transferredForm, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var formID string
+	err = tx.QueryRow(ctx, `
+		UPDATE form_transfers
+		SET status = 'accepted',
+		    to_workspace_id = $3,
+		    resolved_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND to_user_id = $2 AND status = 'pending' AND expires_at > NOW()
+		RETURNING form_id
+	`, transferID, userID, workspaceID).Scan(&formID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	result := transferredForm{}
+	err = tx.QueryRow(ctx, `
+		UPDATE forms
+		SET user_id = $2,
+		    workspace_id = $3,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING auto_slug, custom_slug, is_template
+	`, formID, userID, workspaceID).Scan(&result.AutoSlug, &result.CustomSlug, &result.IsTemplate)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM form_collaborators WHERE form_id = $1 AND user_id = $2
+	`, formID, userID); err != nil {
+		return nil, err
+	}
+
+	result.Transfer, err = scanTransfer(tx.QueryRow(ctx, transferSelect+` WHERE t.id = $1`, transferID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, tx.Commit(ctx)
+}
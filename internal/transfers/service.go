@@ -0,0 +1,210 @@
+package transfers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"smart-forms/internal/audit"
+	"smart-forms/internal/cache"
+	"smart-forms/internal/collaborators"
+	"smart-forms/internal/workspaces"
+)
+
+type TransfersService struct {
+	repo       *TransfersRepository
+	access     *collaborators.AccessChecker
+	workspaces *workspaces.WorkspacesService
+	cache      *cache.Cache
+	audit      *audit.AuditService
+}
+
+func NewTransfersService(
+	repo *TransfersRepository,
+	access *collaborators.AccessChecker,
+	workspaceService *workspaces.WorkspacesService,
+	formCache *cache.Cache,
+	auditService *audit.AuditService,
+) *TransfersService {
+	return &TransfersService{
+		repo:       repo,
+		access:     access,
+		workspaces: workspaceService,
+		cache:      formCache,
+		audit:      auditService,
+	}
+}
+
+/*
+========================
+ OWNER SIDE
+========================
+*/
+
+// StartTransfer offers the form to the user with the given email. The form
+// leaves its workspace, so beyond the owner role on the form this takes an
+// owner or admin of the workspace (the form's owner for a personal one).
+func (s *TransfersService) StartTransfer(ctx context.Context, userID, formID string, req StartTransferRequest) (*Transfer, error) {
+	if err := s.access.Require(ctx, userID, formID, collaborators.ManageForm); err != nil {
+		return nil, err
+	}
+
+	can, err := s.repo.CanTransferOut(ctx, formID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, ErrCannotTransfer
+	}
+
+	return s.start(ctx, userID, formID, req, false)
+}
+
+// AdminStartTransfer offers any form to the user with the given email
+// (forms:transfer permission, checked by the route)
+func (s *TransfersService) AdminStartTransfer(ctx context.Context, actorID, formID string, req StartTransferRequest) (*Transfer, error) {
+	return s.start(ctx, actorID, formID, req, true)
+}
+
+func (s *TransfersService) start(ctx context.Context, actorID, formID string, req StartTransferRequest, asAdmin bool) (*Transfer, error) {
+	email := strings.TrimSpace(req.Email)
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, ErrInvalidInput
+	}
+
+	ownerID, err := s.repo.GetFormOwner(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	toUserID, err := s.repo.FindActiveUserIDByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if toUserID == ownerID {
+		return nil, ErrSameOwner
+	}
+
+	transfer, err := s.repo.Create(ctx, formID, ownerID, toUserID, actorID, time.Now().UTC().Add(transferTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actorID, audit.ActionFormTransferStart, transfer, map[string]interface{}{
+		"admin": asAdmin,
+	})
+
+	return transfer, nil
+}
+
+// ListFormTransfers lists a form's transfers (form owners only)
+func (s *TransfersService) ListFormTransfers(ctx context.Context, userID, formID string) ([]Transfer, error) {
+	if err := s.access.Require(ctx, userID, formID, collaborators.ManageForm); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListForForm(ctx, formID)
+}
+
+// CancelTransfer cancels the form's pending transfer (form owners only)
+func (s *TransfersService) CancelTransfer(ctx context.Context, userID, formID, transferID string) error {
+	if err := s.access.Require(ctx, userID, formID, collaborators.ManageForm); err != nil {
+		return err
+	}
+
+	return s.cancel(ctx, userID, formID, transferID)
+}
+
+// AdminCancelTransfer cancels any pending transfer (forms:transfer permission)
+func (s *TransfersService) AdminCancelTransfer(ctx context.Context, actorID, formID, transferID string) error {
+	return s.cancel(ctx, actorID, formID, transferID)
+}
+
+func (s *TransfersService) cancel(ctx context.Context, actorID, formID, transferID string) error {
+	transfer, err := s.repo.Cancel(ctx, formID, transferID)
+	if err != nil {
+		return err
+	}
+
+	s.record(ctx, actorID, audit.ActionFormTransferCancel, transfer, nil)
+	return nil
+}
+
+/*
+========================
+ RECIPIENT SIDE
+========================
+*/
+
+// ListIncoming lists pending transfers addressed to the user
+func (s *TransfersService) ListIncoming(ctx context.Context, userID string) ([]Transfer, error) {
+	return s.repo.ListIncoming(ctx, userID)
+}
+
+// AcceptTransfer makes the user the form's owner and moves the form into the
+// chosen workspace (personal workspace by default)
+func (s *TransfersService) AcceptTransfer(ctx context.Context, userID, transferID string, req AcceptTransferRequest) (*Transfer, error) {
+	workspaceID, err := s.workspaces.ResolveFormWorkspace(ctx, userID, strings.TrimSpace(req.WorkspaceID))
+	if err != nil {
+		if err == workspaces.ErrNotFound {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	result, err := s.repo.Accept(ctx, transferID, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invalidate cached public form
+	s.cache.Delete(cache.FormIDKey(result.Transfer.FormID))
+	if result.AutoSlug != nil && *result.AutoSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*result.AutoSlug))
+	}
+	if result.CustomSlug != nil && *result.CustomSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*result.CustomSlug))
+	}
+	if result.IsTemplate {
+		s.cache.Delete("templates:list")
+	}
+
+	s.record(ctx, userID, audit.ActionFormTransferAccept, result.Transfer, map[string]interface{}{
+		"workspace_id": workspaceID,
+	})
+
+	return result.Transfer, nil
+}
+
+// DeclineTransfer declines a pending transfer addressed to the user
+func (s *TransfersService) DeclineTransfer(ctx context.Context, userID, transferID string) error {
+	transfer, err := s.repo.Decline(ctx, transferID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.record(ctx, userID, audit.ActionFormTransferDecline, transfer, nil)
+	return nil
+}
+
+// record writes a best-effort audit entry about a transfer (the subject is the recipient)
+func (s *TransfersService) record(ctx context.Context, actorID, action string, t *Transfer, extra map[string]interface{}) {
+	metadata := map[string]interface{}{
+		"transfer_id": t.ID,
+		"form_id":     t.FormID,
+		"to_user_id":  t.ToUserID,
+	}
+	if t.FromUserID != nil {
+		metadata["from_user_id"] = *t.FromUserID
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &t.ToUserID,
+		Action:        action,
+		Metadata:      metadata,
+	})
+}
@@ -0,0 +1,15 @@
+package uploads
+
+import "errors"
+
+var (
+	ErrFormNotFound     = errors.New("form not found")
+	ErrFormNotAccepting = errors.New("form is not accepting responses")
+	ErrNotFileQuestion  = errors.New("not a file question")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrTooLarge         = errors.New("file too large")
+	ErrTypeNotAllowed   = errors.New("file type not allowed")
+	ErrInvalidUpload    = errors.New("upload not found or already attached")
+	ErrInvalidSignature = errors.New("invalid or expired download link")
+	ErrNotFound         = errors.New("upload not found")
+)
@@ -0,0 +1,113 @@
+package uploads
+
+import (
+	"mime"
+	"strconv"
+
+	"smart-forms/internal/collaborators"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UploadsHandler struct {
+	service *UploadsService
+	access  *collaborators.AccessChecker
+}
+
+func NewUploadsHandler(service *UploadsService, access *collaborators.AccessChecker) *UploadsHandler {
+	return &UploadsHandler{
+		service: service,
+		access:  access,
+	}
+}
+
+// Upload stores a file for a file question before the response is
+// submitted (public endpoint, multipart "file" and "flow_connection_id")
+// POST /f/:slug/uploads
+func (h *UploadsHandler) Upload(c *fiber.Ctx) error {
+	// The body is streamed, unread (see limitBodies in main.go): bound it
+	// before the form is parsed. The question's own limit is checked on
+	// the file.
+	size := c.Request().Header.ContentLength()
+	if size < 0 {
+		return fiber.ErrLengthRequired
+	}
+	if size > MaxBodySize {
+		c.Context().SetConnectionClose()
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "File too large")
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	file, err := h.service.Upload(c.Context(), c.Params("slug"), c.FormValue("flow_connection_id"), header)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(file)
+}
+
+// ResponseFiles lists a response's files with signed download URLs
+// (protected endpoint)
+// GET /responses/:response_id/files
+func (h *UploadsHandler) ResponseFiles(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	formID, files, err := h.service.ResponseFiles(c.Context(), c.Params("response_id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	// Checked after loading, as for response details
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewResponses); err != nil {
+		if err == collaborators.ErrInsufficientRole {
+			return collaborators.AccessError(err)
+		}
+		return fiber.ErrNotFound
+	}
+
+	return c.JSON(fiber.Map{
+		"items":      files,
+		"expires_in": int(URLTTL.Seconds()),
+	})
+}
+
+// Download serves a file for a signed URL from ResponseFiles (public
+// endpoint, the signature is the credential)
+// GET /files/:upload_id?expires=...&signature=...
+func (h *UploadsHandler) Download(c *fiber.Ctx) error {
+	upload, body, err := h.service.Open(c.Context(), c.Params("upload_id"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	c.Set(fiber.HeaderContentType, upload.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": upload.Name}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, max-age="+strconv.Itoa(int(URLTTL.Seconds())))
+	return c.SendStream(body, int(upload.Size))
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrFormNotFound, ErrNotFound:
+		return fiber.ErrNotFound
+	case ErrFormNotAccepting:
+		return fiber.NewError(fiber.StatusForbidden, "Form is not accepting responses")
+	case ErrInvalidSignature:
+		return fiber.NewError(fiber.StatusForbidden, "Invalid or expired download link")
+	case ErrNotFileQuestion:
+		return fiber.NewError(fiber.StatusBadRequest, "Not a file question")
+	case ErrInvalidInput:
+		return fiber.ErrBadRequest
+	case ErrTooLarge:
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "File too large")
+	case ErrTypeNotAllowed:
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "File type not allowed")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
@@ -0,0 +1,37 @@
+package uploads
+
+import "time"
+
+// Upload is a file a respondent uploaded to a file question. ResponseID is
+// set once a submitted response attaches it.
+type Upload struct {
+	ID               string
+	FormID           string
+	FlowConnectionID string
+	ResponseID       *string
+	StorageKey       string
+	Name             string
+	ContentType      string
+	Size             int64
+	CreatedAt        time.Time
+}
+
+// File is an upload as a file answer's answer_value lists it
+type File struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// SignedFile is an attached file with a time-limited download URL
+type SignedFile struct {
+	File
+	FlowConnectionID string    `json:"flow_connection_id"`
+	URL              string    `json:"url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func (u *Upload) file() File {
+	return File{ID: u.ID, Name: u.Name, Size: u.Size, ContentType: u.ContentType}
+}
@@ -0,0 +1,222 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UploadsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUploadsRepository(db *pgxpool.Pool) *UploadsRepository {
+	return &UploadsRepository{db: db}
+}
+
+// GetFormBySlug returns a published form's ID and whether it accepts
+// responses
+func (r *UploadsRepository) GetFormBySlug(ctx context.Context, slug string) (string, bool, error) {
+	var formID string
+	var accepting bool
+	err := r.db.QueryRow(ctx, `
+		SELECT id, accepting_responses
+		FROM forms
+		WHERE (auto_slug = $1 OR custom_slug = $1)
+		  AND status = 'published'
+		  AND deleted_at IS NULL
+	`, slug).Scan(&formID, &accepting)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", false, ErrFormNotFound
+		}
+		return "", false, err
+	}
+	return formID, accepting, nil
+}
+
+// GetQuestion returns the kind and validation rules of the question at a
+// flow connection of the form's published or archived versions
+func (r *UploadsRepository) GetQuestion(ctx context.Context, formID, flowConnectionID string) ([]byte, map[string]interface{}, error) {
+	var kind []byte
+	var rules map[string]interface{}
+	err := r.db.QueryRow(ctx, `
+		SELECT q.metadata->'kind', q.validation_rules
+		FROM flow_connections fc
+		JOIN form_versions v ON v.id = fc.version_id
+		JOIN questions q ON q.id = fc.question_id
+		WHERE fc.id = $1 AND fc.form_id = $2
+		  AND fc.deleted_at IS NULL
+		  AND v.status <> 'draft'
+	`, flowConnectionID, formID).Scan(&kind, &rules)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFileQuestion
+		}
+		return nil, nil, err
+	}
+	return kind, rules, nil
+}
+
+// Create records an upload whose blob is stored
+func (r *UploadsRepository) Create(ctx context.Context, u *Upload) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO response_uploads (id, form_id, flow_connection_id, storage_key, name, content_type, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, u.ID, u.FormID, u.FlowConnectionID, u.StorageKey, u.Name, u.ContentType, u.Size).Scan(&u.CreatedAt)
+}
+
+// Attach attaches unattached uploads of one flow connection to a response,
+// all or none, and returns them in the order of ids
+func (r *UploadsRepository) Attach(ctx context.Context, formID, flowConnectionID, responseID string, ids []string) ([]Upload, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE response_uploads
+		SET response_id = $1, attached_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = ANY($2::uuid[])
+		  AND form_id = $3
+		  AND flow_connection_id = $4
+		  AND response_id IS NULL
+		RETURNING id, form_id, flow_connection_id, response_id, storage_key, name, content_type, size, created_at
+	`, responseID, ids, formID, flowConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	byID, err := scanUploads(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Upload, len(ids))
+	for i, id := range ids {
+		u, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidUpload
+		}
+		result[i] = u
+	}
+	if len(byID) != len(ids) {
+		return nil, ErrInvalidUpload
+	}
+	return result, tx.Commit(ctx)
+}
+
+// Detach releases the uploads attached to a response that wasn't stored
+func (r *UploadsRepository) Detach(ctx context.Context, responseID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE response_uploads SET response_id = NULL, attached_at = NULL WHERE response_id = $1
+	`, responseID)
+	return err
+}
+
+// GetResponseForm returns the form a stored response belongs to
+func (r *UploadsRepository) GetResponseForm(ctx context.Context, responseID string) (string, error) {
+	var formID string
+	err := r.db.QueryRow(ctx, `SELECT form_id FROM form_responses WHERE id = $1`, responseID).Scan(&formID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return formID, nil
+}
+
+// ListByResponse returns the uploads attached to a response
+func (r *UploadsRepository) ListByResponse(ctx context.Context, responseID string) ([]Upload, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, form_id, flow_connection_id, response_id, storage_key, name, content_type, size, created_at
+		FROM response_uploads
+		WHERE response_id = $1
+		ORDER BY created_at, id
+	`, responseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := []Upload{}
+	for rows.Next() {
+		var u Upload
+		if err := rows.Scan(&u.ID, &u.FormID, &u.FlowConnectionID, &u.ResponseID, &u.StorageKey,
+			&u.Name, &u.ContentType, &u.Size, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// GetAttached returns an upload attached to a response
+func (r *UploadsRepository) GetAttached(ctx context.Context, id string) (*Upload, error) {
+	var u Upload
+	err := r.db.QueryRow(ctx, `
+		SELECT id, form_id, flow_connection_id, response_id, storage_key, name, content_type, size, created_at
+		FROM response_uploads
+		WHERE id = $1 AND response_id IS NOT NULL
+	`, id).Scan(&u.ID, &u.FormID, &u.FlowConnectionID, &u.ResponseID, &u.StorageKey,
+		&u.Name, &u.ContentType, &u.Size, &u.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ListStale returns uploads still unattached since before the given time
+func (r *UploadsRepository) ListStale(ctx context.Context, before time.Time, limit int) ([]Upload, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, form_id, flow_connection_id, response_id, storage_key, name, content_type, size, created_at
+		FROM response_uploads
+		WHERE response_id IS NULL AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	byID, err := scanUploads(rows)
+	if err != nil {
+		return nil, err
+	}
+	uploads := make([]Upload, 0, len(byID))
+	for _, u := range byID {
+		uploads = append(uploads, u)
+	}
+	return uploads, nil
+}
+
+// DeleteUnattached removes an upload's row unless a response attached it
+// meanwhile; reports whether it did
+func (r *UploadsRepository) DeleteUnattached(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM response_uploads WHERE id = $1 AND response_id IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func scanUploads(rows pgx.Rows) (map[string]Upload, error) {
+	defer rows.Close()
+	uploads := make(map[string]Upload)
+	for rows.Next() {
+		var u Upload
+		if err := rows.Scan(&u.ID, &u.FormID, &u.FlowConnectionID, &u.ResponseID, &u.StorageKey,
+			&u.Name, &u.ContentType, &u.Size, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		uploads[u.ID] = u
+	}
+	return uploads, rows.Err()
+}
@@ -0,0 +1,303 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"smart-forms/internal/blobstore"
+	"smart-forms/internal/kinds"
+
+	"github.com/google/uuid"
+)
+
+// Download links stay valid this long
+const URLTTL = 15 * time.Minute
+
+// Unattached uploads are purged after StaleAfter, PurgeBatch at a time
+const (
+	StaleAfter = 24 * time.Hour
+	PurgeBatch = 500
+)
+
+// MaxNameLength bounds a stored file name
+const MaxNameLength = 255
+
+// MaxBodySize bounds an upload request: a file of up to kinds.MaxFileSize
+// and the form fields
+const MaxBodySize = kinds.MaxFileSize + 1<<20
+
+type UploadsService struct {
+	repo  *UploadsRepository
+	store blobstore.Store
+	key   []byte // signs download links
+}
+
+func NewUploadsService(repo *UploadsRepository, store blobstore.Store, signingKey []byte) *UploadsService {
+	return &UploadsService{
+		repo:  repo,
+		store: store,
+		key:   signingKey,
+	}
+}
+
+// SigningKeyFromEnv returns the key download links are signed with, from
+// UPLOADS_SIGNING_KEY. Without one a random key is made, and links stop
+// working when the server restarts.
+func SigningKeyFromEnv() []byte {
+	if key := os.Getenv("UPLOADS_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	log.Println("Warning: UPLOADS_SIGNING_KEY not set, download links won't survive a restart")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal("Failed to generate upload signing key: ", err)
+	}
+	return key
+}
+
+// Upload stores a file for a file question of a published form, within the
+// question's size and type limits. Respondents list the returned file in
+// their answer's answer_value.
+func (s *UploadsService) Upload(ctx context.Context, slug, flowConnectionID string, header *multipart.FileHeader) (*File, error) {
+	if header == nil {
+		return nil, ErrInvalidInput
+	}
+	if _, err := uuid.Parse(flowConnectionID); err != nil {
+		return nil, ErrNotFileQuestion
+	}
+
+	formID, accepting, err := s.repo.GetFormBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if !accepting {
+		return nil, ErrFormNotAccepting
+	}
+
+	raw, rules, err := s.repo.GetQuestion(ctx, formID, flowConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if spec := kinds.Decode(raw); spec == nil || spec.Type != kinds.File {
+		return nil, ErrNotFileQuestion
+	}
+	limits := kinds.DecodeFileLimits(rules)
+	if header.Size > limits.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType, ok := contentType(head, header.Header.Get("Content-Type"))
+	if !ok || !limits.Allows(contentType) {
+		return nil, ErrTypeNotAllowed
+	}
+
+	u := &Upload{
+		ID:               uuid.NewString(),
+		FormID:           formID,
+		FlowConnectionID: flowConnectionID,
+		Name:             cleanName(header.Filename),
+		ContentType:      contentType,
+		Size:             header.Size,
+	}
+	u.StorageKey = formID + "/" + u.ID
+	body := io.MultiReader(bytes.NewReader(head), f)
+	if err := s.store.Put(ctx, u.StorageKey, body, u.Size, u.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, u); err != nil {
+		s.store.Delete(ctx, u.StorageKey)
+		return nil, err
+	}
+
+	file := u.file()
+	return &file, nil
+}
+
+// contentType decides a file's media type: the sniffed one, or the one the
+// client declared when sniffing can't tell (documents, archives, text...).
+// A declared type that sniffing recognizes (images, audio, video, PDF) must
+// match the content.
+func contentType(head []byte, declared string) (string, bool) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	declared, _, _ = mime.ParseMediaType(strings.ToLower(declared))
+
+	generic := sniffed == "application/octet-stream" || sniffed == "application/zip" || sniffed == "text/plain"
+	if !generic {
+		return sniffed, declared == "" || declared == sniffed || !sniffable(declared)
+	}
+	if declared == "" || sniffable(declared) {
+		return sniffed, declared == ""
+	}
+	return declared, true
+}
+
+// sniffable reports whether http.DetectContentType recognizes content of
+// this media type
+func sniffable(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "audio/") ||
+		strings.HasPrefix(contentType, "video/") || contentType == "application/pdf"
+}
+
+// cleanName keeps a file name's base, without control characters
+func cleanName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if len(name) > MaxNameLength {
+		name = strings.ToValidUTF8(name[:MaxNameLength], "")
+	}
+	return name
+}
+
+// Attach attaches a file answer's uploads to a response. Each must be an
+// unattached upload of the same form and flow connection. Returns the files
+// as stored.
+func (s *UploadsService) Attach(ctx context.Context, formID, flowConnectionID, responseID string, ids []string) ([]File, error) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil || seen[id] {
+			return nil, ErrInvalidUpload
+		}
+		seen[id] = true
+	}
+
+	uploads, err := s.repo.Attach(ctx, formID, flowConnectionID, responseID, ids)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, len(uploads))
+	for i := range uploads {
+		files[i] = uploads[i].file()
+	}
+	return files, nil
+}
+
+// Detach releases the uploads of a response that couldn't be stored, so
+// the respondent can submit them again
+func (s *UploadsService) Detach(ctx context.Context, responseID string) error {
+	return s.repo.Detach(ctx, responseID)
+}
+
+// ResponseFiles returns the form of a response (for the access check) and
+// its files with download links valid for URLTTL
+func (s *UploadsService) ResponseFiles(ctx context.Context, responseID string) (string, []SignedFile, error) {
+	if _, err := uuid.Parse(responseID); err != nil {
+		return "", nil, ErrNotFound
+	}
+	formID, err := s.repo.GetResponseForm(ctx, responseID)
+	if err != nil {
+		return "", nil, err
+	}
+	uploads, err := s.repo.ListByResponse(ctx, responseID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	expires := time.Now().Add(URLTTL).UTC().Truncate(time.Second)
+	files := make([]SignedFile, len(uploads))
+	for i, u := range uploads {
+		files[i] = SignedFile{
+			File:             u.file(),
+			FlowConnectionID: u.FlowConnectionID,
+			URL:              s.url(u.ID, expires),
+			ExpiresAt:        expires,
+		}
+	}
+	return formID, files, nil
+}
+
+// Open opens an attached file for a signed download link
+func (s *UploadsService) Open(ctx context.Context, id, expires, signature string) (*Upload, io.ReadCloser, error) {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return nil, nil, ErrInvalidSignature
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, s.sign(id, unix)) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	u, err := s.repo.GetAttached(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := s.store.Get(ctx, u.StorageKey)
+	if err == blobstore.ErrNotFound {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, body, nil
+}
+
+// PurgeStale deletes uploads no response attached within StaleAfter.
+// Returns how many it deleted.
+func (s *UploadsService) PurgeStale(ctx context.Context) (int, error) {
+	stale, err := s.repo.ListStale(ctx, time.Now().Add(-StaleAfter), PurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, u := range stale {
+		deleted, err := s.repo.DeleteUnattached(ctx, u.ID)
+		if err != nil {
+			return purged, err
+		}
+		if !deleted {
+			continue // attached meanwhile
+		}
+		if err := s.store.Delete(ctx, u.StorageKey); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *UploadsService) url(id string, expires time.Time) string {
+	unix := expires.Unix()
+	return "/files/" + id + "?expires=" + strconv.FormatInt(unix, 10) +
+		"&signature=" + hex.EncodeToString(s.sign(id, unix))
+}
+
+func (s *UploadsService) sign(id string, expires int64) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(id + "\n" + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
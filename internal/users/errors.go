@@ -0,0 +1,13 @@
+package users
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("user not found")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrCannotModifySelf  = errors.New("admins cannot change their own account here")
+	ErrLastRoleManager   = errors.New("cannot remove the last active user able to manage roles")
+	ErrCannotImpersonate = errors.New("user cannot be impersonated")
+	ErrInsufficientRole  = errors.New("role grants permissions the admin lacks")
+)
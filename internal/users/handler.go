@@ -0,0 +1,175 @@
+package users
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsersHandler struct {
+	service *UsersService
+}
+
+func NewUsersHandler(service *UsersService) *UsersHandler {
+	return &UsersHandler{service: service}
+}
+
+// ListUsers lists and searches users (users:manage)
+// GET /admin/users?search=&role=&status=active|inactive&limit=&offset=
+func (h *UsersHandler) ListUsers(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	filter := ListFilter{
+		Search: c.Query("search", ""),
+		Role:   c.Query("role", ""),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	switch c.Query("status", "") {
+	case "":
+	case "active":
+		active := true
+		filter.Active = &active
+	case "inactive":
+		active := false
+		filter.Active = &active
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "status must be active or inactive")
+	}
+
+	items, total, err := h.service.ListUsers(c.Context(), filter)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetUser retrieves a single user
+// GET /admin/users/:id
+func (h *UsersHandler) GetUser(c *fiber.Ctx) error {
+	user, err := h.service.GetUser(c.Context(), c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(user)
+}
+
+// DeactivateUser blocks a user from signing in
+// POST /admin/users/:id/deactivate
+func (h *UsersHandler) DeactivateUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	user, err := h.service.DeactivateUser(c.Context(), actorID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(user)
+}
+
+// ReactivateUser restores a deactivated user
+// POST /admin/users/:id/reactivate
+func (h *UsersHandler) ReactivateUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	user, err := h.service.ReactivateUser(c.Context(), actorID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(user)
+}
+
+// UpdateRole changes a user's role
+// PATCH /admin/users/:id/role
+func (h *UsersHandler) UpdateRole(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	var req UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	user, err := h.service.UpdateRole(c.Context(), actorID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(user)
+}
+
+// ForcePasswordReset invalidates the password and returns a one-time reset token
+// POST /admin/users/:id/password-reset
+func (h *UsersHandler) ForcePasswordReset(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	reset, err := h.service.ForcePasswordReset(c.Context(), actorID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(reset)
+}
+
+// DeleteUser permanently deletes a user and their forms
+// DELETE /admin/users/:id
+func (h *UsersHandler) DeleteUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	if err := h.service.DeleteUser(c.Context(), actorID, c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User deleted successfully",
+	})
+}
+
+// ImpersonateUser issues a short-lived access token acting as the user
+// POST /admin/users/:id/impersonate
+func (h *UsersHandler) ImpersonateUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	token, err := h.service.ImpersonateUser(
+		c.Context(),
+		actorID,
+		c.Params("id"),
+		c.IP(),
+		c.Get(fiber.HeaderUserAgent),
+	)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(token)
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrNotFound:
+		return fiber.ErrNotFound
+	case ErrInvalidInput:
+		return fiber.ErrBadRequest
+	case ErrInvalidRole:
+		return fiber.NewError(fiber.StatusBadRequest, "Role does not exist")
+	case ErrCannotModifySelf:
+		return fiber.NewError(fiber.StatusForbidden, "You cannot change your own account via the admin API")
+	case ErrInsufficientRole:
+		return fiber.NewError(fiber.StatusForbidden, "You cannot manage users or assign roles with permissions you lack")
+	case ErrCannotImpersonate:
+		return fiber.NewError(fiber.StatusForbidden, "Only active users without admin permissions can be impersonated")
+	case ErrLastRoleManager:
+		return fiber.NewError(fiber.StatusConflict, "Cannot remove the last active user with roles:manage")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
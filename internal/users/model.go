@@ -0,0 +1,55 @@
+package users
+
+import "time"
+
+// User is the admin view of an account
+type User struct {
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	IsActive              bool       `json:"is_active"`
+	MFAEnabled            bool       `json:"mfa_enabled"`
+	HasPassword           bool       `json:"has_password"` // false for social-login-only accounts
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	FormCount             int        `json:"form_count"`
+	ResponseCount         int        `json:"response_count"`
+	DeactivatedAt         *time.Time `json:"deactivated_at,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// ListFilter narrows the user list
+type ListFilter struct {
+	Search string // substring of email
+	Role   string
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// UpdateRoleRequest changes a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// PasswordReset is returned once when an admin forces a reset.
+// The token is handed to the user out of band and redeemed via POST /auth/password/reset.
+type PasswordReset struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ImpersonationToken is a short-lived access token acting as the target user
+type ImpersonationToken struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	User        *User     `json:"user"`
+}
+
+// deletedForm identifies a form removed with its owner (for cache invalidation)
+type deletedForm struct {
+	ID         string
+	AutoSlug   *string
+	CustomSlug *string
+	IsTemplate bool
+}
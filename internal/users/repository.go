@@ -0,0 +1,379 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UsersRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUsersRepository(db *pgxpool.Pool) *UsersRepository {
+	return &UsersRepository{db: db}
+}
+
+// Columns of the admin view, including per-user form and response counts
+const userSelect = `
+	SELECT u.id, u.email, u.role, u.is_active, u.mfa_enabled,
+	       u.password_hash <> '' AS has_password,
+	       u.password_reset_required,
+	       COALESCE(fc.form_count, 0), COALESCE(rc.response_count, 0),
+	       u.deactivated_at, u.created_at, u.updated_at
+	FROM users u
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS form_count
+		FROM forms f
+		WHERE f.user_id = u.id AND f.deleted_at IS NULL
+	) fc ON true
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS response_count
+		FROM form_responses r
+		JOIN forms f ON f.id = r.form_id
+		WHERE f.user_id = u.id AND f.deleted_at IS NULL
+	) rc ON true
+`
+
+func scanUser(row pgx.Row) (*User, error) {
+	var u User
+	err := row.Scan(
+		&u.ID,
+		&u.Email,
+		&u.Role,
+		&u.IsActive,
+		&u.MFAEnabled,
+		&u.HasPassword,
+		&u.PasswordResetRequired,
+		&u.FormCount,
+		&u.ResponseCount,
+		&u.DeactivatedAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// List retrieves users matching the filter, newest first
+func (r *UsersRepository) List(ctx context.Context, filter ListFilter) ([]User, int, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if filter.Search != "" {
+		args = append(args, filter.Search)
+		conditions = append(conditions, fmt.Sprintf("u.email ILIKE '%%' || $%d || '%%'", len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("u.role = $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, fmt.Sprintf("u.is_active = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users u"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	listArgs := append(args, filter.Limit, filter.Offset)
+	query := userSelect + where + fmt.Sprintf(" ORDER BY u.created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, listArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+
+	return users, total, rows.Err()
+}
+
+// GetByID retrieves a single user
+func (r *UsersRepository) GetByID(ctx context.Context, userID string) (*User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, userSelect+" WHERE u.id = $1", userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// RoleExists reports whether a role is defined
+func (r *UsersRepository) RoleExists(ctx context.Context, role string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, role).Scan(&exists)
+	return exists, err
+}
+
+// CountRolePermissions counts the permissions granted by a role
+func (r *UsersRepository) CountRolePermissions(ctx context.Context, role string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM role_permissions WHERE role_name = $1`, role).Scan(&count)
+	return count, err
+}
+
+// RoleHasPermission reports whether a role grants perm
+func (r *UsersRepository) RoleHasPermission(ctx context.Context, role, perm string) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM role_permissions
+			WHERE role_name = $1 AND permission = $2
+		)
+	`
+
+	var has bool
+	err := r.db.QueryRow(ctx, query, role, perm).Scan(&has)
+	return has, err
+}
+
+// RoleWithin reports whether every permission role grants is also granted
+// by other
+func (r *UsersRepository) RoleWithin(ctx context.Context, role, other string) (bool, error) {
+	const query = `
+		SELECT NOT EXISTS (
+			SELECT 1 FROM role_permissions rp
+			WHERE rp.role_name = $1
+			  AND NOT EXISTS (
+				SELECT 1 FROM role_permissions o
+				WHERE o.role_name = $2 AND o.permission = rp.permission
+			  )
+		)
+	`
+
+	var within bool
+	err := r.db.QueryRow(ctx, query, role, other).Scan(&within)
+	return within, err
+}
+
+// CountActiveWithPermission counts active users other than excludeUserID
+// whose role grants perm
+func (r *UsersRepository) CountActiveWithPermission(ctx context.Context, perm, excludeUserID string) (int, error) {
+	const query = `
+		SELECT COUNT(*)
+		FROM users u
+		JOIN role_permissions rp ON rp.role_name = u.role
+		WHERE rp.permission = $1 AND u.is_active = true AND u.id <> $2
+	`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, perm, excludeUserID).Scan(&count)
+	return count, err
+}
+
+// SetActive deactivates or reactivates a user.
+// Deactivation also revokes outstanding refresh tokens.
+func (r *UsersRepository) SetActive(ctx context.Context, userID string, active bool) error {
+	query := `
+		UPDATE users
+		SET is_active = true,
+		    deactivated_at = NULL,
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`
+	if !active {
+		query = `
+			UPDATE users
+			SET is_active = false,
+			    deactivated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+			    tokens_valid_after = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+			    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+			WHERE id = $1
+		`
+	}
+
+	cmd, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdateRole changes a user's role
+func (r *UsersRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	const query = `
+		UPDATE users
+		SET role = $1, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $2
+	`
+
+	cmd, err := r.db.Exec(ctx, query, role, userID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RequirePasswordReset stores a reset token hash, blocks password login and
+// revokes outstanding refresh tokens
+func (r *UsersRepository) RequirePasswordReset(
+	ctx context.Context,
+	userID string,
+	tokenHash string,
+	expiresAt time.Time,
+) error {
+
+	const query = `
+		UPDATE users
+		SET password_reset_required = true,
+		    password_reset_token_hash = $2,
+		    password_reset_expires_at = $3,
+		    tokens_valid_after = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`
+
+	cmd, err := r.db.Exec(ctx, query, userID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete permanently removes a user together with the workspaces only they
+// belong to (their personal workspace included) and those workspaces' forms
+// and responses. Shared workspaces keep their forms; if the user was their
+// only owner, the longest-standing admin (or member) becomes owner.
+// Returns the removed forms so callers can invalidate caches.
+func (r *UsersRepository) Delete(ctx context.Context, userID string) ([]deletedForm, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var soleWorkspaces []string
+	if err := tx.QueryRow(ctx, `
+		SELECT COALESCE(array_agg(m.workspace_id), '{}')
+		FROM workspace_members m
+		WHERE m.user_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM workspace_members o
+			WHERE o.workspace_id = m.workspace_id AND o.user_id <> $1
+		  )
+	`, userID).Scan(&soleWorkspaces); err != nil {
+		return nil, err
+	}
+
+	// response_answers -> flow_connections is ON DELETE RESTRICT, so answers
+	// must go before the forms' flow connections cascade away
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM response_answers
+		WHERE response_id IN (
+			SELECT r.id
+			FROM form_responses r
+			JOIN forms f ON f.id = r.form_id
+			WHERE f.workspace_id = ANY($1)
+		)
+	`, soleWorkspaces); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		DELETE FROM forms
+		WHERE workspace_id = ANY($1)
+		RETURNING id, auto_slug, custom_slug, is_template
+	`, soleWorkspaces)
+	if err != nil {
+		return nil, err
+	}
+
+	forms := []deletedForm{}
+	for rows.Next() {
+		var f deletedForm
+		if err := rows.Scan(&f.ID, &f.AutoSlug, &f.CustomSlug, &f.IsTemplate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		forms = append(forms, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM workspaces WHERE id = ANY($1)`, soleWorkspaces); err != nil {
+		return nil, err
+	}
+
+	// Shared workspaces must not end up without an owner
+	if _, err := tx.Exec(ctx, `
+		UPDATE workspace_members wm
+		SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (o.workspace_id) o.workspace_id, o.user_id
+			FROM workspace_members m
+			JOIN workspace_members o ON o.workspace_id = m.workspace_id AND o.user_id <> $1
+			WHERE m.user_id = $1
+			  AND m.role = 'owner'
+			  AND NOT EXISTS (
+				SELECT 1 FROM workspace_members x
+				WHERE x.workspace_id = m.workspace_id AND x.role = 'owner' AND x.user_id <> $1
+			  )
+			ORDER BY o.workspace_id, (o.role = 'admin') DESC, o.created_at
+		) heir
+		WHERE wm.workspace_id = heir.workspace_id AND wm.user_id = heir.user_id
+	`, userID); err != nil {
+		return nil, err
+	}
+
+	// analytics_status.triggered_by has no ON DELETE action
+	if _, err := tx.Exec(ctx, `DELETE FROM analytics_status WHERE triggered_by = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	cmd, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return forms, nil
+}
@@ -0,0 +1,358 @@
+package users
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"smart-forms/internal/audit"
+	"smart-forms/internal/auth"
+	"smart-forms/internal/cache"
+)
+
+// How long an admin-issued password reset token stays valid
+const passwordResetTTL = 24 * time.Hour
+
+type UsersService struct {
+	repo  *UsersRepository
+	cache *cache.Cache
+	audit *audit.AuditService
+}
+
+func NewUsersService(repo *UsersRepository, cacheClient *cache.Cache, auditService *audit.AuditService) *UsersService {
+	return &UsersService{
+		repo:  repo,
+		cache: cacheClient,
+		audit: auditService,
+	}
+}
+
+// ListUsers lists and searches users
+func (s *UsersService) ListUsers(ctx context.Context, filter ListFilter) ([]User, int, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.repo.List(ctx, filter)
+}
+
+// GetUser retrieves a user by ID
+func (s *UsersService) GetUser(ctx context.Context, userID string) (*User, error) {
+	return s.repo.GetByID(ctx, userID)
+}
+
+// DeactivateUser blocks sign-in and API key use
+func (s *UsersService) DeactivateUser(ctx context.Context, actorID, userID string) (*User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureWithinActor(ctx, actorID, user.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureAnotherRoleManager(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetActive(ctx, userID, false); err != nil {
+		return nil, err
+	}
+
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &userID,
+		Action:        audit.ActionUserDeactivate,
+	})
+
+	return s.repo.GetByID(ctx, userID)
+}
+
+// ReactivateUser restores access for a deactivated user
+func (s *UsersService) ReactivateUser(ctx context.Context, actorID, userID string) (*User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureWithinActor(ctx, actorID, user.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetActive(ctx, userID, true); err != nil {
+		return nil, err
+	}
+
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &userID,
+		Action:        audit.ActionUserReactivate,
+	})
+
+	return s.repo.GetByID(ctx, userID)
+}
+
+// UpdateRole assigns an existing role to a user. Both the user's current
+// role and the new one must grant no permission the admin lacks.
+// Takes effect on the user's next token refresh.
+func (s *UsersService) UpdateRole(ctx context.Context, actorID, userID string, req UpdateRoleRequest) (*User, error) {
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		return nil, ErrInvalidRole
+	}
+
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	exists, err := s.repo.RoleExists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureWithinActor(ctx, actorID, user.Role, role); err != nil {
+		return nil, err
+	}
+
+	keepsRoleManagement, err := s.repo.RoleHasPermission(ctx, role, auth.PermRolesManage)
+	if err != nil {
+		return nil, err
+	}
+	if !keepsRoleManagement {
+		if err := s.ensureAnotherRoleManager(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &userID,
+		Action:        audit.ActionUserRoleChange,
+		Metadata: map[string]interface{}{
+			"from": user.Role,
+			"to":   role,
+		},
+	})
+
+	return s.repo.GetByID(ctx, userID)
+}
+
+// ForcePasswordReset blocks password login until the user sets a new password
+// with the returned one-time token. The token takes over the account, so
+// the user's role must grant no permission the admin lacks.
+func (s *UsersService) ForcePasswordReset(ctx context.Context, actorID, userID string) (*PasswordReset, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureWithinActor(ctx, actorID, user.Role); err != nil {
+		return nil, err
+	}
+
+	token, hash, err := auth.GeneratePasswordResetToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().UTC().Add(passwordResetTTL)
+
+	// No audit entry, no token
+	if err := s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &userID,
+		Action:        audit.ActionUserPasswordReset,
+		Metadata: map[string]interface{}{
+			"expires_at": expiresAt,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RequirePasswordReset(ctx, userID, hash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &PasswordReset{
+		ResetToken: token,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// DeleteUser permanently deletes a user with all of their forms and responses
+func (s *UsersService) DeleteUser(ctx context.Context, actorID, userID string) error {
+	if actorID == userID {
+		return ErrCannotModifySelf
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureWithinActor(ctx, actorID, user.Role); err != nil {
+		return err
+	}
+
+	if err := s.ensureAnotherRoleManager(ctx, user); err != nil {
+		return err
+	}
+
+	forms, err := s.repo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_ = s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &userID,
+		Action:        audit.ActionUserDelete,
+		Metadata: map[string]interface{}{
+			"email": user.Email,
+			"forms": len(forms),
+		},
+	})
+
+	// Invalidate cached public forms
+	templatesChanged := false
+	for _, f := range forms {
+		s.cache.Delete(cache.FormIDKey(f.ID))
+		if f.AutoSlug != nil && *f.AutoSlug != "" {
+			s.cache.Delete(cache.FormSlugKey(*f.AutoSlug))
+		}
+		if f.CustomSlug != nil && *f.CustomSlug != "" {
+			s.cache.Delete(cache.FormSlugKey(*f.CustomSlug))
+		}
+		if f.IsTemplate {
+			templatesChanged = true
+		}
+	}
+	if templatesChanged {
+		s.cache.Delete("templates:list")
+	}
+
+	return nil
+}
+
+// ImpersonateUser issues a short-lived access token for the target user that
+// also names the acting admin. Inactive users and users whose role grants any
+// permission cannot be impersonated.
+func (s *UsersService) ImpersonateUser(ctx context.Context, actorID, userID, ip, userAgent string) (*ImpersonationToken, error) {
+	if actorID == userID {
+		return nil, ErrCannotImpersonate
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrCannotImpersonate
+	}
+
+	permissionCount, err := s.repo.CountRolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if permissionCount > 0 {
+		return nil, ErrCannotImpersonate
+	}
+
+	token, expiresAt, err := auth.GenerateImpersonationToken(user.ID, user.Role, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	// No audit entry, no token
+	if err := s.audit.Record(ctx, audit.Entry{
+		ActorID:       &actorID,
+		SubjectUserID: &user.ID,
+		Action:        audit.ActionImpersonationStart,
+		IPAddress:     &ip,
+		UserAgent:     &userAgent,
+		Metadata: map[string]interface{}{
+			"expires_at": expiresAt,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &ImpersonationToken{
+		AccessToken: token,
+		ExpiresAt:   expiresAt,
+		User:        user,
+	}, nil
+}
+
+// ensureWithinActor refuses admin actions involving roles that grant
+// permissions the admin lacks: users:manage must not reach super_admin, nor
+// hand out a role above the admin's own
+func (s *UsersService) ensureWithinActor(ctx context.Context, actorID string, roles ...string) error {
+	actor, err := s.repo.GetByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		within, err := s.repo.RoleWithin(ctx, role, actor.Role)
+		if err != nil {
+			return err
+		}
+		if !within {
+			return ErrInsufficientRole
+		}
+	}
+	return nil
+}
+
+// ensureAnotherRoleManager prevents locking everyone out of the admin API:
+// if user is an active holder of roles:manage, someone else must be too
+func (s *UsersService) ensureAnotherRoleManager(ctx context.Context, user *User) error {
+	if !user.IsActive {
+		return nil
+	}
+
+	manages, err := s.repo.RoleHasPermission(ctx, user.Role, auth.PermRolesManage)
+	if err != nil {
+		return err
+	}
+	if !manages {
+		return nil
+	}
+
+	count, err := s.repo.CountActiveWithPermission(ctx, auth.PermRolesManage, user.ID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastRoleManager
+	}
+	return nil
+}
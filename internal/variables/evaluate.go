@@ -0,0 +1,84 @@
+package variables
+
+import "math"
+
+// Step is one block a respondent passed, in the order they passed it
+type Step struct {
+	Variable string       // variable the answer is stored under, if any
+	Answer   *string      // the answer; nil when the block wasn't answered
+	Set      []Assignment // evaluated after the answer is stored
+}
+
+// Start returns the declared variables at their initial values
+func Start(decls []Variable) Env {
+	env := make(Env, len(decls))
+	for _, d := range decls {
+		v, err := d.initial()
+		if err != nil {
+			continue
+		}
+		env[d.Name] = v
+	}
+	return env
+}
+
+// Evaluate runs the steps of one respondent from the initial values: each
+// answer is stored under its variable (coerced to the declared type), then
+// the step's assignments run in order. An expression that fails at runtime
+// (division by zero, overflow) leaves its variable null rather than failing
+// the submission.
+func Evaluate(decls []Variable, steps []Step) Env {
+	env := Start(decls)
+
+	types := make(map[string]string, len(decls))
+	for _, d := range decls {
+		types[d.Name] = d.Type
+	}
+
+	for _, step := range steps {
+		if step.Variable != "" && step.Answer != nil {
+			env[step.Variable] = Coerce(types[step.Variable], *step.Answer)
+		}
+		for _, a := range step.Set {
+			env[a.Var] = assign(a, types[a.Var], env)
+		}
+	}
+
+	return env
+}
+
+func assign(a Assignment, varType string, env Env) interface{} {
+	e, err := Parse(a.Expr)
+	if err != nil {
+		return nil
+	}
+	v, err := e.Eval(env)
+	if err != nil {
+		return nil
+	}
+	// Overflow can't be stored as JSON
+	if n, ok := v.(float64); ok && (math.IsInf(n, 0) || math.IsNaN(n)) {
+		return nil
+	}
+	return convert(v, varType)
+}
+
+// convert fits a computed value to the declared type of its variable;
+// undeclared variables keep whatever the expression produced
+func convert(v interface{}, varType string) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch varType {
+	case TypeNumber:
+		if n, ok := toNumber(v); ok {
+			return n
+		}
+		return nil
+	case TypeText:
+		return Format(v)
+	case TypeBoolean:
+		return truthy(v)
+	}
+	return v
+}
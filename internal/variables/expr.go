@@ -0,0 +1,564 @@
+package variables
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxExprLength caps the source of one expression
+const MaxExprLength = 500
+
+// ErrDivisionByZero is returned by Eval for x / 0 and x % 0
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Expr is a parsed expression. The language has numbers, 'text' or "text",
+// true, false, null, variable names, parentheses and, by precedence:
+//
+//	cond ? a : b
+//	a || b
+//	a && b
+//	a == b, a != b, a < b, a <= b, a > b, a >= b
+//	a + b, a - b        (+ joins text if either side is text)
+//	a * b, a / b, a % b
+//	!a, -a
+//
+// and the functions min, max, round, floor, ceil, abs, number and text.
+// Unset variables are null, which counts as 0 in arithmetic.
+type Expr struct {
+	root node
+	refs []string
+}
+
+// Refs returns the variables the expression reads, in order of appearance
+func (e *Expr) Refs() []string {
+	return e.refs
+}
+
+// Eval computes the expression against env
+func (e *Expr) Eval(env Env) (interface{}, error) {
+	return e.root.eval(env)
+}
+
+// Parse parses an expression
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxExprLength {
+		return nil, fmt.Errorf("expression longer than %d characters", MaxExprLength)
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, seen: make(map[string]bool)}
+	root, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return &Expr{root: root, refs: p.refs}, nil
+}
+
+/*
+========================
+ LEXER
+========================
+*/
+
+const (
+	tokEOF = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", ","}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+
+		case c == '\'' || c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(src) && rune(src[i]) != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("unterminated text at %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String(), start})
+
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] < utf8.RuneSelf && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])))) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("unexpected %q at %d", string(r), i)
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+/*
+========================
+ PARSER
+========================
+*/
+
+type parser struct {
+	tokens []token
+	i      int
+	refs   []string
+	seen   map[string]bool
+	depth  int
+}
+
+// maxDepth stops deeply nested input before it exhausts the stack
+const maxDepth = 50
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.i++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d, got %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) ternary() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond, then, otherwise}, nil
+}
+
+// binary operators by precedence level, loosest first
+var levels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(levels) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(levels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op, left, right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, errors.New("expression nested too deeply")
+		}
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op, operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return &literalNode{n}, nil
+
+	case tokString:
+		return &literalNode{t.text}, nil
+
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.call(t)
+		}
+		if !p.seen[t.text] {
+			p.seen[t.text] = true
+			p.refs = append(p.refs, t.text)
+		}
+		return &varNode{t.text}, nil
+
+	case tokOp:
+		if t.text == "(" {
+			inner, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+
+	var args []node
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s at %d", name.text, name.pos)
+	}
+	return &callNode{fn, args}, nil
+}
+
+/*
+========================
+ EVALUATION
+========================
+*/
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(Env) (interface{}, error) { return n.value, nil }
+
+type varNode struct{ name string }
+
+func (n *varNode) eval(env Env) (interface{}, error) { return env[n.name], nil }
+
+type condNode struct{ cond, then, otherwise node }
+
+func (n *condNode) eval(env Env) (interface{}, error) {
+	c, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(v), nil
+	}
+	return -number(v), nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit and yield booleans
+	switch n.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := n.right.eval(env)
+		return truthy(r), err
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := n.right.eval(env)
+		return truthy(r), err
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return Format(l) + Format(r), nil
+		}
+		return number(l) + number(r), nil
+	case "-":
+		return number(l) - number(r), nil
+	case "*":
+		return number(l) * number(r), nil
+	case "/":
+		if number(r) == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return number(l) / number(r), nil
+	case "%":
+		if number(r) == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return math.Mod(number(l), number(r)), nil
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	default:
+		c := compare(l, r)
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+}
+
+// number converts a value for arithmetic; null and unparsable text are 0
+func number(v interface{}) float64 {
+	n, _ := toNumber(v)
+	return n
+}
+
+// equal compares numbers numerically and everything else by its text, so a
+// text answer "3" equals 3
+func equal(l, r interface{}) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if lok && rok {
+		return ln == rn
+	}
+	return Format(l) == Format(r)
+}
+
+// compare orders numbers numerically, anything else by text
+func compare(l, r interface{}) int {
+	_, lok := toNumber(l)
+	_, rok := toNumber(r)
+	if lok && rok || l == nil || r == nil {
+		switch ln, rn := number(l), number(r); {
+		case ln < rn:
+			return -1
+		case ln > rn:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(Format(l), Format(r))
+}
+
+/*
+========================
+ FUNCTIONS
+========================
+*/
+
+type function struct {
+	minArgs, maxArgs int // maxArgs -1: any
+	call             func(args []interface{}) interface{}
+}
+
+type callNode struct {
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args), nil
+}
+
+var functions = map[string]function{
+	"min": {1, -1, func(args []interface{}) interface{} {
+		m := number(args[0])
+		for _, a := range args[1:] {
+			m = math.Min(m, number(a))
+		}
+		return m
+	}},
+	"max": {1, -1, func(args []interface{}) interface{} {
+		m := number(args[0])
+		for _, a := range args[1:] {
+			m = math.Max(m, number(a))
+		}
+		return m
+	}},
+	// round(x) or round(x, decimals)
+	"round": {1, 2, func(args []interface{}) interface{} {
+		scale := 1.0
+		if len(args) == 2 {
+			scale = math.Pow(10, math.Round(number(args[1])))
+		}
+		return math.Round(number(args[0])*scale) / scale
+	}},
+	"floor": {1, 1, func(args []interface{}) interface{} { return math.Floor(number(args[0])) }},
+	"ceil":  {1, 1, func(args []interface{}) interface{} { return math.Ceil(number(args[0])) }},
+	"abs":   {1, 1, func(args []interface{}) interface{} { return math.Abs(number(args[0])) }},
+	// number(x) is null when x isn't a number
+	"number": {1, 1, func(args []interface{}) interface{} {
+		if n, ok := toNumber(args[0]); ok {
+			return n
+		}
+		return nil
+	}},
+	"text": {1, 1, func(args []interface{}) interface{} { return Format(args[0]) }},
+}
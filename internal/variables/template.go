@@ -0,0 +1,47 @@
+package variables
+
+import (
+	"regexp"
+	"strings"
+)
+
+// placeholder matches {{ name }}; anything else between braces is left alone
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// TemplateRefs returns the variables a text refers to, in order of appearance
+func TemplateRefs(text string) []string {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+	var refs []string
+	seen := make(map[string]bool)
+	for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// Render replaces each {{ name }} with the variable's value; unset variables
+// render as empty text
+func Render(text string, env Env) string {
+	return RenderWith(text, env, nil)
+}
+
+// RenderWith is Render with every value passed through escape, for texts
+// that end up in HTML or URLs
+func RenderWith(text string, env Env, escape func(string) string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		value := Format(env[name])
+		if escape != nil {
+			value = escape(value)
+		}
+		return value
+	})
+}
@@ -0,0 +1,176 @@
+// Package variables implements flow variables: answers stored under a name,
+// computed hidden variables (a running price, a score) and {{name}}
+// templates in question texts. Everything here is pure; flows store the
+// definitions and responses evaluate them per respondent.
+package variables
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variable types
+const (
+	TypeNumber  = "number"
+	TypeText    = "text"
+	TypeBoolean = "boolean"
+)
+
+// MaxVariables caps the declarations of one flow
+const MaxVariables = 100
+
+// Variable declares a computed (hidden) variable of a flow, or gives an
+// answer variable a type. Undeclared answer variables are text.
+type Variable struct {
+	Name    string      `json:"name" yaml:"name"`
+	Type    string      `json:"type" yaml:"type"`                           // number | text | boolean
+	Initial interface{} `json:"initial,omitempty" yaml:"initial,omitempty"` // zero value of the type when omitted
+}
+
+// Assignment sets a variable to an expression when a respondent passes the
+// block it belongs to, e.g. {"var": "price", "expr": "price + 20"}
+type Assignment struct {
+	Var  string `json:"var" yaml:"var"`
+	Expr string `json:"expr" yaml:"expr"`
+}
+
+// Env holds variable values: float64, string, bool or nil
+type Env map[string]interface{}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// ValidName reports whether name can be used as a variable name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name) && !keywords[name]
+}
+
+// keywords can't be variable names
+var keywords = map[string]bool{"true": true, "false": true, "null": true}
+
+// Check validates declarations: names, types, initial values and duplicates
+func Check(vars []Variable) error {
+	if len(vars) > MaxVariables {
+		return fmt.Errorf("at most %d variables", MaxVariables)
+	}
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if !ValidName(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %q declared twice", v.Name)
+		}
+		seen[v.Name] = true
+		if _, err := v.initial(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initial returns the starting value of a declared variable
+func (v Variable) initial() (interface{}, error) {
+	switch v.Type {
+	case TypeNumber:
+		if v.Initial == nil {
+			return 0.0, nil
+		}
+		if n, ok := toNumber(v.Initial); ok {
+			return n, nil
+		}
+	case TypeText:
+		if v.Initial == nil {
+			return "", nil
+		}
+		if s, ok := v.Initial.(string); ok {
+			return s, nil
+		}
+	case TypeBoolean:
+		if v.Initial == nil {
+			return false, nil
+		}
+		if b, ok := v.Initial.(bool); ok {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("variable %q: type must be number, text or boolean", v.Name)
+	}
+	return nil, fmt.Errorf("variable %q: initial value is not a %s", v.Name, v.Type)
+}
+
+// Coerce converts an answer to the type of its variable; answers that don't
+// parse (a number variable answered "lots") become nil
+func Coerce(varType, answer string) interface{} {
+	answer = strings.TrimSpace(answer)
+	switch varType {
+	case TypeNumber:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(answer, ",", "."), 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil
+		}
+		return n
+	case TypeBoolean:
+		switch strings.ToLower(answer) {
+		case "true", "yes", "1", "y":
+			return true
+		case "false", "no", "0", "n":
+			return false
+		}
+		return nil
+	default:
+		return answer
+	}
+}
+
+// Format renders a value the way templates show it
+func Format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil && !math.IsInf(n, 0) && !math.IsNaN(n)
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
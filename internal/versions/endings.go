@@ -0,0 +1,102 @@
+package versions
+
+import (
+	"strconv"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/variables"
+)
+
+// endingBuiltins are the variables endings can use besides the flow's own;
+// a flow variable of the same name wins
+var endingBuiltins = []string{"response_id", "score", "max_score", "percent", "passed", "outcome"}
+
+// Ending picks a respondent's ending and renders it with their variables:
+// the ending of the last node on path that names one, else the first whose
+// score range holds their percent (quiz forms), else the default. Returns
+// nil when none applies.
+func (l *Logic) Ending(path []string, env variables.Env, score *quiz.Result, responseID string) *endings.Rendered {
+	named := make(map[string]string)
+	for _, n := range l.Nodes {
+		if n.Ending != "" {
+			named[n.ID] = n.Ending
+		}
+	}
+	nodeEnding := ""
+	for i := len(path) - 1; i >= 0; i-- {
+		if id, ok := named[path[i]]; ok {
+			nodeEnding = id
+			break
+		}
+	}
+
+	var percent *float64
+	if score != nil {
+		percent = &score.Percent
+	}
+
+	e := endings.Select(l.Endings, nodeEnding, percent)
+	if e == nil {
+		return nil
+	}
+
+	values := make(variables.Env, len(env)+len(endingBuiltins))
+	values["response_id"] = responseID
+	if score != nil {
+		values["score"] = score.Score
+		values["max_score"] = score.MaxScore
+		values["percent"] = score.Percent
+		if score.Passed != nil {
+			values["passed"] = *score.Passed
+		}
+		values["outcome"] = score.Outcome
+	}
+	for name, v := range env {
+		values[name] = v
+	}
+	return e.Render(values)
+}
+
+// checkEndings reports bad ending definitions, nodes naming endings that
+// don't exist or that aren't where the flow ends, and templates reading
+// variables nothing sets
+func (l *linter) checkEndings(nodes []Node, ends []endings.Ending, known map[string]bool) {
+	if err := endings.Check(ends); err != nil {
+		l.add(SeverityError, "invalid_endings", "/endings", nil, "Invalid endings: "+err.Error())
+	}
+
+	defined := make(map[string]bool, len(ends))
+	for _, e := range ends {
+		defined[e.ID] = true
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Ending == "" {
+			continue
+		}
+		path := l.paths[n.ID]
+		if !defined[n.Ending] {
+			l.add(SeverityError, "unknown_ending", path, n, "Ending \""+n.Ending+"\" is not defined")
+			continue
+		}
+		if len(l.children[n.ID]) > 0 || len(n.Next) > 0 {
+			l.add(SeverityWarning, "ending_not_terminal", path, n, "Block has an ending but the flow continues after it")
+		}
+	}
+
+	builtin := make(map[string]bool, len(endingBuiltins))
+	for _, name := range endingBuiltins {
+		builtin[name] = true
+	}
+	for i, e := range ends {
+		reported := make(map[string]bool)
+		for _, name := range e.TemplateRefs() {
+			if !known[name] && !builtin[name] && !reported[name] {
+				reported[name] = true
+				l.add(SeverityWarning, "unknown_variable", "/endings/"+strconv.Itoa(i), nil,
+					"Variable \""+name+"\" is never set")
+			}
+		}
+	}
+}
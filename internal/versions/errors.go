@@ -0,0 +1,15 @@
+package versions
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("version not found")
+	ErrFormNotFound     = errors.New("form not found")
+	ErrNoDraft          = errors.New("form has no draft version")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrAlreadyPublished = errors.New("version is already published")
+	ErrDraftRollback    = errors.New("cannot roll back to a draft version")
+	ErrPrecondition     = errors.New("flow was changed since it was read")
+	ErrInvalidVariants  = errors.New("invalid variants")
+	ErrDraftVariant     = errors.New("a variant cannot serve a draft version")
+)
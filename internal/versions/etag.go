@@ -0,0 +1,24 @@
+package versions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ETag identifies the state of a version's flow: the version number plus
+// the number of saves into it
+func ETag(number, revision int) string {
+	return fmt.Sprintf(`"%d.%d"`, number, revision)
+}
+
+// ETagMatches reports whether an If-Match header value matches etag.
+// Accepts "*", comma-separated lists and weak validators.
+func ETagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
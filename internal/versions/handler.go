@@ -0,0 +1,203 @@
+package versions
+
+import (
+	"errors"
+	"strconv"
+
+	"smart-forms/internal/collaborators"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VersionsHandler struct {
+	service *VersionsService
+	access  *collaborators.AccessChecker
+}
+
+func NewVersionsHandler(service *VersionsService, access *collaborators.AccessChecker) *VersionsHandler {
+	return &VersionsHandler{
+		service: service,
+		access:  access,
+	}
+}
+
+// ListVersions lists a form's versions
+// GET /forms/:form_id/versions
+func (h *VersionsHandler) ListVersions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	versions, err := h.service.ListVersions(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(versions)
+}
+
+// GetVersion returns a version with its flow
+// GET /forms/:form_id/versions/:version
+func (h *VersionsHandler) GetVersion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	number, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	version, err := h.service.GetVersion(c.Context(), formID, number)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(version)
+}
+
+// DiffVersions compares two versions
+// GET /forms/:form_id/versions/diff?from=1&to=2
+func (h *VersionsHandler) DiffVersions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	diff, err := h.service.DiffVersions(c.Context(), formID, c.QueryInt("from"), c.QueryInt("to"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(diff)
+}
+
+// PublishDraft makes the draft the live version (the public link is kept)
+// POST /forms/:form_id/versions/publish
+func (h *VersionsHandler) PublishDraft(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	version, err := h.service.PublishDraft(c.Context(), formID, userID)
+	if err != nil {
+		if ok, resp := LintFailed(c, err); ok {
+			return resp
+		}
+		return mapServiceError(err)
+	}
+
+	return c.JSON(version)
+}
+
+// Rollback makes an archived version live again
+// POST /forms/:form_id/versions/:version/rollback
+func (h *VersionsHandler) Rollback(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	number, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	version, err := h.service.Rollback(c.Context(), formID, userID, number)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(version)
+}
+
+// ListVariants returns the form's A/B test variants
+// GET /forms/:form_id/variants
+func (h *VersionsHandler) ListVariants(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.ViewForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	variants, err := h.service.ListVariants(c.Context(), formID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{"variants": variants})
+}
+
+// SetVariants replaces the form's A/B test variants
+// PUT /forms/:form_id/variants
+func (h *VersionsHandler) SetVariants(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	formID := c.Params("form_id")
+
+	var req VariantsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.access.Require(c.Context(), userID, formID, collaborators.EditForm); err != nil {
+		return collaborators.AccessError(err)
+	}
+
+	variants, err := h.service.SetVariants(c.Context(), formID, req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{"variants": variants})
+}
+
+// LintFailed answers a publish the linter rejected with 422 and the lint
+// report. It returns false if err is not a LintError.
+func LintFailed(c *fiber.Ctx, err error) (bool, error) {
+	var lintErr *LintError
+	if !errors.As(err, &lintErr) {
+		return false, nil
+	}
+	return true, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"message": "Flow has errors; fix them before publishing",
+		"lint":    lintErr.Report,
+	})
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Version not found")
+	case ErrFormNotFound:
+		return fiber.ErrNotFound
+	case ErrInvalidInput:
+		return fiber.NewError(fiber.StatusBadRequest, "from and to must be version numbers")
+	case ErrNoDraft:
+		return fiber.NewError(fiber.StatusConflict, "No draft to publish")
+	case ErrAlreadyPublished:
+		return fiber.NewError(fiber.StatusConflict, "Version is already published")
+	case ErrDraftRollback:
+		return fiber.NewError(fiber.StatusBadRequest, "Cannot roll back to an unpublished draft")
+	case ErrInvalidVariants:
+		return fiber.NewError(fiber.StatusBadRequest,
+			"Variants need 2-10 unique keys (a-z, 0-9, _ or -) and versions, and weights 0-1000 adding up to more than 0")
+	case ErrDraftVariant:
+		return fiber.NewError(fiber.StatusBadRequest, "Variants must use published or archived versions")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
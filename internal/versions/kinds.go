@@ -0,0 +1,50 @@
+package versions
+
+import (
+	"strings"
+
+	"smart-forms/internal/kinds"
+)
+
+// CheckAnswer reports whether an answer's value fits the kind of the
+// question it answers; questions without a kind take any value
+func (l *Logic) CheckAnswer(id string, value map[string]interface{}) error {
+	for _, n := range l.Nodes {
+		if n.ID == id && n.Kind != nil {
+			return n.Kind.Check(value)
+		}
+	}
+	return nil
+}
+
+// checkKinds reports invalid question kinds and kinds on blocks that aren't
+// questions
+func (l *linter) checkKinds(nodes []Node) {
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Kind == nil {
+			continue
+		}
+		path := l.paths[n.ID]
+
+		if n.Type != TypeQuestion {
+			l.add(SeverityError, "kind_not_question", path, n, "Only questions can have a kind")
+			continue
+		}
+		if err := n.Kind.Validate(); err != nil {
+			l.add(SeverityError, "invalid_kind", path, n,
+				"Invalid question kind: "+strings.TrimPrefix(err.Error(), kinds.ErrInvalidSpec.Error()+": "))
+		}
+	}
+}
+
+// Kind returns the kind of the question at a flow connection, nil if it has
+// none
+func (l *Logic) Kind(id string) *kinds.Spec {
+	for _, n := range l.Nodes {
+		if n.ID == id {
+			return n.Kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package versions
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/variables"
+)
+
+// Block types the linter knows about
+const (
+	TypeQuestion = "question"
+	TypeOption   = "option"
+	TypeFragment = "fragment" // embeds a flow fragment, see ExpandDraft
+)
+
+// MaxFlowDepth is the deepest nesting a flow may have (root = 0)
+const MaxFlowDepth = 15
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// LintIssue is a problem found in a flow. Path points at the block in the
+// blocks tree, e.g. "/blocks/0/children/2".
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path"`
+	BlockID  string `json:"block_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintReport lists a flow's problems. Errors block publishing; warnings don't.
+type LintReport struct {
+	Valid    bool        `json:"valid"`
+	Errors   []LintIssue `json:"errors"`
+	Warnings []LintIssue `json:"warnings"`
+}
+
+// LintError rejects publishing a flow with lint errors
+type LintError struct {
+	Report *LintReport
+}
+
+func (e *LintError) Error() string {
+	return "flow has " + strconv.Itoa(len(e.Report.Errors)) + " lint error(s)"
+}
+
+type linter struct {
+	byID     map[string]*Node
+	children map[string][]*Node // parent ID ("" = root) -> children in order
+	paths    map[string]string
+	report   *LintReport
+}
+
+// Lint checks a flow's structure: node types and placement, depth, duplicate
+// options, jumps, reachability and where the flow ends; its variables:
+// declarations, names, expressions and the variables they refer to; its
+// quiz scoring and score routing; its randomization; its question kinds;
+// and its endings
+func Lint(nodes []Node, vars []variables.Variable, ends []endings.Ending) *LintReport {
+	l := &linter{
+		byID:     make(map[string]*Node, len(nodes)),
+		children: make(map[string][]*Node),
+		paths:    make(map[string]string, len(nodes)),
+		report:   &LintReport{Errors: []LintIssue{}, Warnings: []LintIssue{}},
+	}
+
+	for i := range nodes {
+		n := &nodes[i]
+		l.byID[n.ID] = n
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		key := ""
+		if n.ParentID != nil {
+			key = *n.ParentID
+		}
+		l.children[key] = append(l.children[key], n)
+	}
+	for _, siblings := range l.children {
+		sort.SliceStable(siblings, func(i, j int) bool { return siblings[i].OrderIndex < siblings[j].OrderIndex })
+	}
+
+	if len(nodes) == 0 {
+		l.add(SeverityError, "empty_flow", "/blocks", nil, "Flow has no blocks")
+		return l.finish()
+	}
+
+	l.walk("", "/blocks", 0)
+	l.checkGraph()
+	known := l.checkVariables(nodes, vars)
+	l.checkQuiz(nodes)
+	l.checkRandomize(nodes)
+	l.checkKinds(nodes)
+	l.checkEndings(nodes, ends, known)
+
+	return l.finish()
+}
+
+func (l *linter) add(severity, code, path string, n *Node, message string) {
+	issue := LintIssue{Severity: severity, Code: code, Path: path, Message: message}
+	if n != nil {
+		issue.BlockID = n.ID
+	}
+	if severity == SeverityError {
+		l.report.Errors = append(l.report.Errors, issue)
+	} else {
+		l.report.Warnings = append(l.report.Warnings, issue)
+	}
+}
+
+func (l *linter) finish() *LintReport {
+	l.report.Valid = len(l.report.Errors) == 0
+	return l.report
+}
+
+// walk checks every block against its parent and siblings
+func (l *linter) walk(parentID, parentPath string, depth int) {
+	var parent *Node
+	if parentID != "" {
+		parent = l.byID[parentID]
+	}
+
+	siblings := l.children[parentID]
+	seen := make(map[string]bool, len(siblings))
+	continues := false
+	for _, n := range siblings {
+		if len(l.children[n.ID]) > 0 || len(n.Next) > 0 {
+			continues = true
+		}
+	}
+
+	for i, n := range siblings {
+		path := parentPath + "/" + strconv.Itoa(i)
+		if parentID != "" {
+			path = parentPath + "/children/" + strconv.Itoa(i)
+		}
+		l.paths[n.ID] = path
+
+		text := strings.TrimSpace(n.Question)
+		hasChildren := len(l.children[n.ID]) > 0
+		hasJumps := len(n.Next) > 0
+
+		switch {
+		case n.Type == "":
+			l.add(SeverityError, "missing_type", path, n, "Block has no type")
+		case n.Type != TypeQuestion && n.Type != TypeOption && n.Type != TypeFragment:
+			l.add(SeverityWarning, "unknown_type", path, n, "Unknown block type \""+n.Type+"\"")
+		}
+		if text == "" {
+			l.add(SeverityError, "empty_text", path, n, "Block has no text")
+		}
+
+		if n.Type == TypeOption && (parent == nil || parent.Type != TypeQuestion) {
+			l.add(SeverityError, "option_outside_question", path, n, "Option is not under a question")
+		}
+
+		if depth == MaxFlowDepth+1 {
+			l.add(SeverityError, "excessive_depth", path, n,
+				"Flow is nested deeper than "+strconv.Itoa(MaxFlowDepth)+" levels")
+		}
+
+		// Same type and text twice under one parent
+		key := n.Type + "\x00" + strings.ToLower(text)
+		if text != "" && seen[key] {
+			if n.Type == TypeOption {
+				l.add(SeverityError, "duplicate_option", path, n, "Duplicate option \""+text+"\"")
+			} else {
+				l.add(SeverityWarning, "duplicate_sibling", path, n, "Duplicate block \""+text+"\" under the same parent")
+			}
+		}
+		seen[key] = true
+
+		if n.Type == TypeQuestion && !hasChildren && !hasJumps {
+			l.add(SeverityWarning, "empty_question", path, n, "Question has no options or follow-up, so the flow ends here")
+		}
+		if n.Type == TypeOption && !hasChildren && !hasJumps && continues {
+			l.add(SeverityWarning, "empty_branch", path, n, "Branch ends here while sibling branches continue")
+		}
+
+		l.walk(n.ID, path, depth+1)
+	}
+}
+
+// checkGraph checks jumps, cycles, reachability from the first block and
+// that the flow can end
+func (l *linter) checkGraph() {
+	successors := make(map[string][]string, len(l.byID))
+	for id, n := range l.byID {
+		for _, child := range l.children[id] {
+			successors[id] = append(successors[id], child.ID)
+		}
+		for _, target := range n.Next {
+			if _, ok := l.byID[target]; !ok {
+				l.add(SeverityError, "unknown_jump_target", l.paths[id], n, "Jump target "+target+" is not in the flow")
+				continue
+			}
+			successors[id] = append(successors[id], target)
+		}
+	}
+
+	if HasCycle(successors) {
+		l.add(SeverityError, "cycle", "/blocks", nil, "Flow contains a cycle")
+	}
+
+	// Respondents start at the first block
+	entry := l.children[""][0]
+	reachable := map[string]bool{}
+	stack := []string{entry.ID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[id] {
+			continue
+		}
+		reachable[id] = true
+		stack = append(stack, successors[id]...)
+	}
+
+	ends := false
+	for id := range reachable {
+		if len(successors[id]) == 0 {
+			ends = true
+			break
+		}
+	}
+	if !ends {
+		l.add(SeverityError, "missing_terminal", "/blocks", nil, "No path through the flow ever ends")
+	}
+
+	// Report only the top of each unreachable subtree
+	var unreachable []*Node
+	for id, n := range l.byID {
+		if reachable[id] {
+			continue
+		}
+		if n.ParentID == nil || reachable[*n.ParentID] {
+			unreachable = append(unreachable, n)
+		}
+	}
+	sort.Slice(unreachable, func(i, j int) bool {
+		return l.paths[unreachable[i].ID] < l.paths[unreachable[j].ID]
+	})
+	for _, n := range unreachable {
+		l.add(SeverityWarning, "unreachable", l.paths[n.ID], n, "Block can't be reached from the first block")
+	}
+}
+
+// HasCycle reports whether following successors can lead back to a node
+func HasCycle(successors map[string][]string) bool {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(successors))
+
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		switch state[id] {
+		case visiting:
+			return true
+		case done:
+			return false
+		}
+		state[id] = visiting
+		for _, next := range successors[id] {
+			if visit(next) {
+				return true
+			}
+		}
+		state[id] = done
+		return false
+	}
+
+	for id := range successors {
+		if visit(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVariables checks declarations, answer variables and assignments, and
+// warns about templates and expressions reading variables nothing sets (they
+// are null, so templates show nothing). Returns the names something
+// declares, stores or assigns.
+func (l *linter) checkVariables(nodes []Node, vars []variables.Variable) map[string]bool {
+	if err := variables.Check(vars); err != nil {
+		l.add(SeverityError, "invalid_variables", "/variables", nil, "Invalid variable declarations: "+err.Error())
+	}
+
+	known := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		known[v.Name] = true
+	}
+	for _, n := range nodes {
+		if n.Variable != "" {
+			known[n.Variable] = true
+		}
+		for _, a := range n.Set {
+			known[a.Var] = true
+		}
+	}
+
+	// Sorted by path so reports are stable
+	sorted := make([]*Node, 0, len(nodes))
+	for i := range nodes {
+		sorted = append(sorted, &nodes[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return l.paths[sorted[i].ID] < l.paths[sorted[j].ID] })
+
+	for _, n := range sorted {
+		path := l.paths[n.ID]
+		var refs []string
+
+		if n.Variable != "" {
+			switch {
+			case !variables.ValidName(n.Variable):
+				l.add(SeverityError, "invalid_variable", path, n, "Invalid variable name \""+n.Variable+"\"")
+			case n.Type == TypeOption:
+				l.add(SeverityError, "option_variable", path, n, "Options can't store a variable; set it on their question")
+			}
+		}
+
+		for _, a := range n.Set {
+			if !variables.ValidName(a.Var) {
+				l.add(SeverityError, "invalid_variable", path, n, "Invalid variable name \""+a.Var+"\"")
+			}
+			e, err := variables.Parse(a.Expr)
+			if err != nil {
+				l.add(SeverityError, "invalid_expression", path, n, "Invalid expression for "+a.Var+": "+err.Error())
+				continue
+			}
+			refs = append(refs, e.Refs()...)
+		}
+
+		refs = append(refs, variables.TemplateRefs(n.Question)...)
+		reported := make(map[string]bool)
+		for _, name := range refs {
+			if !known[name] && !reported[name] {
+				reported[name] = true
+				l.add(SeverityWarning, "unknown_variable", path, n, "Variable \""+name+"\" is never set")
+			}
+		}
+	}
+	return known
+}
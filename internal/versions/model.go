@@ -0,0 +1,142 @@
+package versions
+
+import (
+	"time"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/randomize"
+	"smart-forms/internal/variables"
+)
+
+// Version statuses. A form has at most one draft and one published version;
+// published and archived versions never change.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Version is one revision of a form's flow
+type Version struct {
+	ID            string     `json:"id"`
+	FormID        string     `json:"form_id"`
+	Number        int        `json:"version"`
+	Status        string     `json:"status"`
+	CreatedBy     *string    `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+	PublishedBy   *string    `json:"published_by,omitempty"`
+	ResponseCount int        `json:"response_count"`
+}
+
+// Node is a flow connection with its question, as frozen in a snapshot
+type Node struct {
+	ID         string  `json:"id"` // flow connection ID (what answers reference)
+	ParentID   *string `json:"parent_id,omitempty"`
+	OrderIndex int     `json:"order_index"`
+	DepthLevel int     `json:"depth_level"`
+	IsTerminal bool    `json:"is_terminal"`
+	QuestionID string  `json:"question_id"`
+	Type       string  `json:"type"`
+	Question   string  `json:"question"`
+	// Jump targets: after this node the flow continues at these nodes
+	// (besides its children); a node with several incoming paths is a
+	// merge point
+	Next []string `json:"next,omitempty"`
+	// Fragment embeds: the fragment and pinned version (nil = latest) of a
+	// fragment node, and for nodes expanded from one on publish, that node
+	FragmentID      *string `json:"fragment_id,omitempty"`
+	FragmentVersion *int    `json:"fragment_version,omitempty"`
+	GeneratedBy     *string `json:"generated_by,omitempty"`
+	// Flow variables: the variable the answer is stored under and the
+	// assignments run when a respondent passes the node
+	Variable string                 `json:"variable,omitempty"`
+	Set      []variables.Assignment `json:"set,omitempty"`
+	// Quiz scoring, from the question's metadata when the version was frozen
+	Quiz *quiz.Scoring `json:"quiz,omitempty"`
+	// Ending shown to respondents who finish at this node
+	Ending string `json:"ending,omitempty"`
+	// How the node's children are shuffled or sampled per respondent
+	Randomize *randomize.Settings `json:"randomize,omitempty"`
+	// Question kind and its configuration, from the question's metadata when
+	// the version was frozen
+	Kind *kinds.Spec `json:"kind,omitempty"`
+}
+
+// VersionDetail is a version with its flow tree
+type VersionDetail struct {
+	Version
+	Flow map[string]interface{} `json:"flow"`
+}
+
+// Logic is what a version needs to compute a respondent's variables, score
+// and ending
+type Logic struct {
+	Nodes     []Node
+	Variables []variables.Variable
+	Endings   []endings.Ending
+}
+
+// Variant is one arm of a form's A/B test: a published or archived version
+// served to a share of respondents in proportion to its weight
+type Variant struct {
+	Key       string `json:"key"`
+	Version   int    `json:"version"`
+	VersionID string `json:"version_id"`
+	Weight    int    `json:"weight"`
+}
+
+// VariantInput is a variant as set by the editor
+type VariantInput struct {
+	Key     string `json:"key"`
+	Version int    `json:"version"`
+	Weight  int    `json:"weight"`
+}
+
+// VariantsRequest replaces a form's variants; an empty list ends the test
+type VariantsRequest struct {
+	Variants []VariantInput `json:"variants"`
+}
+
+// Position is where a question sits in a flow
+type Position struct {
+	Parent     string `json:"parent,omitempty"` // parent question text, empty at root
+	OrderIndex int    `json:"order_index"`
+}
+
+// DiffNode is a question added or removed between versions
+type DiffNode struct {
+	QuestionID string `json:"question_id"`
+	Type       string `json:"type"`
+	Question   string `json:"question"`
+	Position
+}
+
+// MovedNode is a question present in both versions at a different place
+type MovedNode struct {
+	QuestionID string   `json:"question_id"`
+	Type       string   `json:"type"`
+	Question   string   `json:"question"`
+	From       Position `json:"from"`
+	To         Position `json:"to"`
+}
+
+// Jump is a jump between two questions, by question text
+type Jump struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Diff compares two versions by question. Editing a question's text shows up
+// as one removed and one added question.
+type Diff struct {
+	From         int         `json:"from"`
+	To           int         `json:"to"`
+	Added        []DiffNode  `json:"added"`
+	Removed      []DiffNode  `json:"removed"`
+	Moved        []MovedNode `json:"moved"`
+	JumpsAdded   []Jump      `json:"jumps_added"`
+	JumpsRemoved []Jump      `json:"jumps_removed"`
+}
@@ -0,0 +1,192 @@
+package versions
+
+import (
+	"strconv"
+
+	"smart-forms/internal/quiz"
+)
+
+// Score computes a respondent's quiz result on a frozen flow. A question is
+// scored when its options carry points or correct flags; it counts once the
+// respondent reached it (it or one of its options is on path or answered),
+// earning the chosen option's points out of the best option's. A chosen
+// option is correct when it is marked so or earns the question's full
+// points. Routing questions aren't scored: the first one reached (else the
+// first in the flow) gives the outcome, and each gets the option for the
+// score in Routes.
+func (l *Logic) Score(settings *quiz.Settings, path []string, answers map[string]string) *quiz.Result {
+	options := make(map[string][]*Node)
+	for i := range l.Nodes {
+		n := &l.Nodes[i]
+		if n.ParentID != nil && n.Type == TypeOption {
+			options[*n.ParentID] = append(options[*n.ParentID], n)
+		}
+	}
+
+	visited := make(map[string]bool, len(path)+len(answers))
+	for _, id := range path {
+		visited[id] = true
+	}
+	for id := range answers {
+		visited[id] = true
+	}
+
+	result := &quiz.Result{Questions: []quiz.QuestionResult{}}
+	var routers []*Node
+	var outcome *Node
+
+	for i := range l.Nodes {
+		n := &l.Nodes[i]
+		if n.Type == TypeOption {
+			continue
+		}
+		if n.Quiz != nil && n.Quiz.Route {
+			routers = append(routers, n)
+			if outcome == nil && visited[n.ID] {
+				outcome = n
+			}
+			continue
+		}
+
+		scored, best := false, 0.0
+		for _, o := range options[n.ID] {
+			if o.Quiz.Scored() {
+				scored = true
+			}
+			if earned := o.Quiz.Earned(); earned > best {
+				best = earned
+			}
+		}
+		if !scored {
+			continue
+		}
+
+		reached := visited[n.ID]
+		var chosen *Node
+		for _, o := range options[n.ID] {
+			if visited[o.ID] {
+				chosen, reached = o, true
+				break
+			}
+		}
+		if !reached {
+			continue
+		}
+
+		q := quiz.QuestionResult{NodeID: n.ID, MaxPoints: best}
+		if chosen != nil {
+			q.OptionID = chosen.ID
+			q.Points = chosen.Quiz.Earned()
+			q.Correct = (chosen.Quiz != nil && chosen.Quiz.Correct) || (best > 0 && q.Points >= best)
+			if chosen.Quiz != nil {
+				q.Feedback = chosen.Quiz.Feedback
+			}
+		}
+		if q.Feedback == "" && n.Quiz != nil {
+			q.Feedback = n.Quiz.Feedback
+		}
+		result.Questions = append(result.Questions, q)
+	}
+
+	result.Finish(settings)
+
+	if outcome == nil && len(routers) > 0 {
+		outcome = routers[0]
+	}
+	for _, r := range routers {
+		var bands []quiz.Band
+		for _, o := range options[r.ID] {
+			if o.Quiz != nil && o.Quiz.MinPercent != nil {
+				bands = append(bands, quiz.Band{ID: o.ID, MinPercent: *o.Quiz.MinPercent})
+			}
+		}
+		id := quiz.Pick(bands, result.Percent)
+		if id == "" {
+			continue
+		}
+		if result.Routes == nil {
+			result.Routes = make(map[string]string)
+		}
+		result.Routes[r.ID] = id
+		if r == outcome {
+			result.OutcomeID = id
+			for _, o := range options[r.ID] {
+				if o.ID == id {
+					result.Outcome = o.Question
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// checkQuiz reports scoring the flow can't use: bad values, points on
+// questions, routing questions without bands and scored questions nobody
+// can get right
+func (l *linter) checkQuiz(nodes []Node) {
+	for i := range nodes {
+		n := &nodes[i]
+		path := l.paths[n.ID]
+
+		if n.Quiz == nil {
+			continue
+		}
+		if msg := n.Quiz.Check(); msg != "" {
+			l.add(SeverityError, "invalid_quiz", path, n, "Invalid quiz data: "+msg)
+		}
+		if n.Type != TypeOption && n.Quiz.Scored() {
+			l.add(SeverityError, "quiz_points_on_question", path, n, "Only options earn points; mark the correct option instead")
+		}
+		if n.Type == TypeOption && n.Quiz.Route {
+			l.add(SeverityError, "invalid_quiz", path, n, "Only questions can route by score")
+		}
+	}
+
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Type == TypeOption {
+			continue
+		}
+		path := l.paths[n.ID]
+		opts := l.children[n.ID]
+
+		if n.Quiz != nil && n.Quiz.Route {
+			bands := 0
+			for _, o := range opts {
+				if o.Type != TypeOption {
+					continue
+				}
+				if o.Quiz != nil && o.Quiz.MinPercent != nil {
+					bands++
+				} else {
+					l.add(SeverityWarning, "missing_band", l.paths[o.ID], o, "Option has no min_percent and is never chosen by score")
+				}
+			}
+			if bands == 0 {
+				l.add(SeverityError, "route_without_bands", path, n, "Routing question has no options with min_percent")
+			}
+			continue
+		}
+
+		scored, best := false, 0.0
+		for _, o := range opts {
+			if o.Type != TypeOption {
+				continue
+			}
+			if o.Quiz.Scored() {
+				scored = true
+			}
+			if o.Quiz != nil && o.Quiz.MinPercent != nil {
+				l.add(SeverityWarning, "unused_band", l.paths[o.ID], o, "min_percent only applies to options of a routing question")
+			}
+			if earned := o.Quiz.Earned(); earned > best {
+				best = earned
+			}
+		}
+		if scored && best <= 0 {
+			l.add(SeverityWarning, "no_correct_answer", path, n,
+				"No option of this question earns points (best is "+strconv.FormatFloat(best, 'f', -1, 64)+")")
+		}
+	}
+}
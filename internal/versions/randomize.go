@@ -0,0 +1,144 @@
+package versions
+
+import (
+	"sort"
+	"strconv"
+
+	"smart-forms/internal/randomize"
+)
+
+// Order returns, for every randomized node, the children a respondent with
+// this seed is shown, in order. Empty if the flow randomizes nothing.
+func (l *Logic) Order(seed string) map[string][]string {
+	children := make(map[string][]*Node)
+	for i := range l.Nodes {
+		n := &l.Nodes[i]
+		if n.ParentID != nil {
+			children[*n.ParentID] = append(children[*n.ParentID], n)
+		}
+	}
+
+	order := make(map[string][]string)
+	for _, n := range l.Nodes {
+		if n.Randomize.IsZero() {
+			continue
+		}
+		kids := children[n.ID]
+		sort.SliceStable(kids, func(i, j int) bool { return kids[i].OrderIndex < kids[j].OrderIndex })
+		ids := make([]string, len(kids))
+		for i, c := range kids {
+			ids[i] = c.ID
+		}
+		order[n.ID] = n.Randomize.Arrange(seed, n.ID, ids)
+	}
+	return order
+}
+
+// Randomized reports whether any block of a tree built by BuildBlocks
+// randomizes its children
+func Randomized(blocks []map[string]interface{}) bool {
+	for _, b := range blocks {
+		if s, ok := b["randomize"].(*randomize.Settings); ok && !s.IsZero() {
+			return true
+		}
+		if children, ok := b["children"].([]map[string]interface{}); ok && Randomized(children) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArrangeBlocks returns a copy of a tree built by BuildBlocks with every
+// randomized block's children as a respondent with this seed sees them
+// (agrees with Logic.Order). Blocks are copied, not changed.
+func ArrangeBlocks(blocks []map[string]interface{}, seed string) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(blocks))
+	for i, b := range blocks {
+		block := make(map[string]interface{}, len(b))
+		for k, v := range b {
+			block[k] = v
+		}
+
+		children, _ := b["children"].([]map[string]interface{})
+		children = ArrangeBlocks(children, seed)
+		if s, ok := b["randomize"].(*randomize.Settings); ok && !s.IsZero() {
+			byID := make(map[string]map[string]interface{}, len(children))
+			ids := make([]string, len(children))
+			for j, c := range children {
+				ids[j], _ = c["id"].(string)
+				byID[ids[j]] = c
+			}
+			id, _ := b["id"].(string)
+			shown := s.Arrange(seed, id, ids)
+			children = make([]map[string]interface{}, len(shown))
+			for j, cid := range shown {
+				children[j] = byID[cid]
+			}
+		}
+		block["children"] = children
+		result[i] = block
+	}
+	return result
+}
+
+// checkRandomize reports invalid randomization settings, settings that
+// change nothing and sampled score routing
+func (l *linter) checkRandomize(nodes []Node) {
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Randomize == nil {
+			continue
+		}
+		path := l.paths[n.ID]
+		s := n.Randomize
+
+		if err := s.Validate(); err != nil {
+			l.add(SeverityError, "invalid_randomize", path, n,
+				"Invalid randomization: pin_last must be 0-"+strconv.Itoa(randomize.MaxPinLast)+
+					" with shuffle or sample, sample 0-"+strconv.Itoa(randomize.MaxSample))
+			continue
+		}
+
+		free := len(l.children[n.ID]) - s.PinLast
+		if s.IsZero() || free < 2 || (!s.Shuffle && s.Sample >= free) {
+			l.add(SeverityWarning, "randomize_no_effect", path, n,
+				"Randomization changes nothing: fewer than 2 children to shuffle or sample")
+			continue
+		}
+		if s.Sample > 0 && s.Sample >= free {
+			l.add(SeverityWarning, "sample_too_large", path, n,
+				"Sample of "+strconv.Itoa(s.Sample)+" keeps every child")
+		}
+		if s.Sample > 0 && s.Sample < free && n.Quiz != nil && n.Quiz.Route {
+			l.add(SeverityError, "sampled_route", path, n, "A question routing by score can't sample its options")
+		}
+	}
+}
+
+// Unshown returns the first node on path that the order kept from the
+// respondent (sampled out), or "" if they could have seen every one
+func (l *Logic) Unshown(order map[string][]string, path []string) string {
+	parents := make(map[string]string, len(l.Nodes))
+	for _, n := range l.Nodes {
+		if n.ParentID != nil {
+			parents[n.ID] = *n.ParentID
+		}
+	}
+	for _, id := range path {
+		shown, ok := order[parents[id]]
+		if !ok {
+			continue
+		}
+		found := false
+		for _, s := range shown {
+			if s == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,687 @@
+package versions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"smart-forms/internal/endings"
+	"smart-forms/internal/kinds"
+	"smart-forms/internal/quiz"
+	"smart-forms/internal/variables"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type VersionsRepository struct {
+	db       *pgxpool.Pool
+	expander Expander
+}
+
+func NewVersionsRepository(db *pgxpool.Pool) *VersionsRepository {
+	return &VersionsRepository{db: db}
+}
+
+// Expander turns a draft's fragment nodes into concrete nodes before the
+// draft is frozen. It runs in the publish transaction.
+type Expander interface {
+	ExpandDraft(ctx context.Context, tx pgx.Tx, formID, versionID, userID string) error
+}
+
+// SetExpander sets the expander PublishDraft runs (the fragments package);
+// without one, fragment nodes are published as they are
+func (r *VersionsRepository) SetExpander(expander Expander) {
+	r.expander = expander
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+const versionSelect = `
+	SELECT v.id, v.form_id, v.version_number, v.status, v.created_by, v.created_at,
+	       v.published_at, v.published_by,
+	       (SELECT COUNT(*) FROM form_responses r WHERE r.version_id = v.id)
+	FROM form_versions v
+`
+
+func scanVersion(row pgx.Row) (*Version, error) {
+	var v Version
+	err := row.Scan(
+		&v.ID, &v.FormID, &v.Number, &v.Status, &v.CreatedBy, &v.CreatedAt,
+		&v.PublishedAt, &v.PublishedBy, &v.ResponseCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+/*
+========================
+ READ
+========================
+*/
+
+// List lists a form's versions, newest first
+func (r *VersionsRepository) List(ctx context.Context, formID string) ([]Version, error) {
+	rows, err := r.db.Query(ctx, versionSelect+`
+		WHERE v.form_id = $1
+		ORDER BY v.version_number DESC
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *v)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetByNumber returns a version with its frozen snapshot (no nodes for
+// drafts), variable declarations and endings
+func (r *VersionsRepository) GetByNumber(ctx context.Context, formID string, number int) (*Version, *Logic, error) {
+	var snapshot, vars, ends []byte
+	var v Version
+	err := r.db.QueryRow(ctx, `
+		SELECT v.id, v.form_id, v.version_number, v.status, v.created_by, v.created_at,
+		       v.published_at, v.published_by,
+		       (SELECT COUNT(*) FROM form_responses r WHERE r.version_id = v.id),
+		       v.snapshot, v.variables, v.endings
+		FROM form_versions v
+		WHERE v.form_id = $1 AND v.version_number = $2
+	`, formID, number).Scan(
+		&v.ID, &v.FormID, &v.Number, &v.Status, &v.CreatedBy, &v.CreatedAt,
+		&v.PublishedAt, &v.PublishedBy, &v.ResponseCount, &snapshot, &vars, &ends,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	logic, err := decodeLogic(snapshot, vars, ends)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &v, logic, nil
+}
+
+// GetPublishedSnapshot returns the live version's ID, frozen flow, variable
+// declarations and endings. Returns ErrNotFound if the form has never been
+// published.
+func (r *VersionsRepository) GetPublishedSnapshot(ctx context.Context, formID string) (string, *Logic, error) {
+	var versionID string
+	var snapshot, vars, ends []byte
+	err := r.db.QueryRow(ctx, `
+		SELECT id, snapshot, variables, endings
+		FROM form_versions
+		WHERE form_id = $1 AND status = 'published'
+	`, formID).Scan(&versionID, &snapshot, &vars, &ends)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+
+	logic, err := decodeLogic(snapshot, vars, ends)
+	if err != nil {
+		return "", nil, err
+	}
+	return versionID, logic, nil
+}
+
+// GetLogic returns the frozen flow, variable declarations and endings of a
+// published or archived version. Returns ErrNotFound for drafts.
+func (r *VersionsRepository) GetLogic(ctx context.Context, versionID string) (*Logic, error) {
+	var snapshot, vars, ends []byte
+	err := r.db.QueryRow(ctx, `
+		SELECT snapshot, variables, endings
+		FROM form_versions
+		WHERE id = $1 AND status <> 'draft'
+	`, versionID).Scan(&snapshot, &vars, &ends)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return decodeLogic(snapshot, vars, ends)
+}
+
+func decodeLogic(snapshot, vars, ends []byte) (*Logic, error) {
+	logic := &Logic{Nodes: []Node{}}
+	if snapshot != nil {
+		if err := json.Unmarshal(snapshot, &logic.Nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	decls, err := decodeVariables(vars)
+	if err != nil {
+		return nil, err
+	}
+	logic.Variables = decls
+
+	logic.Endings, err = decodeEndings(ends)
+	if err != nil {
+		return nil, err
+	}
+	return logic, nil
+}
+
+func decodeEndings(data []byte) ([]endings.Ending, error) {
+	ends := []endings.Ending{}
+	if len(data) == 0 {
+		return ends, nil
+	}
+	if err := json.Unmarshal(data, &ends); err != nil {
+		return nil, err
+	}
+	return ends, nil
+}
+
+func decodeVariables(data []byte) ([]variables.Variable, error) {
+	decls := []variables.Variable{}
+	if len(data) == 0 {
+		return decls, nil
+	}
+	if err := json.Unmarshal(data, &decls); err != nil {
+		return nil, err
+	}
+	return decls, nil
+}
+
+// EncodeAssignments returns a node's assignments as stored in
+// flow_connections.assignments (NULL when there are none)
+func EncodeAssignments(set []variables.Assignment) ([]byte, error) {
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(set)
+}
+
+// ListNodes reads a version's flow from flow_connections (used for drafts)
+func (r *VersionsRepository) ListNodes(ctx context.Context, versionID string) ([]Node, error) {
+	return listNodes(ctx, r.db, versionID)
+}
+
+func listNodes(ctx context.Context, q querier, versionID string) ([]Node, error) {
+	rows, err := q.Query(ctx, `
+		SELECT fc.id, fc.parent_id, fc.order_index, fc.depth_level, fc.is_terminal,
+		       q.id, q.type, q.question_text,
+		       fc.fragment_id, fc.fragment_version, fc.generated_by,
+		       COALESCE(fc.variable, ''), fc.assignments, q.metadata->'quiz', COALESCE(fc.ending, ''),
+		       fc.randomize, q.metadata->'kind'
+		FROM flow_connections fc
+		JOIN questions q ON q.id = fc.question_id
+		WHERE fc.version_id = $1 AND fc.deleted_at IS NULL
+		ORDER BY fc.depth_level, fc.order_index
+	`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []Node{}
+	for rows.Next() {
+		var n Node
+		var assignments, scoring, kind []byte
+		if err := rows.Scan(
+			&n.ID, &n.ParentID, &n.OrderIndex, &n.DepthLevel, &n.IsTerminal,
+			&n.QuestionID, &n.Type, &n.Question,
+			&n.FragmentID, &n.FragmentVersion, &n.GeneratedBy,
+			&n.Variable, &assignments, &scoring, &n.Ending,
+			&n.Randomize, &kind,
+		); err != nil {
+			return nil, err
+		}
+		n.Quiz = quiz.Decode(scoring)
+		n.Kind = kinds.Decode(kind)
+		if assignments != nil {
+			if err := json.Unmarshal(assignments, &n.Set); err != nil {
+				return nil, err
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	next, err := listEdges(ctx, q, versionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes {
+		nodes[i].Next = next[nodes[i].ID]
+	}
+
+	return nodes, nil
+}
+
+// listEdges returns a version's jumps as source ID -> ordered target IDs
+func listEdges(ctx context.Context, q querier, versionID string) (map[string][]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT e.source_id, e.target_id
+		FROM flow_edges e
+		JOIN flow_connections s ON s.id = e.source_id AND s.deleted_at IS NULL
+		JOIN flow_connections t ON t.id = e.target_id AND t.deleted_at IS NULL
+		WHERE e.version_id = $1
+		ORDER BY e.source_id, e.order_index
+	`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	next := make(map[string][]string)
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, err
+		}
+		next[source] = append(next[source], target)
+	}
+
+	return next, rows.Err()
+}
+
+// GetHeadETag returns the ETag of the flow the editor sees (draft, else
+// published). Returns ErrNotFound if the form has no versions.
+func (r *VersionsRepository) GetHeadETag(ctx context.Context, formID string) (string, error) {
+	var number, revision int
+	err := r.db.QueryRow(ctx, `
+		SELECT version_number, revision
+		FROM form_versions
+		WHERE form_id = $1 AND status IN ('draft', 'published')
+		ORDER BY status = 'draft' DESC
+		LIMIT 1
+	`, formID).Scan(&number, &revision)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return ETag(number, revision), nil
+}
+
+// GetFormSlugs retrieves slugs for cache invalidation
+func (r *VersionsRepository) GetFormSlugs(ctx context.Context, formID string) (*string, *string, error) {
+	var autoSlug, customSlug *string
+	err := r.db.QueryRow(ctx, `
+		SELECT auto_slug, custom_slug FROM forms WHERE id = $1
+	`, formID).Scan(&autoSlug, &customSlug)
+	return autoSlug, customSlug, err
+}
+
+/*
+========================
+ WRITE
+========================
+*/
+
+// lockForm serializes version changes of a form
+func lockForm(ctx context.Context, tx pgx.Tx, formID string) error {
+	var id string
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM forms WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+	`, formID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrFormNotFound
+	}
+	return err
+}
+
+// EnsureDraft returns the ETag of the form's draft version, creating the
+// draft as a copy of the published version (with new flow connection IDs,
+// jumps included) if there is none. Nodes expanded from fragments are not
+// copied: the draft keeps the fragment node, expanded again on publish. A non-empty ifMatch must match the ETag
+// of the flow the editor currently sees (draft, else published), or
+// ErrPrecondition is returned.
+func (r *VersionsRepository) EnsureDraft(ctx context.Context, formID, userID, ifMatch string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockForm(ctx, tx, formID); err != nil {
+		return "", err
+	}
+
+	var status string
+	var number, revision int
+	err = tx.QueryRow(ctx, `
+		SELECT status, version_number, revision
+		FROM form_versions
+		WHERE form_id = $1 AND status IN ('draft', 'published')
+		ORDER BY status = 'draft' DESC
+		LIMIT 1
+	`, formID).Scan(&status, &number, &revision)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+
+	if ifMatch != "" && !ETagMatches(ifMatch, ETag(number, revision)) {
+		return "", ErrPrecondition
+	}
+
+	if status == StatusDraft {
+		return ETag(number, revision), tx.Commit(ctx)
+	}
+
+	var draftID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO form_versions (form_id, version_number, status, created_by, variables, endings)
+		SELECT $1, COALESCE(MAX(version_number), 0) + 1, 'draft', $2,
+		       COALESCE((SELECT variables FROM form_versions WHERE form_id = $1 AND status = 'published'), '[]'),
+		       COALESCE((SELECT endings FROM form_versions WHERE form_id = $1 AND status = 'published'), '[]')
+		FROM form_versions
+		WHERE form_id = $1
+		RETURNING id, version_number
+	`, formID, userID).Scan(&draftID, &number)
+	if err != nil {
+		return "", err
+	}
+	etag := ETag(number, 0)
+
+	var publishedID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM form_versions WHERE form_id = $1 AND status = 'published'
+	`, formID).Scan(&publishedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return etag, tx.Commit(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	nodes, err := listNodes(ctx, tx, publishedID)
+	if err != nil {
+		return "", err
+	}
+
+	// Nodes are ordered by depth, so parents are copied before their children
+	newIDs := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		if n.GeneratedBy != nil {
+			continue
+		}
+
+		var parentID *string
+		if n.ParentID != nil {
+			id, ok := newIDs[*n.ParentID]
+			if !ok {
+				continue
+			}
+			parentID = &id
+		}
+
+		// Without its expanded nodes, a fragment node ends the flow unless it jumps
+		isTerminal := n.IsTerminal
+		if n.FragmentID != nil {
+			isTerminal = len(n.Next) == 0
+		}
+
+		assignments, err := EncodeAssignments(n.Set)
+		if err != nil {
+			return "", err
+		}
+
+		var id string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO flow_connections (form_id, version_id, origin_id, question_id, parent_id, order_index, depth_level, is_terminal,
+			                              fragment_id, fragment_version, variable, assignments, ending, randomize)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, NULLIF($13, ''), $14)
+			RETURNING id
+		`, formID, draftID, n.ID, n.QuestionID, parentID, n.OrderIndex, n.DepthLevel, isTerminal,
+			n.FragmentID, n.FragmentVersion, n.Variable, assignments, n.Ending, n.Randomize).Scan(&id)
+		if err != nil {
+			return "", err
+		}
+		newIDs[n.ID] = id
+	}
+
+	for _, n := range nodes {
+		for i, target := range n.Next {
+			source, ok1 := newIDs[n.ID]
+			target, ok2 := newIDs[target]
+			if !ok1 || !ok2 {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO flow_edges (form_id, version_id, source_id, target_id, order_index)
+				VALUES ($1, $2, $3, $4, $5)
+			`, formID, draftID, source, target, i); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return etag, tx.Commit(ctx)
+}
+
+// PublishDraft expands the draft's fragment nodes, freezes its flow and
+// question texts into its snapshot, makes it the published version and
+// archives the previous one
+func (r *VersionsRepository) PublishDraft(ctx context.Context, formID, userID string) (*Version, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockForm(ctx, tx, formID); err != nil {
+		return nil, err
+	}
+
+	var draftID string
+	var vars, ends []byte
+	err = tx.QueryRow(ctx, `
+		SELECT id, variables, endings FROM form_versions WHERE form_id = $1 AND status = 'draft'
+	`, formID).Scan(&draftID, &vars, &ends)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoDraft
+		}
+		return nil, err
+	}
+
+	decls, err := decodeVariables(vars)
+	if err != nil {
+		return nil, err
+	}
+	endingDefs, err := decodeEndings(ends)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.expander != nil {
+		if err := r.expander.ExpandDraft(ctx, tx, formID, draftID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	nodes, err := listNodes(ctx, tx, draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lint exactly what gets frozen
+	if report := Lint(nodes, decls, endingDefs); !report.Valid {
+		return nil, &LintError{Report: report}
+	}
+
+	snapshot, err := json.Marshal(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE form_versions SET status = 'archived'
+		WHERE form_id = $1 AND status = 'published'
+	`, formID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE form_versions
+		SET status = 'published',
+		    snapshot = $2,
+		    published_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    published_by = $3
+		WHERE id = $1
+	`, draftID, snapshot, userID); err != nil {
+		return nil, err
+	}
+
+	v, err := scanVersion(tx.QueryRow(ctx, versionSelect+` WHERE v.id = $1`, draftID))
+	if err != nil {
+		return nil, err
+	}
+
+	return v, tx.Commit(ctx)
+}
+
+// ListVariants returns the form's A/B test variants in order (none if it
+// isn't running one)
+func (r *VersionsRepository) ListVariants(ctx context.Context, formID string) ([]Variant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT fv.key, v.version_number, v.id, fv.weight
+		FROM form_variants fv
+		JOIN form_versions v ON v.id = fv.version_id
+		WHERE fv.form_id = $1
+		ORDER BY fv.position
+	`, formID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []Variant{}
+	for rows.Next() {
+		var v Variant
+		if err := rows.Scan(&v.Key, &v.Version, &v.VersionID, &v.Weight); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// SetVariants replaces the form's variants. Versions are given by number and
+// must be published or archived.
+func (r *VersionsRepository) SetVariants(ctx context.Context, formID string, inputs []VariantInput) ([]Variant, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockForm(ctx, tx, formID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM form_variants WHERE form_id = $1`, formID); err != nil {
+		return nil, err
+	}
+
+	variants := make([]Variant, len(inputs))
+	for i, in := range inputs {
+		var versionID, status string
+		err := tx.QueryRow(ctx, `
+			SELECT id, status FROM form_versions WHERE form_id = $1 AND version_number = $2
+		`, formID, in.Version).Scan(&versionID, &status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		if status == StatusDraft {
+			return nil, ErrDraftVariant
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO form_variants (form_id, key, version_id, weight, position)
+			VALUES ($1, $2, $3, $4, $5)
+		`, formID, in.Key, versionID, in.Weight, i); err != nil {
+			return nil, err
+		}
+		variants[i] = Variant{Key: in.Key, Version: in.Version, VersionID: versionID, Weight: in.Weight}
+	}
+
+	return variants, tx.Commit(ctx)
+}
+
+// Republish makes an archived version live again (its flow connection IDs
+// are unchanged) and archives the current published version
+func (r *VersionsRepository) Republish(ctx context.Context, formID string, number int, userID string) (*Version, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockForm(ctx, tx, formID); err != nil {
+		return nil, err
+	}
+
+	var versionID, status string
+	err = tx.QueryRow(ctx, `
+		SELECT id, status FROM form_versions WHERE form_id = $1 AND version_number = $2
+	`, formID, number).Scan(&versionID, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	switch status {
+	case StatusDraft:
+		return nil, ErrDraftRollback
+	case StatusPublished:
+		return nil, ErrAlreadyPublished
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE form_versions SET status = 'archived'
+		WHERE form_id = $1 AND status = 'published'
+	`, formID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE form_versions
+		SET status = 'published',
+		    published_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		    published_by = $2
+		WHERE id = $1
+	`, versionID, userID); err != nil {
+		return nil, err
+	}
+
+	v, err := scanVersion(tx.QueryRow(ctx, versionSelect+` WHERE v.id = $1`, versionID))
+	if err != nil {
+		return nil, err
+	}
+
+	return v, tx.Commit(ctx)
+}
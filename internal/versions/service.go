@@ -0,0 +1,392 @@
+package versions
+
+import (
+	"context"
+
+	"smart-forms/internal/cache"
+	"smart-forms/internal/variables"
+)
+
+type VersionsService struct {
+	repo  *VersionsRepository
+	cache *cache.Cache
+}
+
+func NewVersionsService(repo *VersionsRepository, cacheClient *cache.Cache) *VersionsService {
+	return &VersionsService{
+		repo:  repo,
+		cache: cacheClient,
+	}
+}
+
+// ListVersions lists a form's versions, newest first
+func (s *VersionsService) ListVersions(ctx context.Context, formID string) ([]Version, error) {
+	return s.repo.List(ctx, formID)
+}
+
+// GetVersion returns a version with its flow: the frozen snapshot for
+// published and archived versions, the current flow for the draft
+func (s *VersionsService) GetVersion(ctx context.Context, formID string, number int) (*VersionDetail, error) {
+	version, logic, err := s.loadLogic(ctx, formID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionDetail{
+		Version: *version,
+		Flow: map[string]interface{}{
+			"blocks":    BuildBlocks(logic.Nodes),
+			"variables": logic.Variables,
+			"endings":   logic.Endings,
+		},
+	}, nil
+}
+
+// DiffVersions compares the flows of two versions
+func (s *VersionsService) DiffVersions(ctx context.Context, formID string, from, to int) (*Diff, error) {
+	if from <= 0 || to <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	_, fromLogic, err := s.loadLogic(ctx, formID, from)
+	if err != nil {
+		return nil, err
+	}
+	_, toLogic, err := s.loadLogic(ctx, formID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffNodes(fromLogic.Nodes, toLogic.Nodes)
+	diff.From = from
+	diff.To = to
+	return diff, nil
+}
+
+// PublishDraft makes the draft the live version. Returns ErrNoDraft if there
+// is nothing to publish.
+func (s *VersionsService) PublishDraft(ctx context.Context, formID, userID string) (*Version, error) {
+	version, err := s.repo.PublishDraft(ctx, formID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, formID)
+	return version, nil
+}
+
+// Rollback makes an archived version live again. A pending draft is kept.
+func (s *VersionsService) Rollback(ctx context.Context, formID, userID string, number int) (*Version, error) {
+	version, err := s.repo.Republish(ctx, formID, number, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, formID)
+	return version, nil
+}
+
+// EnsureDraft makes sure the form has a draft for edits to go to, creating
+// one from the published version if needed, and returns the draft's ETag.
+// A non-empty ifMatch must match HeadETag (ErrPrecondition otherwise).
+func (s *VersionsService) EnsureDraft(ctx context.Context, formID, userID, ifMatch string) (string, error) {
+	return s.repo.EnsureDraft(ctx, formID, userID, ifMatch)
+}
+
+// HeadETag returns the ETag of the flow the editor sees ("" if the form has
+// no versions)
+func (s *VersionsService) HeadETag(ctx context.Context, formID string) (string, error) {
+	etag, err := s.repo.GetHeadETag(ctx, formID)
+	if err == ErrNotFound {
+		return "", nil
+	}
+	return etag, err
+}
+
+// PublishedFlow returns the live version's ID, frozen flow blocks and
+// variable declarations, as shown to respondents: quiz answers, points and
+// feedback are left out. Returns ErrNotFound if the form has never been
+// published.
+func (s *VersionsService) PublishedFlow(ctx context.Context, formID string) (string, []map[string]interface{}, []variables.Variable, error) {
+	versionID, logic, err := s.repo.GetPublishedSnapshot(ctx, formID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return versionID, publicBlocks(logic), logic.Variables, nil
+}
+
+// VersionFlow returns a published or archived version's frozen flow blocks
+// and variable declarations as shown to respondents (see PublishedFlow)
+func (s *VersionsService) VersionFlow(ctx context.Context, versionID string) ([]map[string]interface{}, []variables.Variable, error) {
+	logic, err := s.repo.GetLogic(ctx, versionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return publicBlocks(logic), logic.Variables, nil
+}
+
+// ListVariants returns the form's A/B test variants in order
+func (s *VersionsService) ListVariants(ctx context.Context, formID string) ([]Variant, error) {
+	return s.repo.ListVariants(ctx, formID)
+}
+
+// SetVariants replaces the form's A/B test; no variants ends it. Public
+// respondents are assigned a variant from then on.
+func (s *VersionsService) SetVariants(ctx context.Context, formID string, req VariantsRequest) ([]Variant, error) {
+	if err := checkVariants(req.Variants); err != nil {
+		return nil, err
+	}
+
+	variants, err := s.repo.SetVariants(ctx, formID, req.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, formID)
+	return variants, nil
+}
+
+// PublishedLogic returns the live version's ID with its frozen flow and
+// variable declarations. Returns ErrNotFound if the form has never been
+// published.
+func (s *VersionsService) PublishedLogic(ctx context.Context, formID string) (string, *Logic, error) {
+	return s.repo.GetPublishedSnapshot(ctx, formID)
+}
+
+// VersionLogic returns the frozen flow and variable declarations of a
+// published or archived version
+func (s *VersionsService) VersionLogic(ctx context.Context, versionID string) (*Logic, error) {
+	return s.repo.GetLogic(ctx, versionID)
+}
+
+func (s *VersionsService) loadLogic(ctx context.Context, formID string, number int) (*Version, *Logic, error) {
+	version, logic, err := s.repo.GetByNumber(ctx, formID, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version.Status == StatusDraft {
+		logic.Nodes, err = s.repo.ListNodes(ctx, version.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return version, logic, nil
+}
+
+// invalidate drops cached public forms after the live version changed
+func (s *VersionsService) invalidate(ctx context.Context, formID string) {
+	s.cache.Delete(cache.FormIDKey(formID))
+
+	autoSlug, customSlug, _ := s.repo.GetFormSlugs(ctx, formID)
+	if autoSlug != nil && *autoSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*autoSlug))
+	}
+	if customSlug != nil && *customSlug != "" {
+		s.cache.Delete(cache.FormSlugKey(*customSlug))
+	}
+}
+
+/*
+========================
+ HELPERS
+========================
+*/
+
+// publicBlocks builds a frozen flow's blocks without the quiz data
+// respondents mustn't see
+func publicBlocks(logic *Logic) []map[string]interface{} {
+	for i := range logic.Nodes {
+		logic.Nodes[i].Quiz = logic.Nodes[i].Quiz.Public()
+	}
+	return BuildBlocks(logic.Nodes)
+}
+
+// BuildBlocks nests flat nodes into the block tree served to clients
+func BuildBlocks(nodes []Node) []map[string]interface{} {
+	return buildTree(nodes, nil)
+}
+
+func buildTree(nodes []Node, parentID *string) []map[string]interface{} {
+	result := []map[string]interface{}{}
+
+	for _, n := range nodes {
+		if (parentID == nil && n.ParentID == nil) ||
+			(parentID != nil && n.ParentID != nil && *parentID == *n.ParentID) {
+
+			id := n.ID
+			block := map[string]interface{}{
+				"id":       id,
+				"type":     n.Type,
+				"question": n.Question,
+				"children": buildTree(nodes, &id),
+				"next":     next(n),
+			}
+			if n.FragmentID != nil {
+				block["fragment"] = map[string]interface{}{
+					"id":      *n.FragmentID,
+					"version": n.FragmentVersion,
+				}
+			}
+			if n.Variable != "" {
+				block["variable"] = n.Variable
+			}
+			if len(n.Set) > 0 {
+				block["set"] = n.Set
+			}
+			if n.Quiz != nil {
+				block["quiz"] = n.Quiz
+			}
+			if n.Ending != "" {
+				block["ending"] = n.Ending
+			}
+			if n.Randomize != nil {
+				block["randomize"] = n.Randomize
+			}
+			if n.Kind != nil {
+				block["kind"] = n.Kind
+			}
+			result = append(result, block)
+		}
+	}
+
+	return result
+}
+
+// next returns a node's jump targets, never nil
+func next(n Node) []string {
+	if n.Next == nil {
+		return []string{}
+	}
+	return n.Next
+}
+
+// diffNodes matches questions between two flows by question ID (the n-th
+// occurrence in one flow pairs with the n-th in the other)
+func diffNodes(from, to []Node) *Diff {
+	diff := &Diff{
+		Added:        []DiffNode{},
+		Removed:      []DiffNode{},
+		Moved:        []MovedNode{},
+		JumpsAdded:   []Jump{},
+		JumpsRemoved: []Jump{},
+	}
+
+	fromPos := positions(from)
+	toPos := positions(to)
+
+	unmatched := make(map[string][]Node)
+	for _, n := range from {
+		unmatched[n.QuestionID] = append(unmatched[n.QuestionID], n)
+	}
+
+	for _, n := range to {
+		candidates := unmatched[n.QuestionID]
+		if len(candidates) == 0 {
+			diff.Added = append(diff.Added, DiffNode{
+				QuestionID: n.QuestionID,
+				Type:       n.Type,
+				Question:   n.Question,
+				Position:   toPos[n.ID],
+			})
+			continue
+		}
+
+		old := candidates[0]
+		unmatched[n.QuestionID] = candidates[1:]
+
+		if fromPos[old.ID] != toPos[n.ID] {
+			diff.Moved = append(diff.Moved, MovedNode{
+				QuestionID: n.QuestionID,
+				Type:       n.Type,
+				Question:   n.Question,
+				From:       fromPos[old.ID],
+				To:         toPos[n.ID],
+			})
+		}
+	}
+
+	// Keep removals in flow order
+	for _, n := range from {
+		candidates := unmatched[n.QuestionID]
+		if len(candidates) == 0 || candidates[0].ID != n.ID {
+			continue
+		}
+		unmatched[n.QuestionID] = candidates[1:]
+
+		diff.Removed = append(diff.Removed, DiffNode{
+			QuestionID: n.QuestionID,
+			Type:       n.Type,
+			Question:   n.Question,
+			Position:   fromPos[n.ID],
+		})
+	}
+
+	diff.JumpsAdded, diff.JumpsRemoved = diffJumps(from, to)
+
+	return diff
+}
+
+// diffJumps compares jumps by the questions they connect
+func diffJumps(from, to []Node) (added, removed []Jump) {
+	added, removed = []Jump{}, []Jump{}
+
+	fromJumps := jumps(from)
+	remaining := make(map[Jump]int, len(fromJumps))
+	for _, j := range fromJumps {
+		remaining[j]++
+	}
+
+	for _, j := range jumps(to) {
+		if remaining[j] > 0 {
+			remaining[j]--
+			continue
+		}
+		added = append(added, j)
+	}
+
+	for _, j := range fromJumps {
+		if remaining[j] > 0 {
+			remaining[j]--
+			removed = append(removed, j)
+		}
+	}
+
+	return added, removed
+}
+
+func jumps(nodes []Node) []Jump {
+	questions := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		questions[n.ID] = n.Question
+	}
+
+	var result []Jump
+	for _, n := range nodes {
+		for _, target := range n.Next {
+			result = append(result, Jump{From: n.Question, To: questions[target]})
+		}
+	}
+	return result
+}
+
+// positions maps node IDs to their parent question text and order
+func positions(nodes []Node) map[string]Position {
+	questions := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		questions[n.ID] = n.Question
+	}
+
+	result := make(map[string]Position, len(nodes))
+	for _, n := range nodes {
+		p := Position{OrderIndex: n.OrderIndex}
+		if n.ParentID != nil {
+			p.Parent = questions[*n.ParentID]
+		}
+		result[n.ID] = p
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package versions
+
+import "smart-forms/internal/variables"
+
+// Evaluate computes a respondent's variables on a frozen flow. Nodes run in
+// the order of path, then any answered node the path left out; answers maps
+// node IDs to answer texts. A question stores its answer in its variable, or
+// the text of the chosen option when it has options; a chosen option runs
+// its own assignments after its question's.
+func (l *Logic) Evaluate(path []string, answers map[string]string) variables.Env {
+	byID := make(map[string]*Node, len(l.Nodes))
+	options := make(map[string][]*Node)
+	for i := range l.Nodes {
+		n := &l.Nodes[i]
+		byID[n.ID] = n
+		if n.ParentID != nil && n.Type == TypeOption {
+			options[*n.ParentID] = append(options[*n.ParentID], n)
+		}
+	}
+
+	// Options count as visited when they are on the path or answered
+	visited := make(map[string]bool, len(path)+len(answers))
+	var order []string
+	visit := func(id string) {
+		if _, ok := byID[id]; ok && !visited[id] {
+			visited[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range path {
+		visit(id)
+	}
+	// Answers come in no particular order; the rest run in snapshot order
+	for _, n := range l.Nodes {
+		if _, ok := answers[n.ID]; ok {
+			visit(n.ID)
+		}
+	}
+
+	steps := make([]variables.Step, 0, len(order))
+	for _, id := range order {
+		n := byID[id]
+		step := variables.Step{Variable: n.Variable, Set: n.Set}
+
+		if n.Variable != "" {
+			if choices := options[n.ID]; len(choices) > 0 {
+				for _, o := range choices {
+					if visited[o.ID] {
+						text := o.Question
+						step.Answer = &text
+						break
+					}
+				}
+			} else if answer, ok := answers[n.ID]; ok {
+				step.Answer = &answer
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return variables.Evaluate(l.Variables, steps)
+}
+
+// Render resolves the {{variable}} templates of the flow's texts and returns
+// the nodes whose text changed, by node ID
+func (l *Logic) Render(env variables.Env) map[string]string {
+	texts := make(map[string]string)
+	for _, n := range l.Nodes {
+		if len(variables.TemplateRefs(n.Question)) > 0 {
+			texts[n.ID] = variables.Render(n.Question, env)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,78 @@
+package versions
+
+import (
+	"hash/fnv"
+	"regexp"
+)
+
+// Limits of a form's A/B test
+const (
+	MaxVariants      = 10
+	MaxVariantWeight = 1000
+)
+
+var (
+	variantKeyPattern    = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	respondentKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+)
+
+// ValidRespondentKey reports whether key can assign a respondent a variant
+func ValidRespondentKey(key string) bool {
+	return respondentKeyPattern.MatchString(key)
+}
+
+// checkVariants validates an A/B test: none (no test), or 2 to MaxVariants
+// variants with unique keys and versions and weights adding up to more
+// than 0
+func checkVariants(variants []VariantInput) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < 2 || len(variants) > MaxVariants {
+		return ErrInvalidVariants
+	}
+	keys := make(map[string]bool, len(variants))
+	numbers := make(map[int]bool, len(variants))
+	total := 0
+	for _, v := range variants {
+		if !variantKeyPattern.MatchString(v.Key) || keys[v.Key] || numbers[v.Version] {
+			return ErrInvalidVariants
+		}
+		if v.Weight < 0 || v.Weight > MaxVariantWeight {
+			return ErrInvalidVariants
+		}
+		keys[v.Key], numbers[v.Version] = true, true
+		total += v.Weight
+	}
+	if total == 0 {
+		return ErrInvalidVariants
+	}
+	return nil
+}
+
+// AssignVariant picks a respondent's variant: the respondent key hashes to a
+// point on the variants' weights, so the same key always gets the same
+// variant while the weights stay the same. Returns nil without variants.
+func AssignVariant(variants []Variant, formID, respondentKey string) *Variant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(formID))
+	h.Write([]byte{0})
+	h.Write([]byte(respondentKey))
+	point := int(h.Sum64() % uint64(total))
+
+	for i := range variants {
+		if point < variants[i].Weight {
+			return &variants[i]
+		}
+		point -= variants[i].Weight
+	}
+	return nil
+}
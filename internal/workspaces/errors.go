@@ -0,0 +1,16 @@
+package workspaces
+
+import "errors"
+
+var (
+	ErrNotFound          = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("member not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrInvalidRole       = errors.New("invalid workspace role")
+	ErrForbidden         = errors.New("insufficient workspace role")
+	ErrAlreadyMember     = errors.New("user is already a member")
+	ErrPersonalWorkspace = errors.New("operation not allowed on a personal workspace")
+	ErrLastOwner         = errors.New("workspace must keep at least one owner")
+	ErrNotEmpty          = errors.New("workspace still has forms")
+)
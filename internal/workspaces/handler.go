@@ -0,0 +1,182 @@
+package workspaces
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type WorkspacesHandler struct {
+	service *WorkspacesService
+}
+
+func NewWorkspacesHandler(service *WorkspacesService) *WorkspacesHandler {
+	return &WorkspacesHandler{service: service}
+}
+
+// ListWorkspaces lists the caller's workspaces
+// GET /workspaces
+func (h *WorkspacesHandler) ListWorkspaces(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	items, err := h.service.ListWorkspaces(c.Context(), userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// CreateWorkspace creates a team workspace
+// POST /workspaces
+func (h *WorkspacesHandler) CreateWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req CreateWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	ws, err := h.service.CreateWorkspace(c.Context(), userID, req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(ws)
+}
+
+// GetWorkspace retrieves a workspace
+// GET /workspaces/:id
+func (h *WorkspacesHandler) GetWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	ws, err := h.service.GetWorkspace(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(ws)
+}
+
+// UpdateWorkspace renames a workspace
+// PATCH /workspaces/:id
+func (h *WorkspacesHandler) UpdateWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req UpdateWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	ws, err := h.service.UpdateWorkspace(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(ws)
+}
+
+// DeleteWorkspace deletes an empty team workspace
+// DELETE /workspaces/:id
+func (h *WorkspacesHandler) DeleteWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.DeleteWorkspace(c.Context(), userID, c.Params("id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Workspace deleted successfully",
+	})
+}
+
+// ListMembers lists workspace members
+// GET /workspaces/:id/members
+func (h *WorkspacesHandler) ListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	members, err := h.service.ListMembers(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(members)
+}
+
+// AddMember adds an existing user by email
+// POST /workspaces/:id/members
+func (h *WorkspacesHandler) AddMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req AddMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	members, err := h.service.AddMember(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(members)
+}
+
+// UpdateMember changes a member's role
+// PATCH /workspaces/:id/members/:user_id
+func (h *WorkspacesHandler) UpdateMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	members, err := h.service.UpdateMember(c.Context(), userID, c.Params("id"), c.Params("user_id"), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(members)
+}
+
+// RemoveMember removes a member, or leaves the workspace when user_id is the caller
+// DELETE /workspaces/:id/members/:user_id
+func (h *WorkspacesHandler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.service.RemoveMember(c.Context(), userID, c.Params("id"), c.Params("user_id")); err != nil {
+		return mapServiceError(err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Member removed successfully",
+	})
+}
+
+func mapServiceError(err error) error {
+	switch err {
+	case ErrNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Workspace not found")
+	case ErrMemberNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Member not found")
+	case ErrUserNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "No account with this email")
+	case ErrInvalidInput:
+		return fiber.ErrBadRequest
+	case ErrInvalidRole:
+		return fiber.NewError(fiber.StatusBadRequest, "role must be owner, admin or member")
+	case ErrForbidden:
+		return fiber.NewError(fiber.StatusForbidden, "Your workspace role does not allow this")
+	case ErrPersonalWorkspace:
+		return fiber.NewError(fiber.StatusForbidden, "Personal workspaces cannot be shared or deleted")
+	case ErrAlreadyMember:
+		return fiber.NewError(fiber.StatusConflict, "User is already a member")
+	case ErrLastOwner:
+		return fiber.NewError(fiber.StatusConflict, "Workspace must keep at least one owner")
+	case ErrNotEmpty:
+		return fiber.NewError(fiber.StatusConflict, "Move or delete the workspace's forms first")
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
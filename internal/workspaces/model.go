@@ -0,0 +1,51 @@
+package workspaces
+
+import "time"
+
+// Member roles within a workspace
+const (
+	RoleOwner  = "owner"  // everything, including deleting the workspace
+	RoleAdmin  = "admin"  // rename the workspace and manage members
+	RoleMember = "member" // work on the workspace's forms
+)
+
+// Workspace groups forms shared by its members
+type Workspace struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	IsPersonal  bool      `json:"is_personal"`
+	Role        string    `json:"role"` // caller's role
+	MemberCount int       `json:"member_count"`
+	FormCount   int       `json:"form_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Member is a user belonging to a workspace
+type Member struct {
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// CreateWorkspaceRequest creates a team workspace
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// UpdateWorkspaceRequest renames a workspace
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// AddMemberRequest adds an existing user by email
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"` // defaults to member
+}
+
+// UpdateMemberRequest changes a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
@@ -0,0 +1,327 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WorkspacesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWorkspacesRepository(db *pgxpool.Pool) *WorkspacesRepository {
+	return &WorkspacesRepository{db: db}
+}
+
+// Columns of a workspace as seen by member $1
+const workspaceSelect = `
+	SELECT w.id, w.name, w.personal_user_id IS NOT NULL, m.role,
+	       (SELECT COUNT(*) FROM workspace_members wm WHERE wm.workspace_id = w.id),
+	       (SELECT COUNT(*) FROM forms f WHERE f.workspace_id = w.id AND f.deleted_at IS NULL),
+	       w.created_at, w.updated_at
+	FROM workspaces w
+	JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $1
+`
+
+func scanWorkspace(row pgx.Row) (*Workspace, error) {
+	var w Workspace
+	err := row.Scan(
+		&w.ID,
+		&w.Name,
+		&w.IsPersonal,
+		&w.Role,
+		&w.MemberCount,
+		&w.FormCount,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+/*
+========================
+ WORKSPACES
+========================
+*/
+
+// ListForUser retrieves the workspaces a user belongs to (personal first)
+func (r *WorkspacesRepository) ListForUser(ctx context.Context, userID string) ([]Workspace, error) {
+	rows, err := r.db.Query(ctx, workspaceSelect+" ORDER BY w.personal_user_id IS NULL, w.name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []Workspace{}
+	for rows.Next() {
+		w, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, *w)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// GetForUser retrieves a workspace if userID is a member
+func (r *WorkspacesRepository) GetForUser(ctx context.Context, workspaceID, userID string) (*Workspace, error) {
+	w, err := scanWorkspace(r.db.QueryRow(ctx, workspaceSelect+" WHERE w.id = $2", userID, workspaceID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// Create inserts a team workspace with userID as owner
+func (r *WorkspacesRepository) Create(ctx context.Context, userID, name string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id
+	`, name, userID).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, id, userID); err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// EnsurePersonal returns the user's personal workspace, creating it if needed
+func (r *WorkspacesRepository) EnsurePersonal(ctx context.Context, userID string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (name, personal_user_id, created_by)
+		VALUES ('Personal', $1, $1)
+		ON CONFLICT (personal_user_id) DO UPDATE SET personal_user_id = EXCLUDED.personal_user_id
+		RETURNING id
+	`, userID).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+		ON CONFLICT DO NOTHING
+	`, id, userID); err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// UpdateName renames a workspace
+func (r *WorkspacesRepository) UpdateName(ctx context.Context, workspaceID, name string) error {
+	const query = `
+		UPDATE workspaces
+		SET name = $2, updated_at = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+		WHERE id = $1
+	`
+
+	cmd, err := r.db.Exec(ctx, query, workspaceID, name)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a workspace without live forms.
+// Soft-deleted forms of the workspace are purged with it.
+func (r *WorkspacesRepository) Delete(ctx context.Context, workspaceID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var live int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM forms
+		WHERE workspace_id = $1 AND deleted_at IS NULL
+	`, workspaceID).Scan(&live); err != nil {
+		return err
+	}
+	if live > 0 {
+		return ErrNotEmpty
+	}
+
+	// response_answers -> flow_connections is ON DELETE RESTRICT
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM response_answers
+		WHERE response_id IN (
+			SELECT r.id
+			FROM form_responses r
+			JOIN forms f ON f.id = r.form_id
+			WHERE f.workspace_id = $1
+		)
+	`, workspaceID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM forms WHERE workspace_id = $1`, workspaceID); err != nil {
+		return err
+	}
+
+	cmd, err := tx.Exec(ctx, `DELETE FROM workspaces WHERE id = $1`, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+/*
+========================
+ MEMBERS
+========================
+*/
+
+// ListMembers retrieves the members of a workspace (owners first)
+func (r *WorkspacesRepository) ListMembers(ctx context.Context, workspaceID string) ([]Member, error) {
+	const query = `
+		SELECT m.user_id, u.email, m.role, m.created_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, u.email
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// GetMemberRole returns a member's role in a workspace
+func (r *WorkspacesRepository) GetMemberRole(ctx context.Context, workspaceID, userID string) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx, `
+		SELECT role FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrMemberNotFound
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// FindUserIDByEmail resolves an account by email
+func (r *WorkspacesRepository) FindUserIDByEmail(ctx context.Context, email string) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `SELECT id FROM users WHERE lower(email) = lower($1)`, email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// AddMember adds a user to a workspace
+func (r *WorkspacesRepository) AddMember(ctx context.Context, workspaceID, userID, role string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, workspaceID, userID, role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateMemberRole changes a member's role
+func (r *WorkspacesRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID, role string) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE workspace_members SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID, role)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a workspace
+func (r *WorkspacesRepository) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// CountOwners counts the owners of a workspace
+func (r *WorkspacesRepository) CountOwners(ctx context.Context, workspaceID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM workspace_members
+		WHERE workspace_id = $1 AND role = 'owner'
+	`, workspaceID).Scan(&count)
+	return count, err
+}
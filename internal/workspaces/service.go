@@ -0,0 +1,263 @@
+package workspaces
+
+import (
+	"context"
+	"strings"
+)
+
+type WorkspacesService struct {
+	repo *WorkspacesRepository
+}
+
+func NewWorkspacesService(repo *WorkspacesRepository) *WorkspacesService {
+	return &WorkspacesService{repo: repo}
+}
+
+// ListWorkspaces lists the caller's workspaces, creating the personal one on first use
+func (s *WorkspacesService) ListWorkspaces(ctx context.Context, userID string) ([]Workspace, error) {
+	if _, err := s.repo.EnsurePersonal(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListForUser(ctx, userID)
+}
+
+// GetWorkspace retrieves a workspace the caller belongs to
+func (s *WorkspacesService) GetWorkspace(ctx context.Context, userID, workspaceID string) (*Workspace, error) {
+	return s.repo.GetForUser(ctx, workspaceID, userID)
+}
+
+// CreateWorkspace creates a team workspace owned by the caller
+func (s *WorkspacesService) CreateWorkspace(ctx context.Context, userID string, req CreateWorkspaceRequest) (*Workspace, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidInput
+	}
+
+	id, err := s.repo.Create(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetForUser(ctx, id, userID)
+}
+
+// UpdateWorkspace renames a workspace (owner or admin)
+func (s *WorkspacesService) UpdateWorkspace(ctx context.Context, userID, workspaceID string, req UpdateWorkspaceRequest) (*Workspace, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidInput
+	}
+
+	ws, err := s.repo.GetForUser(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ws.Role == RoleMember {
+		return nil, ErrForbidden
+	}
+
+	if err := s.repo.UpdateName(ctx, workspaceID, name); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetForUser(ctx, workspaceID, userID)
+}
+
+// DeleteWorkspace deletes an empty team workspace (owner only)
+func (s *WorkspacesService) DeleteWorkspace(ctx context.Context, userID, workspaceID string) error {
+	ws, err := s.repo.GetForUser(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if ws.IsPersonal {
+		return ErrPersonalWorkspace
+	}
+	if ws.Role != RoleOwner {
+		return ErrForbidden
+	}
+
+	return s.repo.Delete(ctx, workspaceID)
+}
+
+/*
+========================
+ MEMBERS
+========================
+*/
+
+// ListMembers lists the members of a workspace the caller belongs to
+func (s *WorkspacesService) ListMembers(ctx context.Context, userID, workspaceID string) ([]Member, error) {
+	if _, err := s.repo.GetForUser(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// AddMember adds an existing user by email (owner or admin).
+// Only owners can add owners or admins.
+func (s *WorkspacesService) AddMember(ctx context.Context, userID, workspaceID string, req AddMemberRequest) ([]Member, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		return nil, ErrInvalidInput
+	}
+
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		role = RoleMember
+	}
+	if !isValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	ws, err := s.repo.GetForUser(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ws.IsPersonal {
+		return nil, ErrPersonalWorkspace
+	}
+	if !canGrant(ws.Role, role) {
+		return nil, ErrForbidden
+	}
+
+	memberID, err := s.repo.FindUserIDByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddMember(ctx, workspaceID, memberID, role); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// UpdateMember changes a member's role (owner or admin).
+// Admins cannot change owners or grant owner/admin.
+func (s *WorkspacesService) UpdateMember(ctx context.Context, userID, workspaceID, memberID string, req UpdateMemberRequest) ([]Member, error) {
+	role := strings.TrimSpace(req.Role)
+	if !isValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	ws, err := s.repo.GetForUser(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ws.IsPersonal {
+		return nil, ErrPersonalWorkspace
+	}
+
+	current, err := s.repo.GetMemberRole(ctx, workspaceID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canGrant(ws.Role, role) || !canGrant(ws.Role, current) {
+		return nil, ErrForbidden
+	}
+
+	if current == RoleOwner && role != RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, workspaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateMemberRole(ctx, workspaceID, memberID, role); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// RemoveMember removes a member (owner or admin), or lets the caller leave
+func (s *WorkspacesService) RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error {
+	ws, err := s.repo.GetForUser(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if ws.IsPersonal {
+		return ErrPersonalWorkspace
+	}
+
+	current, err := s.repo.GetMemberRole(ctx, workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if memberID != userID && !canGrant(ws.Role, current) {
+		return ErrForbidden
+	}
+
+	if current == RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+/*
+========================
+ FORM ACCESS
+========================
+*/
+
+// ResolveFormWorkspace returns the workspace a new form should be created in:
+// workspaceID if the user is a member, otherwise their personal workspace when
+// workspaceID is empty
+func (s *WorkspacesService) ResolveFormWorkspace(ctx context.Context, userID, workspaceID string) (string, error) {
+	if workspaceID == "" {
+		return s.repo.EnsurePersonal(ctx, userID)
+	}
+
+	if _, err := s.repo.GetMemberRole(ctx, workspaceID, userID); err != nil {
+		if err == ErrMemberNotFound {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	return workspaceID, nil
+}
+
+/*
+========================
+ HELPERS
+========================
+*/
+
+func isValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// canGrant reports whether actorRole may hand out (or take away) role
+func canGrant(actorRole, role string) bool {
+	switch actorRole {
+	case RoleOwner:
+		return true
+	case RoleAdmin:
+		return role == RoleMember
+	default:
+		return false
+	}
+}
+
+func (s *WorkspacesService) ensureAnotherOwner(ctx context.Context, workspaceID string) error {
+	count, err := s.repo.CountOwners(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
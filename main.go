@@ -2,21 +2,34 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"smart-forms/internal/analytics"
+	"smart-forms/internal/audit"
 	"smart-forms/internal/auth"
+	"smart-forms/internal/auth/oidc"
+	"smart-forms/internal/blobstore"
 	"smart-forms/internal/cache"
+	"smart-forms/internal/collaborators"
 	"smart-forms/internal/flows"
 	"smart-forms/internal/forms"
+	"smart-forms/internal/fragments"
 	"smart-forms/internal/links"
 	"smart-forms/internal/migrations"
 	"smart-forms/internal/plans"
 	"smart-forms/internal/questions"
+	"smart-forms/internal/rbac"
 	"smart-forms/internal/responses"
 	"smart-forms/internal/responses/buffer"
+	"smart-forms/internal/transfers"
+	"smart-forms/internal/uploads"
+	"smart-forms/internal/users"
+	"smart-forms/internal/versions"
+	"smart-forms/internal/workspaces"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -42,6 +55,17 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	// Load JWT signing keys (fail fast on misconfiguration)
+	if err := auth.LoadKeyrings(); err != nil {
+		log.Fatal("Failed to load JWT keyrings: ", err)
+	}
+
+	// Load social login providers (optional)
+	oidcProviders, err := oidc.LoadProviders()
+	if err != nil {
+		log.Fatal("Failed to load identity providers: ", err)
+	}
+
 	// Run database migrations
 	if err := migrations.RunMigrations(os.Getenv("DATABASE_URL")); err != nil {
 		log.Printf("Warning: Migration failed: %v", err)
@@ -74,16 +98,33 @@ func main() {
 	)
 	defer responseBuffer.Close()
 
-	app := fiber.New()
+	// Uploaded files (file questions) are kept in the blob store
+	blobStore, err := blobstore.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to open blob store: ", err)
+	}
+
+	// Bodies over the default limit are streamed to handlers instead of
+	// rejected, so file uploads can be larger; limitBodies keeps the
+	// default limit on every other route
+	app := fiber.New(fiber.Config{
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	// Logger middleware
 	app.Use(logger.New())
 
+	// Body limit (POST /f/:slug/uploads checks its own)
+	app.Use(limitBodies)
+
 	// CORS middleware (from ENV)
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: os.Getenv("CORS_ORIGINS"),
 		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, ngrok-skip-browser-warning",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-API-Key, If-Match, ngrok-skip-browser-warning",
+		// Flow editors read the ETag for If-Match (see docs/flows.txt)
+		ExposeHeaders: "ETag",
 	}))
 
 	// Custom ENV middleware
@@ -98,100 +139,284 @@ func main() {
 
 	// Auth setup
 	authRepo := auth.NewAuthRepository(db)
-	authService := auth.NewAuthService(authRepo)
+	authService := auth.NewAuthService(authRepo, oidcProviders)
 	authHandler := auth.NewAuthHandler(authService)
 
 	// Auth routes
 	app.Post("/auth/login", authHandler.Login)
+	app.Post("/auth/login/mfa", authHandler.LoginMFA)
 	app.Post("/auth/refresh", authHandler.Refresh)
 	app.Post("/auth/register", authHandler.Register)
+	app.Post("/auth/password/reset", authHandler.ResetPassword)
+	app.Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// Social login routes
+	app.Get("/auth/oidc/providers", authHandler.OIDCProviders)
+	app.Get("/auth/oidc/:provider/authorize", authHandler.OIDCAuthorize)
+	app.Post("/auth/oidc/:provider/callback", authHandler.OIDCCallback)
+
+	workspacesRepo := workspaces.NewWorkspacesRepository(db)
+	workspacesService := workspaces.NewWorkspacesService(workspacesRepo)
+	workspacesHandler := workspaces.NewWorkspacesHandler(workspacesService)
+
+	// Per-form roles, checked by the forms, flows, links, responses and analytics handlers
+	collaboratorsRepo := collaborators.NewCollaboratorsRepository(db)
+	formAccess := collaborators.NewAccessChecker(collaboratorsRepo)
+	collaboratorsService := collaborators.NewCollaboratorsService(collaboratorsRepo, formAccess)
+	collaboratorsHandler := collaborators.NewCollaboratorsHandler(collaboratorsService)
 
 	formsRepo := forms.NewFormsRepository(db)
-	formsService := forms.NewFormsService(formsRepo, formCache)
-	formsHandler := forms.NewFormsHandler(formsService)
+	formsService := forms.NewFormsService(formsRepo, formCache, workspacesService)
+	formsHandler := forms.NewFormsHandler(formsService, formAccess)
 
 	questionRepo := questions.NewQuestionRepository(db)
 	questionService := questions.NewQuestionService(questionRepo)
 	questionHandler := questions.NewQuestionHandler(questionService)
 
+	versionsRepo := versions.NewVersionsRepository(db)
+	versionsService := versions.NewVersionsService(versionsRepo, formCache)
+	versionsHandler := versions.NewVersionsHandler(versionsService, formAccess)
+
 	flowRepo := flows.NewFlowRepository(db)
-	flowService := flows.NewFlowService(flowRepo, formCache)
-	flowHandler := flows.NewFlowHandler(flowService)
+	flowService := flows.NewFlowService(flowRepo, versionsService)
+	flowHandler := flows.NewFlowHandler(flowService, formAccess)
+
+	// Fragments are embedded by flows and expanded when a draft is published
+	fragmentsRepo := fragments.NewFragmentsRepository(db)
+	fragmentsService := fragments.NewFragmentsService(fragmentsRepo, workspacesService)
+	fragmentsHandler := fragments.NewFragmentsHandler(fragmentsService)
+	flowService.SetFragments(fragmentsService)
+	versionsRepo.SetExpander(fragmentsRepo)
 
 	// Inject flowRepo into formsHandler for template cloning
 	formsHandler.SetFlowRepo(&flowRepoAdapter{flowRepo})
 
 	linksRepo := links.NewLinksRepository(db)
-	linksService := links.NewLinksService(linksRepo, formCache)
-	linksHandler := links.NewLinksHandler(linksService)
+	linksService := links.NewLinksService(linksRepo, formCache, versionsService)
+	linksHandler := links.NewLinksHandler(linksService, formAccess)
 
 	responsesRepo := responses.NewResponsesRepository(db)
-	responsesService := responses.NewResponsesService(responsesRepo, responseBuffer)
-	responsesHandler := responses.NewResponsesHandler(responsesService)
+	responsesService := responses.NewResponsesService(responsesRepo, responseBuffer, versionsService)
+	responsesHandler := responses.NewResponsesHandler(responsesService, formAccess)
+
+	// Files are uploaded before the response that attaches them is submitted
+	uploadsRepo := uploads.NewUploadsRepository(db)
+	uploadsService := uploads.NewUploadsService(uploadsRepo, blobStore, uploads.SigningKeyFromEnv())
+	uploadsHandler := uploads.NewUploadsHandler(uploadsService, formAccess)
+	responsesService.SetUploads(uploadsService)
+	go purgeStaleUploads(uploadsService)
 
 	analyticsRepo := analytics.NewAnalyticsRepository(db)
 	analyticsService := analytics.NewAnalyticsService(analyticsRepo)
-	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService)
+	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService, formAccess)
 
 	plansRepo := plans.NewPlansRepository(db)
 	plansService := plans.NewPlansService(plansRepo)
 	plansHandler := plans.NewPlansHandler(plansService)
 
+	auditRepo := audit.NewAuditRepository(db)
+	auditService := audit.NewAuditService(auditRepo)
+	auditHandler := audit.NewAuditHandler(auditService)
+
+	usersRepo := users.NewUsersRepository(db)
+	usersService := users.NewUsersService(usersRepo, formCache, auditService)
+	usersHandler := users.NewUsersHandler(usersService)
+
+	rbacRepo := rbac.NewRBACRepository(db)
+	rbacService := rbac.NewRBACService(rbacRepo, auditService)
+	rbacHandler := rbac.NewRBACHandler(rbacService)
+
+	transfersRepo := transfers.NewTransfersRepository(db)
+	transfersService := transfers.NewTransfersService(transfersRepo, formAccess, workspacesService, formCache, auditService)
+	transfersHandler := transfers.NewTransfersHandler(transfersService)
+
 	// Public routes (no auth) - MUST be before protected group
 	app.Get("/f/:slug", linksHandler.GetPublicForm)
 	app.Post("/f/:slug/responses", responsesHandler.SubmitResponse)
+	app.Post("/f/:slug/evaluate", responsesHandler.Evaluate)
+	app.Post("/f/:slug/uploads", uploadsHandler.Upload)
+	app.Get("/files/:upload_id", uploadsHandler.Download) // Signed download links
 	app.Get("/plans", plansHandler.ListActivePlans) // Public pricing page
 	app.Get("/templates", formsHandler.ListTemplates) // Public template gallery
 
-	// Protect routes
-	api := app.Group("/", auth.JWTAuthMiddleware())
+	// Protect routes (JWT or API key); impersonated requests are audited and
+	// read-only, but for the dry runs allowed here
+	api := app.Group("/",
+		auth.JWTAuthMiddleware(authService),
+		audit.ImpersonationLogger(auditService),
+		auth.BlockImpersonatedWrites(
+			"POST /forms/:form_id/flow/validate",
+			"POST /forms/:form_id/flow/simulate",
+		),
+	)
+
+	// Route guards: session rejects API keys, scope guards limit what API keys may call
+	session := auth.RequireSession()
+	formsRead := auth.RequireScope(auth.ScopeFormsRead)
+	formsWrite := auth.RequireScope(auth.ScopeFormsWrite)
+	responsesRead := auth.RequireScope(auth.ScopeResponsesRead, auth.ScopeResponsesExport)
+	analyticsRead := auth.RequireScope(auth.ScopeAnalyticsRead)
+
+	// MFA management routes
+	api.Get("/auth/mfa", session, authHandler.MFAStatus)
+	api.Post("/auth/mfa/enroll", session, authHandler.EnrollMFA)
+	api.Post("/auth/mfa/verify", session, authHandler.VerifyMFA)
+	api.Post("/auth/mfa/disable", session, authHandler.DisableMFA)
+	api.Post("/auth/mfa/recovery-codes", session, authHandler.RegenerateRecoveryCodes)
+
+	// API key management routes
+	api.Get("/auth/api-keys", session, authHandler.ListAPIKeys)
+	api.Post("/auth/api-keys", session, authHandler.CreateAPIKey)
+	api.Patch("/auth/api-keys/:id", session, authHandler.UpdateAPIKey)
+	api.Delete("/auth/api-keys/:id", session, authHandler.RevokeAPIKey)
+
+	// Linked identity routes
+	api.Get("/auth/identities", session, authHandler.ListIdentities)
+	api.Post("/auth/identities/:provider/link", session, authHandler.LinkIdentity)
+	api.Delete("/auth/identities/:id", session, authHandler.UnlinkIdentity)
+
+	// Workspace routes (membership changes require a session)
+	api.Get("/workspaces", formsRead, workspacesHandler.ListWorkspaces)
+	api.Post("/workspaces", session, workspacesHandler.CreateWorkspace)
+	api.Get("/workspaces/:id", formsRead, workspacesHandler.GetWorkspace)
+	api.Patch("/workspaces/:id", session, workspacesHandler.UpdateWorkspace)
+	api.Delete("/workspaces/:id", session, workspacesHandler.DeleteWorkspace)
+	api.Get("/workspaces/:id/members", formsRead, workspacesHandler.ListMembers)
+	api.Post("/workspaces/:id/members", session, workspacesHandler.AddMember)
+	api.Patch("/workspaces/:id/members/:user_id", session, workspacesHandler.UpdateMember)
+	api.Delete("/workspaces/:id/members/:user_id", session, workspacesHandler.RemoveMember)
 
 	// Forms routes
-	api.Post("/forms", formsHandler.Create)
-	api.Get("/forms", formsHandler.List)
-	api.Get("/forms/:id", formsHandler.GetByID)
-	api.Patch("/forms/:id", formsHandler.Update)
-	api.Patch("/forms/:id/delete", formsHandler.SoftDelete)
+	api.Post("/forms", formsWrite, formsHandler.Create)
+	api.Get("/forms", formsRead, formsHandler.List)
+	api.Get("/forms/:id", formsRead, formsHandler.GetByID)
+	api.Patch("/forms/:id", formsWrite, formsHandler.Update)
+	api.Patch("/forms/:id/delete", formsWrite, formsHandler.SoftDelete)
+	api.Patch("/forms/:id/workspace", formsWrite, formsHandler.MoveToWorkspace)
+	api.Put("/forms/:id/quiz", formsWrite, formsHandler.SetQuiz)
+
+	// Collaborator routes (sharing changes require a session)
+	api.Get("/forms/:form_id/collaborators", formsRead, collaboratorsHandler.ListCollaborators)
+	api.Post("/forms/:form_id/collaborators/invitations", session, collaboratorsHandler.Invite)
+	api.Delete("/forms/:form_id/collaborators/invitations/:id", session, collaboratorsHandler.RevokeInvitation)
+	api.Patch("/forms/:form_id/collaborators/:user_id", session, collaboratorsHandler.UpdateRole)
+	api.Delete("/forms/:form_id/collaborators/:user_id", session, collaboratorsHandler.RemoveCollaborator)
+
+	// Invitations addressed to the caller
+	api.Get("/invitations", session, collaboratorsHandler.ListMyInvitations)
+	api.Post("/invitations/:id/accept", session, collaboratorsHandler.AcceptInvitation)
+	api.Post("/invitations/:id/decline", session, collaboratorsHandler.DeclineInvitation)
+
+	// Ownership transfers (session only)
+	api.Get("/forms/:form_id/transfers", session, transfersHandler.ListFormTransfers)
+	api.Post("/forms/:form_id/transfers", session, transfersHandler.StartTransfer)
+	api.Delete("/forms/:form_id/transfers/:id", session, transfersHandler.CancelTransfer)
+	api.Get("/transfers", session, transfersHandler.ListIncoming)
+	api.Post("/transfers/:id/accept", session, transfersHandler.AcceptTransfer)
+	api.Post("/transfers/:id/decline", session, transfersHandler.DeclineTransfer)
 
 	// Template clone (authenticated users)
-	api.Post("/templates/:id/clone", formsHandler.CloneTemplate)
+	api.Post("/templates/:id/clone", formsWrite, formsHandler.CloneTemplate)
 
 	// Questions routes
-	api.Post("/questions", questionHandler.Create)
-	api.Get("/questions", questionHandler.List)
-	api.Get("/questions/:id", questionHandler.GetByID)
-	api.Patch("/questions/:id", questionHandler.Update)
-	api.Delete("/questions/:id", questionHandler.Delete)
+	api.Post("/questions", formsWrite, questionHandler.Create)
+	api.Get("/questions", formsRead, questionHandler.List)
+	api.Get("/questions/kinds", formsRead, questionHandler.Kinds)
+	api.Get("/questions/:id", formsRead, questionHandler.GetByID)
+	api.Patch("/questions/:id", formsWrite, questionHandler.Update)
+	api.Delete("/questions/:id", formsWrite, questionHandler.Delete)
 
 	// Flow routes
-	api.Patch("/forms/:form_id/flow", flowHandler.UpdateFlow)
-	api.Get("/forms/:form_id/flow", flowHandler.GetFlow)
+	api.Patch("/forms/:form_id/flow", formsWrite, flowHandler.UpdateFlow)
+	api.Post("/forms/:form_id/flow/operations", formsWrite, flowHandler.ApplyOperations)
+	api.Post("/forms/:form_id/flow/validate", formsRead, flowHandler.ValidateFlow)
+	api.Post("/forms/:form_id/flow/simulate", formsRead, flowHandler.SimulateFlow)
+	api.Get("/forms/:form_id/flow/export", formsRead, flowHandler.ExportFlow)
+	api.Post("/forms/:form_id/flow/import", formsWrite, flowHandler.ImportFlow)
+	api.Get("/forms/:form_id/flow", formsRead, flowHandler.GetFlow)
+
+	// Fragment routes
+	api.Get("/fragments", formsRead, fragmentsHandler.ListFragments)
+	api.Post("/fragments", formsWrite, fragmentsHandler.CreateFragment)
+	api.Get("/fragments/:id", formsRead, fragmentsHandler.GetFragment)
+	api.Patch("/fragments/:id", formsWrite, fragmentsHandler.UpdateFragment)
+	api.Delete("/fragments/:id", formsWrite, fragmentsHandler.DeleteFragment)
+	api.Get("/fragments/:id/versions", formsRead, fragmentsHandler.ListVersions)
+	api.Post("/fragments/:id/versions", formsWrite, fragmentsHandler.CreateVersion)
+	api.Get("/fragments/:id/versions/:version", formsRead, fragmentsHandler.GetVersion)
+
+	// Version routes (diff must precede :version)
+	api.Get("/forms/:form_id/versions", formsRead, versionsHandler.ListVersions)
+	api.Get("/forms/:form_id/versions/diff", formsRead, versionsHandler.DiffVersions)
+	api.Get("/forms/:form_id/versions/:version", formsRead, versionsHandler.GetVersion)
+	api.Post("/forms/:form_id/versions/publish", formsWrite, versionsHandler.PublishDraft)
+	api.Post("/forms/:form_id/versions/:version/rollback", formsWrite, versionsHandler.Rollback)
+	api.Get("/forms/:form_id/variants", formsRead, versionsHandler.ListVariants)
+	api.Put("/forms/:form_id/variants", formsWrite, versionsHandler.SetVariants)
 
 	// Links routes (protected)
-	api.Patch("/forms/:form_id/publish", linksHandler.PublishForm)
-	api.Patch("/forms/:form_id/accepting-responses", linksHandler.ToggleAcceptingResponses)
+	api.Patch("/forms/:form_id/publish", formsWrite, linksHandler.PublishForm)
+	api.Patch("/forms/:form_id/accepting-responses", formsWrite, linksHandler.ToggleAcceptingResponses)
 
 	// Responses routes (protected)
-	api.Get("/forms/:form_id/responses", responsesHandler.GetFormResponses)
-	api.Get("/responses/:response_id", responsesHandler.GetResponseDetails)
+	api.Get("/forms/:form_id/responses", responsesRead, responsesHandler.GetFormResponses)
+	api.Get("/responses/:response_id", responsesRead, responsesHandler.GetResponseDetails)
+	api.Get("/responses/:response_id/files", responsesRead, uploadsHandler.ResponseFiles)
 
 	// Analytics routes (protected)
-	api.Get("/forms/:form_id/analytics/status", analyticsHandler.GetAnalyticsStatus)
-	api.Get("/forms/:form_id/analytics/nodes", analyticsHandler.GetNodeAnalytics)
-	api.Get("/forms/:form_id/analytics/flow", analyticsHandler.GetFlowAnalytics)
-
-	// Super Admin routes (requires super_admin role)
-	admin := api.Group("/admin", auth.RequireSuperAdmin())
-
-	// Plans management (super admin only)
-	admin.Get("/plans", plansHandler.ListAllPlans)
-	admin.Post("/plans", plansHandler.CreatePlan)
-	admin.Get("/plans/:id", plansHandler.GetPlan)
-	admin.Patch("/plans/:id", plansHandler.UpdatePlan)
-	admin.Delete("/plans/:id", plansHandler.DeletePlan)
-
-	// Template management (super admin only)
-	admin.Patch("/forms/:id/template", formsHandler.ToggleTemplate)
+	api.Get("/forms/:form_id/analytics/status", analyticsRead, analyticsHandler.GetAnalyticsStatus)
+	api.Get("/forms/:form_id/analytics/nodes", analyticsRead, analyticsHandler.GetNodeAnalytics)
+	api.Get("/forms/:form_id/analytics/flow", analyticsRead, analyticsHandler.GetFlowAnalytics)
+	api.Get("/forms/:form_id/analytics/quiz", analyticsRead, analyticsHandler.GetQuizAnalytics)
+	api.Get("/forms/:form_id/analytics/endings", analyticsRead, analyticsHandler.GetEndingAnalytics)
+	api.Get("/forms/:form_id/analytics/order", analyticsRead, analyticsHandler.GetOrderAnalytics)
+	api.Get("/forms/:form_id/analytics/variants", analyticsRead, analyticsHandler.GetVariantAnalytics)
+	api.Get("/forms/:form_id/analytics/kinds", analyticsRead, analyticsHandler.GetKindAnalytics)
+
+	// Admin routes (each route requires a permission granted by the user's role)
+	admin := api.Group("/admin", session)
+	plansWrite := auth.RequirePermission(auth.PermPlansWrite)
+	templatesModerate := auth.RequirePermission(auth.PermTemplatesModerate)
+	usersManage := auth.RequirePermission(auth.PermUsersManage)
+	usersImpersonate := auth.RequirePermission(auth.PermUsersImpersonate)
+	auditRead := auth.RequirePermission(auth.PermAuditRead)
+	rolesManage := auth.RequirePermission(auth.PermRolesManage)
+	formsTransfer := auth.RequirePermission(auth.PermFormsTransfer)
+
+	// Plans management
+	admin.Get("/plans", plansWrite, plansHandler.ListAllPlans)
+	admin.Post("/plans", plansWrite, plansHandler.CreatePlan)
+	admin.Get("/plans/:id", plansWrite, plansHandler.GetPlan)
+	admin.Patch("/plans/:id", plansWrite, plansHandler.UpdatePlan)
+	admin.Delete("/plans/:id", plansWrite, plansHandler.DeletePlan)
+
+	// Template management
+	admin.Patch("/forms/:id/template", templatesModerate, formsHandler.ToggleTemplate)
+
+	// Ownership transfers of any form (recipient still has to accept)
+	admin.Post("/forms/:id/transfers", formsTransfer, transfersHandler.AdminStartTransfer)
+	admin.Delete("/forms/:id/transfers/:transfer_id", formsTransfer, transfersHandler.AdminCancelTransfer)
+
+	// User management
+	admin.Get("/users", usersManage, usersHandler.ListUsers)
+	admin.Get("/users/:id", usersManage, usersHandler.GetUser)
+	admin.Post("/users/:id/deactivate", usersManage, usersHandler.DeactivateUser)
+	admin.Post("/users/:id/reactivate", usersManage, usersHandler.ReactivateUser)
+	admin.Patch("/users/:id/role", usersManage, usersHandler.UpdateRole)
+	admin.Post("/users/:id/password-reset", usersManage, usersHandler.ForcePasswordReset)
+	admin.Delete("/users/:id", usersManage, usersHandler.DeleteUser)
+	admin.Post("/users/:id/impersonate", usersImpersonate, usersHandler.ImpersonateUser)
+
+	// Audit log
+	admin.Get("/audit-log", auditRead, auditHandler.ListEntries)
+
+	// Roles and permissions
+	admin.Get("/permissions", rolesManage, rbacHandler.ListPermissions)
+	admin.Get("/roles", rolesManage, rbacHandler.ListRoles)
+	admin.Post("/roles", rolesManage, rbacHandler.CreateRole)
+	admin.Get("/roles/:name", rolesManage, rbacHandler.GetRole)
+	admin.Patch("/roles/:name", rolesManage, rbacHandler.UpdateRole)
+	admin.Delete("/roles/:name", rolesManage, rbacHandler.DeleteRole)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -202,6 +427,53 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
+// limitBodies enforces Fiber's default body limit on every route but file
+// uploads. With StreamRequestBody on, larger and chunked bodies reach the
+// handlers unread, so they are checked here.
+func limitBodies(c *fiber.Ctx) error {
+	if c.Method() == fiber.MethodPost && isUploadPath(c.Path()) {
+		return c.Next()
+	}
+
+	req := c.Request()
+	switch size := req.Header.ContentLength(); {
+	case size > fiber.DefaultBodyLimit:
+		c.Context().SetConnectionClose()
+		return fiber.ErrRequestEntityTooLarge
+	case size == -1 && req.BodyStream() != nil:
+		// Chunked: read it here, within the limit
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), fiber.DefaultBodyLimit+1))
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		if len(body) > fiber.DefaultBodyLimit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		req.SetBodyRaw(body)
+	}
+	return c.Next()
+}
+
+// isUploadPath reports whether a path is /f/:slug/uploads
+func isUploadPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) == 3 && parts[0] == "f" && parts[1] != "" && parts[2] == "uploads"
+}
+
+// purgeStaleUploads hourly deletes uploads no submitted response attached
+func purgeStaleUploads(service *uploads.UploadsService) {
+	for range time.Tick(time.Hour) {
+		purged, err := service.PurgeStale(context.Background())
+		if err != nil {
+			log.Printf("Warning: purging stale uploads failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d stale uploads", purged)
+		}
+	}
+}
+
 // ---------------- HANDLERS ----------------
 
 func helloHandler(c *fiber.Ctx) error {
@@ -0,0 +1,7 @@
+-- Drop recovery codes table
+DROP TABLE IF EXISTS user_recovery_codes;
+
+-- Remove MFA fields from users table
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_last_used_step;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_secret;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled;
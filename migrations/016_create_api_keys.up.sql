@@ -0,0 +1,23 @@
+-- Long-lived API keys for programmatic access
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+
+    -- Public lookup prefix (e.g. "sfk_ab12cd34") and SHA-256 of the full key
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+COMMENT ON TABLE api_keys IS 'Hashed, scoped API keys (personal access tokens)';
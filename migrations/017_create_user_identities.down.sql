@@ -0,0 +1,3 @@
+-- Drop social login tables
+DROP TABLE IF EXISTS oidc_login_states;
+DROP TABLE IF EXISTS user_identities;
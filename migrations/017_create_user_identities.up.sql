@@ -0,0 +1,38 @@
+-- External identities (OIDC / OAuth2 social login) linked to users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Configured provider name (e.g. "google") and the provider's stable subject id
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+
+    email TEXT,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+
+    last_login_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+COMMENT ON TABLE user_identities IS 'Identity provider accounts linked to users';
+
+-- In-flight authorization requests (state, nonce and PKCE verifier never leave the server)
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+
+    -- Set when an authenticated user is linking a provider to their account
+    link_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states(expires_at);
@@ -0,0 +1,9 @@
+-- Remove admin user management fields
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_password_reset_token_hash;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_valid_after;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_token_hash;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
@@ -0,0 +1,18 @@
+-- Admin user management: forced password reset and session revocation
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_token_hash TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_expires_at TIMESTAMPTZ;
+
+-- Refresh tokens issued before this instant are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_password_reset_token_hash
+    ON users(password_reset_token_hash) WHERE password_reset_token_hash IS NOT NULL;
+
+-- Admin search by email
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+
+COMMENT ON COLUMN users.password_reset_required IS 'Set by admin; password login is refused until the password is reset';
+COMMENT ON COLUMN users.tokens_valid_after IS 'Refresh tokens issued before this time are invalid';
@@ -0,0 +1,29 @@
+-- Append-only audit trail (impersonation, ownership changes, ...)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Who acted (admin during impersonation) and on whose behalf / whom it concerns.
+    -- No foreign keys: entries must survive user deletion.
+    actor_id UUID,
+    subject_user_id UUID,
+
+    action TEXT NOT NULL,
+
+    -- Request details (NULL for non-HTTP events)
+    method TEXT,
+    path TEXT,
+    status_code INT,
+    ip_address TEXT,
+    user_agent TEXT,
+
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_subject_user_id ON audit_log(subject_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+
+COMMENT ON TABLE audit_log IS 'Append-only audit trail of privileged actions';
@@ -0,0 +1,6 @@
+-- Drop permission model (users.role stays a plain TEXT column)
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_role;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,52 @@
+-- Named roles (users.role references roles.name)
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+
+    -- System roles cannot be renamed or deleted
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+-- Permission catalog (defined by the application, seeded by migrations)
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission)
+);
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('user', 'Regular account (default for new users)', true),
+    ('super_admin', 'Full administrative access', true)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('plans:write', 'Create, update and delete subscription plans'),
+    ('templates:moderate', 'Mark forms as public templates'),
+    ('users:manage', 'List, deactivate, delete users and change their roles'),
+    ('users:impersonate', 'Act as another user with an impersonation token'),
+    ('audit:read', 'Read the audit log'),
+    ('roles:manage', 'Create roles and change role permissions')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission)
+SELECT 'super_admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+-- Any custom role values already in use become roles without permissions
+INSERT INTO roles (name, description)
+SELECT DISTINCT role, '' FROM users
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+
+COMMENT ON TABLE roles IS 'Named roles assignable to users';
+COMMENT ON TABLE role_permissions IS 'Permissions granted by each role';
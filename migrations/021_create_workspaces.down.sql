@@ -0,0 +1,6 @@
+-- Forms fall back to per-user ownership (forms.user_id)
+DROP INDEX IF EXISTS idx_forms_workspace_updated;
+ALTER TABLE forms DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
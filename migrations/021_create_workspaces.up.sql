@@ -0,0 +1,56 @@
+-- Workspaces group forms; every member can work on every form of the workspace
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+
+    -- Set for the single-member personal workspace of a user
+    personal_user_id UUID UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- owner: everything incl. delete; admin: manage members; member: work on forms
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+-- Access checks resolve "workspaces of user X"
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- forms.user_id stays as the creator; access goes through workspace membership
+ALTER TABLE forms
+    ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_forms_workspace_updated
+    ON forms(workspace_id, updated_at DESC)
+    WHERE deleted_at IS NULL;
+
+-- Move every existing user into a personal workspace holding their forms
+INSERT INTO workspaces (name, personal_user_id, created_by)
+SELECT 'Personal', id, id FROM users
+ON CONFLICT (personal_user_id) DO NOTHING;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, personal_user_id, 'owner'
+FROM workspaces
+WHERE personal_user_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+UPDATE forms f
+SET workspace_id = w.id
+FROM workspaces w
+WHERE w.personal_user_id = f.user_id
+  AND f.workspace_id IS NULL;
+
+COMMENT ON TABLE workspaces IS 'Teams sharing forms; personal_user_id marks personal workspaces';
+COMMENT ON COLUMN forms.workspace_id IS 'Workspace the form belongs to (NULL only for forms of users deleted before workspaces existed)';
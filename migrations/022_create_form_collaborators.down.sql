@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS form_invitations;
+DROP TABLE IF EXISTS form_collaborators;
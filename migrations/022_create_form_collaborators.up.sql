@@ -0,0 +1,36 @@
+-- Per-form access for users outside the form's workspace
+CREATE TABLE IF NOT EXISTS form_collaborators (
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'responder-viewer', 'analytics-viewer')),
+
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+
+    PRIMARY KEY (form_id, user_id)
+);
+
+-- "Forms shared with me"
+CREATE INDEX IF NOT EXISTS idx_form_collaborators_user_id ON form_collaborators(user_id);
+
+-- Pending invitations, matched to accounts by email and accepted by the invitee
+CREATE TABLE IF NOT EXISTS form_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'responder-viewer', 'analytics-viewer')),
+
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (form_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_form_invitations_email ON form_invitations(email);
+
+COMMENT ON TABLE form_collaborators IS 'Per-form roles: owner, editor, responder-viewer, analytics-viewer';
+COMMENT ON TABLE form_invitations IS 'Pending collaborator invitations (deleted once accepted or declined)';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS form_transfers;
+
+-- role_permissions rows cascade
+DELETE FROM permissions WHERE name = 'forms:transfer';
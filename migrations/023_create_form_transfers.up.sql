@@ -0,0 +1,38 @@
+-- Ownership transfers: started by the form owner (or an admin), accepted by the recipient
+CREATE TABLE IF NOT EXISTS form_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+
+    -- forms.user_id at the time the transfer was started
+    from_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    initiated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+
+    -- Workspace the form was moved into on acceptance
+    to_workspace_id UUID REFERENCES workspaces(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
+);
+
+-- At most one pending transfer per form
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_transfers_pending
+ON form_transfers(form_id) WHERE status = 'pending';
+
+-- "Transfers waiting for me"
+CREATE INDEX IF NOT EXISTS idx_form_transfers_to_user_id
+ON form_transfers(to_user_id) WHERE status = 'pending';
+
+INSERT INTO permissions (name, description) VALUES
+    ('forms:transfer', 'Start ownership transfers of any form')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('super_admin', 'forms:transfer')
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE form_transfers IS 'Form ownership transfers (pending until the recipient accepts)';
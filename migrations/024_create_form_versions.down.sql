@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_form_responses_version_id;
+ALTER TABLE form_responses DROP COLUMN IF EXISTS version_id;
+
+-- Without versions every live connection is part of the public flow: keep
+-- only those of the published version (or the draft if never published)
+UPDATE flow_connections fc
+SET deleted_at = NOW()
+WHERE fc.deleted_at IS NULL
+  AND fc.version_id IS DISTINCT FROM (
+      SELECT v.id FROM form_versions v
+      WHERE v.form_id = fc.form_id AND v.status IN ('draft', 'published')
+      ORDER BY v.status = 'published' DESC
+      LIMIT 1
+  );
+
+DROP INDEX IF EXISTS idx_flow_connections_version_id;
+ALTER TABLE flow_connections DROP COLUMN IF EXISTS version_id;
+
+DROP TABLE IF EXISTS form_versions;
@@ -0,0 +1,93 @@
+-- Immutable form versions: one editable draft, one published (live) version,
+-- any number of archived ones. Each version owns its flow_connections rows.
+CREATE TABLE IF NOT EXISTS form_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    version_number INT NOT NULL,
+
+    status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'published', 'archived')),
+
+    -- Flow and question texts frozen at publish time (NULL while draft)
+    snapshot JSONB,
+
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    published_at TIMESTAMPTZ,
+    published_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    UNIQUE (form_id, version_number)
+);
+
+-- At most one draft and one published version per form
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_versions_draft
+ON form_versions(form_id) WHERE status = 'draft';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_versions_published
+ON form_versions(form_id) WHERE status = 'published';
+
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS version_id UUID REFERENCES form_versions(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_flow_connections_version_id ON flow_connections(version_id);
+
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS version_id UUID REFERENCES form_versions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_form_responses_version_id ON form_responses(version_id);
+
+-- Backfill: every form gets version 1 holding its current flow
+INSERT INTO form_versions (form_id, version_number, status, created_by, created_at, published_at)
+SELECT
+    f.id,
+    1,
+    CASE WHEN f.status = 'published' THEN 'published' ELSE 'draft' END,
+    (SELECT u.id FROM users u WHERE u.id = f.user_id),
+    f.created_at,
+    f.published_at
+FROM forms f
+ON CONFLICT (form_id, version_number) DO NOTHING;
+
+UPDATE flow_connections fc
+SET version_id = v.id
+FROM form_versions v
+WHERE v.form_id = fc.form_id
+  AND v.version_number = 1
+  AND fc.deleted_at IS NULL
+  AND fc.version_id IS NULL;
+
+UPDATE form_versions v
+SET snapshot = (
+    SELECT COALESCE(jsonb_agg(jsonb_build_object(
+        'id', fc.id,
+        'parent_id', fc.parent_id,
+        'order_index', fc.order_index,
+        'depth_level', fc.depth_level,
+        'is_terminal', fc.is_terminal,
+        'question_id', q.id,
+        'type', q.type,
+        'question', q.question_text
+    ) ORDER BY fc.depth_level, fc.order_index), '[]'::jsonb)
+    FROM flow_connections fc
+    JOIN questions q ON q.id = fc.question_id
+    WHERE fc.version_id = v.id AND fc.deleted_at IS NULL
+)
+WHERE v.status = 'published' AND v.snapshot IS NULL;
+
+-- Existing responses whose answers all belong to the published flow
+UPDATE form_responses r
+SET version_id = v.id
+FROM form_versions v
+WHERE v.form_id = r.form_id
+  AND v.status = 'published'
+  AND r.version_id IS NULL
+  AND NOT EXISTS (
+      SELECT 1
+      FROM response_answers a
+      JOIN flow_connections fc ON fc.id = a.flow_connection_id
+      WHERE a.response_id = r.id
+        AND fc.version_id IS DISTINCT FROM v.id
+  );
+
+COMMENT ON TABLE form_versions IS 'Form versions; published/archived versions are immutable';
+COMMENT ON COLUMN form_responses.version_id IS 'Version the response was filled against (NULL for older responses)';
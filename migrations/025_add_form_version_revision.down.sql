@@ -0,0 +1 @@
+ALTER TABLE form_versions DROP COLUMN IF EXISTS revision;
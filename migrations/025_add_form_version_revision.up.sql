@@ -0,0 +1,4 @@
+-- Bumped on every flow save of a draft; (version_number, revision) is the
+-- flow's ETag for optimistic concurrency
+ALTER TABLE form_versions
+    ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 0;
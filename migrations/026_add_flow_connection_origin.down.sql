@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_flow_connections_origin_id;
+ALTER TABLE flow_connections DROP COLUMN IF EXISTS origin_id;
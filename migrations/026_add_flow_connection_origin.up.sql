@@ -0,0 +1,7 @@
+-- Connection a draft node was copied from (previous version), so IDs read from
+-- the published flow still resolve in the draft and analytics can follow a
+-- node across versions
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS origin_id UUID REFERENCES flow_connections(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_flow_connections_origin_id ON flow_connections(origin_id);
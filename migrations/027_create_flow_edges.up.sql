@@ -0,0 +1,17 @@
+-- Jumps between flow nodes ("after source, continue at target"). Together
+-- with parent_id they make a flow a DAG: a node reached from several
+-- branches is a merge point and exists (and is analysed) once
+CREATE TABLE IF NOT EXISTS flow_edges (
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    version_id UUID NOT NULL REFERENCES form_versions(id) ON DELETE CASCADE,
+    source_id UUID NOT NULL REFERENCES flow_connections(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES flow_connections(id) ON DELETE CASCADE,
+    order_index INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    PRIMARY KEY (source_id, target_id),
+    CHECK (source_id <> target_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flow_edges_version_id ON flow_edges(version_id);
+CREATE INDEX IF NOT EXISTS idx_flow_edges_target_id ON flow_edges(target_id);
+CREATE INDEX IF NOT EXISTS idx_flow_edges_form_id ON flow_edges(form_id);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_flow_connections_fragment_id;
+ALTER TABLE flow_connections
+    DROP COLUMN IF EXISTS generated_by,
+    DROP COLUMN IF EXISTS fragment_version,
+    DROP COLUMN IF EXISTS fragment_id;
+
+-- Embeds and their expanded nodes stay as plain nodes
+UPDATE questions SET type = 'question' WHERE type = 'fragment';
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_type_check;
+ALTER TABLE questions ADD CONSTRAINT questions_type_check CHECK (type IN ('question', 'option', 'input'));
+
+DROP TABLE IF EXISTS flow_fragment_versions;
+DROP TABLE IF EXISTS flow_fragments;
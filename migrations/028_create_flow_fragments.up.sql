@@ -0,0 +1,43 @@
+-- Named, versioned pieces of flow ("contact details", "consent") that forms
+-- embed by reference. A fragment belongs to a workspace; personal workspaces
+-- make it the user's own.
+CREATE TABLE IF NOT EXISTS flow_fragments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    latest_version INT NOT NULL DEFAULT 1,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_flow_fragments_workspace_name
+    ON flow_fragments(workspace_id, lower(name))
+    WHERE deleted_at IS NULL;
+
+-- Fragment versions never change; editing a fragment adds a version
+CREATE TABLE IF NOT EXISTS flow_fragment_versions (
+    fragment_id UUID NOT NULL REFERENCES flow_fragments(id) ON DELETE CASCADE,
+    version_number INT NOT NULL,
+    blocks JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    PRIMARY KEY (fragment_id, version_number)
+);
+
+-- A flow node of type 'fragment' embeds a fragment: a pinned version, or the
+-- latest one when fragment_version is NULL. Publishing expands it into nodes
+-- under it whose generated_by points back at it; drafts keep only the embed.
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_type_check;
+ALTER TABLE questions ADD CONSTRAINT questions_type_check CHECK (type IN ('question', 'option', 'input', 'fragment'));
+
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS fragment_id UUID REFERENCES flow_fragments(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS fragment_version INT,
+    ADD COLUMN IF NOT EXISTS generated_by UUID REFERENCES flow_connections(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_flow_connections_fragment_id
+    ON flow_connections(fragment_id)
+    WHERE fragment_id IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE form_responses DROP COLUMN IF EXISTS variables;
+ALTER TABLE form_versions DROP COLUMN IF EXISTS variables;
+ALTER TABLE flow_connections
+    DROP COLUMN IF EXISTS assignments,
+    DROP COLUMN IF EXISTS variable;
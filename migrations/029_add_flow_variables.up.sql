@@ -0,0 +1,13 @@
+-- Flow variables. A node may store its answer under a variable name and run
+-- assignments ([{"var": "price", "expr": "price + 20"}]) when a respondent
+-- passes it; the version declares the computed variables and their types.
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS variable TEXT,
+    ADD COLUMN IF NOT EXISTS assignments JSONB;
+
+ALTER TABLE form_versions
+    ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '[]';
+
+-- Final variable values, computed when the response is submitted
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS variables JSONB;
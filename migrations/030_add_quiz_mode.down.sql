@@ -0,0 +1,2 @@
+ALTER TABLE form_responses DROP COLUMN IF EXISTS quiz;
+ALTER TABLE forms DROP COLUMN IF EXISTS quiz;
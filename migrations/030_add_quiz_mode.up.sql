@@ -0,0 +1,9 @@
+-- Quiz mode. A form's settings ({"enabled", "pass_percent", "show_feedback"})
+-- live on the form; correct answers, points and feedback live under "quiz" in
+-- the metadata of the questions the flow's options use.
+ALTER TABLE forms
+    ADD COLUMN IF NOT EXISTS quiz JSONB;
+
+-- Score computed when the response is submitted, with the per-question results
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS quiz JSONB;
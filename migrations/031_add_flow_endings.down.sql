@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_form_responses_form_ending;
+ALTER TABLE form_responses DROP COLUMN IF EXISTS ending;
+ALTER TABLE flow_connections DROP COLUMN IF EXISTS ending;
+ALTER TABLE form_versions DROP COLUMN IF EXISTS endings;
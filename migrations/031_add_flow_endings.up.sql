@@ -0,0 +1,14 @@
+-- Ending screens. A version declares its endings (title, rich text body,
+-- redirect URL, buttons, score range); a node may name the ending shown to
+-- respondents who finish there.
+ALTER TABLE form_versions
+    ADD COLUMN IF NOT EXISTS endings JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS ending TEXT;
+
+-- Ending shown on submission, by ID
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS ending TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_form_responses_form_ending ON form_responses (form_id, ending);
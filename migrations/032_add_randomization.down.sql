@@ -0,0 +1,3 @@
+ALTER TABLE form_responses DROP COLUMN IF EXISTS display_order;
+ALTER TABLE form_responses DROP COLUMN IF EXISTS seed;
+ALTER TABLE flow_connections DROP COLUMN IF EXISTS randomize;
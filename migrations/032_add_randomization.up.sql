@@ -0,0 +1,9 @@
+-- Randomization. A node may shuffle or sample its children per respondent;
+-- a response records the seed it was arranged with and the children each
+-- randomized node showed, in order.
+ALTER TABLE flow_connections
+    ADD COLUMN IF NOT EXISTS randomize JSONB;
+
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS seed TEXT,
+    ADD COLUMN IF NOT EXISTS display_order JSONB;
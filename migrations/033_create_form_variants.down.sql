@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_form_responses_form_variant;
+ALTER TABLE form_responses DROP COLUMN IF EXISTS variant;
+DROP TABLE IF EXISTS form_variants;
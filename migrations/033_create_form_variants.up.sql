@@ -0,0 +1,20 @@
+-- A/B tests. While a form has variants, each respondent of its public link
+-- is assigned one by a stable key and served that variant's version
+-- (published or archived) instead of the live one.
+CREATE TABLE IF NOT EXISTS form_variants (
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version_id UUID NOT NULL REFERENCES form_versions(id) ON DELETE CASCADE,
+    weight INT NOT NULL CHECK (weight >= 0),
+    position INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+
+    PRIMARY KEY (form_id, key),
+    UNIQUE (form_id, version_id)
+);
+
+-- Variant the response was served, by key
+ALTER TABLE form_responses
+    ADD COLUMN IF NOT EXISTS variant TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_form_responses_form_variant ON form_responses (form_id, variant);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_response_uploads_unattached;
+DROP INDEX IF EXISTS idx_response_uploads_response_id;
+DROP TABLE IF EXISTS response_uploads;
@@ -0,0 +1,25 @@
+-- Files respondents upload to file questions before submitting. The blob
+-- lives in the blobstore under storage_key; response_id is set when a
+-- submitted response attaches the file (no foreign key: responses are
+-- inserted in batches after the submission returns).
+CREATE TABLE IF NOT EXISTS response_uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    form_id UUID NOT NULL REFERENCES forms(id) ON DELETE CASCADE,
+    flow_connection_id UUID NOT NULL REFERENCES flow_connections(id) ON DELETE CASCADE,
+    response_id UUID,
+    storage_key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    attached_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_response_uploads_response_id
+    ON response_uploads(response_id)
+    WHERE response_id IS NOT NULL;
+
+-- Unattached uploads are purged after a while
+CREATE INDEX IF NOT EXISTS idx_response_uploads_unattached
+    ON response_uploads(created_at)
+    WHERE response_id IS NULL;
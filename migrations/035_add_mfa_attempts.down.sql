@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS mfa_challenges;
+
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_failed_attempts;
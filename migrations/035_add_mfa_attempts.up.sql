@@ -0,0 +1,16 @@
+-- Failed second factor attempts; too many lock MFA for a while
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_locked_until TIMESTAMPTZ;
+
+-- Outstanding MFA challenge tokens by jti; each is exchanged once
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    jti UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+COMMENT ON COLUMN users.mfa_locked_until IS 'Second factor attempts are refused until this time';
+COMMENT ON TABLE mfa_challenges IS 'Single-use MFA challenge tokens issued after the password check';